@@ -0,0 +1,97 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"hello-go/blockchain"
+	"hello-go/config"
+	"hello-go/database"
+	"hello-go/webhook"
+	"log"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+)
+
+// storage 数据访问层需要同时提供区块链和回调的持久化
+type storage interface {
+	blockchain.Database
+	webhook.Store
+}
+
+// app 进程内共享的依赖，启动时创建一次后注入 REST 接口、P2P、JSON-RPC、WebSocket 和回调投递
+// 使用 MySQL 时所有模块共用同一个连接池
+type app struct {
+	db    *sql.DB
+	store storage
+	bc    *blockchain.Blockchain
+}
+
+// newApp 按配置连接数据库并加载区块链，链为空时创建创世区块
+func newApp() (*app, error) {
+	a := &app{}
+
+	dbConfig := config.GetDBConfig()
+	switch dbConfig.Driver {
+	case config.DriverMemory:
+		log.Println("Using in-memory blockchain database, data will be lost on restart")
+		a.store = database.NewBlockchainMemory()
+	case config.DriverMySQL:
+		db, err := database.NewMySQLDB(dbConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to database: %v", err)
+		}
+		a.db = db
+		if dbConfig.AutoMigrate {
+			applied, err := database.MigrateUp(db, 0, config.GetChainConfig().Decimals)
+			if err != nil {
+				a.Close()
+				return nil, fmt.Errorf("failed to migrate database: %v", err)
+			}
+			for _, m := range applied {
+				log.Printf("Applied migration %d_%s", m.Version, m.Name)
+			}
+		}
+		a.store = database.NewBlockchainMySQL(db)
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", dbConfig.Driver)
+	}
+
+	// 初始化加密 keystore
+	ksConfig := config.GetKeyStoreConfig()
+	ks := keystore.NewKeyStore(ksConfig.Dir, ksConfig.ScryptN, ksConfig.ScryptP)
+
+	a.bc = blockchain.NewBlockchain(a.store, ks, config.GetChainConfig(), config.GetMiningConfig())
+
+	// 补全升级前已上链交易的哈希
+	filled, err := a.bc.BackfillTransactionHashes()
+	if err != nil {
+		a.Close()
+		return nil, fmt.Errorf("failed to backfill transaction hashes: %v", err)
+	}
+	if filled > 0 {
+		log.Printf("Backfilled hashes of %d transactions", filled)
+	}
+
+	// 检查是否有创世区块，如果没有则创建
+	latestBlock, err := a.store.GetLatestBlock()
+	if err != nil {
+		genesis, err := a.bc.CreateGenesisBlock()
+		if err != nil {
+			a.Close()
+			return nil, fmt.Errorf("failed to create genesis block: %v", err)
+		}
+		log.Printf("Created genesis block: %s", genesis.Hash)
+	} else {
+		log.Printf("Latest block: Index=%d, Hash=%s", latestBlock.Index, latestBlock.Hash)
+	}
+
+	return a, nil
+}
+
+// Close 关闭数据库连接池
+func (a *app) Close() error {
+	if a.db == nil {
+		return nil
+	}
+	return a.db.Close()
+}
@@ -0,0 +1,172 @@
+package apperr
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// Kind 错误类别，决定接口返回的 HTTP 状态码
+type Kind int
+
+const (
+	// Internal 未分类的错误，通常是程序或存储故障
+	Internal Kind = iota
+	// Validation 请求参数或交易内容无效
+	Validation
+	// NotFound 钱包、区块、交易等资源不存在
+	NotFound
+	// Conflict 与当前状态冲突，例如重复提交、钱包未解锁
+	Conflict
+	// InsufficientFunds 余额不足
+	InsufficientFunds
+	// Unavailable 依赖的服务暂时不可用，例如数据库连接失败、挖矿超时，可以稍后重试
+	Unavailable
+	// RateLimited 请求过于频繁或超出限额，稍后可以重试
+	RateLimited
+)
+
+// 各类错误默认的 error_code，更具体的错误可以使用自己的错误码
+const (
+	CodeInternal          = "internal_error"
+	CodeInvalidRequest    = "invalid_request"
+	CodeNotFound          = "not_found"
+	CodeConflict          = "conflict"
+	CodeInsufficientFunds = "insufficient_funds"
+	CodeUnavailable       = "unavailable"
+	CodeRateLimited       = "rate_limited"
+)
+
+// 具体的错误码，作为接口的一部分保持稳定
+const (
+	CodeWalletNotFound         = "wallet_not_found"
+	CodeBlockNotFound          = "block_not_found"
+	CodeTransactionNotFound    = "transaction_not_found"
+	CodeWebhookNotFound        = "webhook_not_found"
+	CodeInvalidTransaction     = "invalid_transaction"
+	CodeInvalidSignature       = "invalid_signature"
+	CodeInvalidPassphrase      = "invalid_passphrase"
+	CodeDuplicateTransaction   = "duplicate_transaction"
+	CodeNonceTooLow            = "nonce_too_low"
+	CodeNonceTooHigh           = "nonce_too_high"
+	CodeWalletLocked           = "wallet_locked"
+	CodeStaleTip               = "stale_tip"
+	CodeMiningTimeout          = "mining_timeout"
+	CodeFaucetDisabled         = "faucet_disabled"
+	CodeFaucetCapReached       = "faucet_cap_reached"
+	CodeInvalidChallenge       = "invalid_challenge"
+	CodeUnsupportedContentType = "unsupported_content_type"
+)
+
+// Error 带类别和错误码的错误，Err 为原始错误，可通过 errors.Is / errors.As 判断
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return e.Code
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New 创建错误，code 为空时使用类别的默认错误码
+func New(kind Kind, code, format string, args ...interface{}) *Error {
+	return &Error{Kind: kind, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// NewNotFound 资源不存在的错误，原始错误为 sql.ErrNoRows，调用方仍可以用 errors.Is(err, sql.ErrNoRows) 判断
+func NewNotFound(code, format string, args ...interface{}) *Error {
+	return &Error{Kind: NotFound, Code: code, Message: fmt.Sprintf(format, args...), Err: sql.ErrNoRows}
+}
+
+// Wrap 为已有错误标注类别和错误码，err 为 nil 时返回 nil
+func Wrap(kind Kind, code string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Code: code, Err: err}
+}
+
+// KindOf 返回错误的类别，未标注类别的错误按常见的存储和网络错误推断
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return Internal
+	case errors.Is(err, sql.ErrNoRows):
+		return NotFound
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone),
+		errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr):
+		return Unavailable
+	default:
+		return Internal
+	}
+}
+
+// CodeOf 返回错误的 error_code
+func CodeOf(err error) string {
+	var e *Error
+	if errors.As(err, &e) && e.Code != "" {
+		return e.Code
+	}
+	return defaultCode(KindOf(err))
+}
+
+func defaultCode(kind Kind) string {
+	switch kind {
+	case Validation:
+		return CodeInvalidRequest
+	case NotFound:
+		return CodeNotFound
+	case Conflict:
+		return CodeConflict
+	case InsufficientFunds:
+		return CodeInsufficientFunds
+	case Unavailable:
+		return CodeUnavailable
+	case RateLimited:
+		return CodeRateLimited
+	default:
+		return CodeInternal
+	}
+}
+
+// HTTPStatus 错误类别对应的 HTTP 状态码
+func HTTPStatus(err error) int {
+	switch KindOf(err) {
+	case Validation:
+		return http.StatusBadRequest
+	case NotFound:
+		return http.StatusNotFound
+	case Conflict:
+		return http.StatusConflict
+	case InsufficientFunds:
+		return http.StatusUnprocessableEntity
+	case Unavailable:
+		return http.StatusServiceUnavailable
+	case RateLimited:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -1,19 +1,51 @@
 package blockchain
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"hello-go/apperr"
+	"hello-go/config"
 	"hello-go/models"
 	"log"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
 )
 
 type Blockchain struct {
-	db Database
+	db       Database
+	mempool  *Mempool
+	keystore *keystore.KeyStore
+	chain    *config.ChainConfig
+	mining   *config.MiningConfig
+	// 第一个减半周期内的出块奖励（最小单位）
+	blockReward models.Amount
+
+	// 提交交易时的余额检查与入池需要原子执行
+	submitMu sync.Mutex
+	// 同一时间只允许一个挖矿任务
+	mineMu sync.Mutex
+	// 串行化区块写入，挖出的区块和从其他节点导入的区块都需要持有
+	chainMu sync.Mutex
+	// 链头变化时通知正在进行的挖矿任务停止
+	tip tipNotifier
+
+	listenersMu      sync.RWMutex
+	blockListeners   []BlockListener
+	txListeners      []TransactionListener
+	balanceListeners []BalanceListener
+
+	statsMu sync.Mutex
+	stats   MinerStats
+
+	// 禁止使用 keystore 代签的地址，例如自行持有私钥的水龙头钱包
+	reservedMu sync.RWMutex
+	reserved   map[string]bool
 }
 
 type Database interface {
@@ -21,39 +53,100 @@ type Database interface {
 	GetBlockByIndex(index int) (*models.Block, error)
 	GetLatestBlock() (*models.Block, error)
 	GetAllBlocks() ([]*models.Block, error)
+	GetBlockByID(id int64) (*models.Block, error)
+	GetBlockByHash(hash string) (*models.Block, error)
+	// GetBlocksByRange 按高度升序获取高度在 [from, to] 内的主链区块
+	GetBlocksByRange(from, to int) ([]*models.Block, error)
+	// GetTransactionsByBlockRange 获取高度在 [from, to] 内的主链区块中的全部交易，同一区块内按打包顺序返回
+	GetTransactionsByBlockRange(from, to int) ([]*models.Transaction, error)
+	// CommitBlock 在同一个事务中保存区块、创建交易涉及的本地不存在的钱包（coinbase 地址除外）
+	// 并执行其中的全部交易，任一交易失败则整体回滚
+	CommitBlock(block *models.Block, txs []*models.Transaction) error
+	// RollbackBlock 撤销链头区块及其交易的余额变动，返回被撤销的交易
+	RollbackBlock(block *models.Block) ([]*models.Transaction, error)
+	// 侧链区块：未在主链上的竞争分支，链重组时可能切换为主链
+	SaveSideBlock(block *models.Block, txs []*models.Transaction) error
+	GetSideBlock(hash string) (*models.Block, []*models.Transaction, error)
+	DeleteSideBlock(hash string) error
 	SaveTransaction(tx *models.Transaction) error
+	GetTransactionByID(id int64) (*models.Transaction, error)
 	GetTransactionsByBlockID(blockID int64) ([]*models.Transaction, error)
+	// GetTransactionByHash 根据哈希获取已上链交易
+	GetTransactionByHash(hash string) (*models.Transaction, error)
+	// GetTransactionsWithoutHash 按ID升序获取ID大于 afterID 且尚未保存哈希的交易，用于补全升级前的交易
+	GetTransactionsWithoutHash(afterID int64, limit int) ([]*models.Transaction, error)
+	// SetTransactionHash 保存已有交易的哈希，哈希重复时返回 duplicate_transaction 错误
+	SetTransactionHash(id int64, hash string) error
+	// GetTransactionsByAddress 获取地址转出和转入的已上链交易，最新的在前
+	GetTransactionsByAddress(address string) ([]*models.Transaction, error)
+	// GetTransactions 分页获取已上链交易，最新的在前
+	GetTransactions(limit, offset int) ([]*models.Transaction, error)
+	// CountTransactions 统计已上链交易总数
+	CountTransactions() (int, error)
+	// GetNonce 钱包的下一个交易序号，即已上链的转出交易数
+	GetNonce(address string) (uint64, error)
 	SaveWallet(*models.Wallet) error
-	TopUpWallet(address string) error
-	Transfer(addressFrom string, addressTo string, balance float64) error
-	GetBalance(address string) (float64, error)
+	Transfer(tx *models.Transaction) error
+	GetBalance(address string) (models.Amount, error)
+	// GetTotalSupply 统计全部钱包的余额之和
+	GetTotalSupply() (models.Amount, error)
+}
+
+func NewBlockchain(db Database, ks *keystore.KeyStore, chain *config.ChainConfig, mining *config.MiningConfig) *Blockchain {
+	// 加载配置时已校验出块奖励的格式
+	blockReward, _ := models.ParseUnits(chain.BlockReward, chain.Decimals)
+
+	return &Blockchain{
+		db:          db,
+		mempool:     NewMempool(),
+		keystore:    ks,
+		chain:       chain,
+		mining:      mining,
+		blockReward: blockReward,
+		reserved:    make(map[string]bool),
+	}
+}
+
+// ChainID 当前链ID
+func (bc *Blockchain) ChainID() uint64 {
+	return bc.chain.ChainID
+}
+
+// Decimals 金额的小数位数
+func (bc *Blockchain) Decimals() int {
+	return bc.chain.Decimals
 }
 
-func NewBlockchain(db Database) *Blockchain {
-	return &Blockchain{db: db}
+// 创世区块的数据内容
+const genesisData = "Genesis Block"
+
+// headerRecord 参与区块哈希计算的区块头字段
+// 时间戳按秒级 Unix 时间参与计算，保证区块从数据库读回后哈希不变
+func headerRecord(block *models.Block) string {
+	return fmt.Sprintf("%d%d%s%s%s%d%d",
+		block.Index, block.Timestamp.Unix(), block.Data, block.MerkleRoot,
+		block.PrevHash, block.Nonce, block.Difficulty)
 }
 
 // 计算区块哈希
 func calculateHash(block *models.Block) string {
-	record := fmt.Sprintf("%d%s%s%s%d%d",
-		block.Index, block.Timestamp, block.Data,
-		block.PrevHash, block.Nonce, block.Difficulty)
 	h := sha256.New()
-	h.Write([]byte(record))
+	h.Write([]byte(headerRecord(block)))
 	hashed := h.Sum(nil)
 	return hex.EncodeToString(hashed)
 }
 
-// 创建创世区块
+// 创建创世区块，创世区块的内容完全由配置决定，相同配置的节点生成相同的创世区块
 func (bc *Blockchain) CreateGenesisBlock() (*models.Block, error) {
 	genesis := &models.Block{
 		Index:      0,
 		Hash:       "",
 		PrevHash:   "",
-		Data:       "Genesis Block",
-		Timestamp:  time.Now(),
+		Data:       genesisData,
+		MerkleRoot: emptyMerkleRoot,
+		Timestamp:  bc.chain.GenesisTime,
 		Nonce:      0,
-		Difficulty: 4,
+		Difficulty: bc.mining.InitialDifficulty,
 	}
 	genesis.Hash = calculateHash(genesis)
 
@@ -64,115 +157,530 @@ func (bc *Blockchain) CreateGenesisBlock() (*models.Block, error) {
 	return genesis, nil
 }
 
-// 创建新区块
-func (bc *Blockchain) CreateNewBlock(data string, difficulty int) (*models.Block, error) {
+// 创建新区块，难度根据之前区块的出块时间自动调整
+func (bc *Blockchain) CreateNewBlock(ctx context.Context, data string) (*models.Block, error) {
+	bc.mineMu.Lock()
+	defer bc.mineMu.Unlock()
+
+	block, _, err := bc.createBlock(ctx, data, nil)
+	return block, err
+}
+
+// createBlock 在最新区块之后打包交易、挖出并保存新区块，调用方需持有 mineMu
+// 配置了矿工地址时在区块首位加入 coinbase 交易，返回区块及其中的全部交易
+func (bc *Blockchain) createBlock(ctx context.Context, data string, txs []*models.Transaction) (*models.Block, []*models.Transaction, error) {
 	prevBlock, err := bc.db.GetLatestBlock()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	difficulty, err := bc.NextDifficulty(prevBlock)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	index := prevBlock.Index + 1
+	coinbase, err := bc.newCoinbase(index, txs)
+	if err != nil {
+		return nil, nil, err
+	}
+	if coinbase != nil {
+		txs = append([]*models.Transaction{coinbase}, txs...)
+	}
+
+	merkleRoot, err := ComputeMerkleRoot(txs)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	block := &models.Block{
-		Index:      prevBlock.Index + 1,
+		Index:      index,
 		PrevHash:   prevBlock.Hash,
 		Data:       data,
-		Timestamp:  time.Now(),
+		MerkleRoot: merkleRoot,
+		Timestamp:  time.Now().Truncate(time.Second),
 		Nonce:      0,
 		Difficulty: difficulty,
 	}
 
 	// 挖矿
-	block = bc.mineBlock(block)
+	block, err = bc.mineBlock(ctx, block)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := bc.commitBlock(block, txs); err != nil {
+		return nil, nil, err
+	}
+
+	return block, txs, nil
+}
+
+// commitBlock 将区块接到当前链头之后保存，链头已经变化时返回 ErrStaleTip
+func (bc *Blockchain) commitBlock(block *models.Block, txs []*models.Transaction) error {
+	if err := setHashes(txs); err != nil {
+		return err
+	}
+
+	bc.chainMu.Lock()
+	latest, err := bc.db.GetLatestBlock()
+	if err != nil {
+		bc.chainMu.Unlock()
+		return err
+	}
+	if latest.Hash != block.PrevHash {
+		bc.chainMu.Unlock()
+		return ErrStaleTip
+	}
+
+	err = bc.db.CommitBlock(block, txs)
+	bc.chainMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	bc.afterCommit(block, txs)
+	return nil
+}
+
+// afterCommit 区块上链后通知挖矿任务、清理交易池并通知区块和余额监听者
+func (bc *Blockchain) afterCommit(block *models.Block, txs []*models.Transaction) {
+	bc.tip.notify()
+	bc.mempool.Remove(txs)
+	bc.emitBlock(block, txs)
+	bc.emitBalances(txs)
+}
+
+// GetBlockByIndex 根据索引获取主链区块
+func (bc *Blockchain) GetBlockByIndex(index int) (*models.Block, error) {
+	return bc.db.GetBlockByIndex(index)
+}
+
+// GetLatestBlock 获取主链链头区块
+func (bc *Blockchain) GetLatestBlock() (*models.Block, error) {
+	return bc.db.GetLatestBlock()
+}
+
+// GetAllBlocks 按高度顺序获取主链全部区块
+func (bc *Blockchain) GetAllBlocks() ([]*models.Block, error) {
+	return bc.db.GetAllBlocks()
+}
+
+// GetTransactionsByBlockID 按打包顺序获取区块中的交易
+func (bc *Blockchain) GetTransactionsByBlockID(blockID int64) ([]*models.Transaction, error) {
+	return bc.db.GetTransactionsByBlockID(blockID)
+}
+
+// GetTransactionsByAddress 获取地址转出和转入的已上链交易，最新的在前
+func (bc *Blockchain) GetTransactionsByAddress(address string) ([]*models.Transaction, error) {
+	return bc.db.GetTransactionsByAddress(address)
+}
+
+// GetTransactions 分页获取已上链交易，最新的在前，同时返回交易总数
+func (bc *Blockchain) GetTransactions(limit, offset int) ([]*models.Transaction, int, error) {
+	total, err := bc.db.CountTransactions()
+	if err != nil {
+		return nil, 0, err
+	}
+	txs, err := bc.db.GetTransactions(limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	return txs, total, nil
+}
+
+// TransactionLocation 交易及其在链上的位置，交易仍在交易池中时 Block 为 nil
+type TransactionLocation struct {
+	Transaction *models.Transaction
+	Block       *models.Block
+	Index       int
+}
+
+// GetTransactionByHash 根据交易哈希查找交易，先查交易池，再按哈希查询已上链交易
+// 找不到时返回 NotFound 错误
+func (bc *Blockchain) GetTransactionByHash(hash common.Hash) (*TransactionLocation, error) {
+	if tx, ok := bc.mempool.Get(hash.Hex()); ok {
+		return &TransactionLocation{Transaction: tx}, nil
+	}
+
+	tx, err := bc.db.GetTransactionByHash(hash.Hex())
+	if err != nil {
+		return nil, err
+	}
+	block, err := bc.db.GetBlockByID(tx.BlockID)
+	if err != nil {
+		return nil, err
+	}
+	txs, err := bc.db.GetTransactionsByBlockID(block.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i, t := range txs {
+		if t.ID == tx.ID {
+			return &TransactionLocation{Transaction: tx, Block: block, Index: i}, nil
+		}
+	}
+	return nil, fmt.Errorf("transaction %s not found in block %d", hash.Hex(), block.Index)
+}
+
+// TransactionStatusConfirmed 交易已上链
+const TransactionStatusConfirmed = "confirmed"
+
+// TransactionInfo 交易及其状态，Status 为 pending、queued（交易池中，等待前面的序号补齐）或 confirmed
+type TransactionInfo struct {
+	Transaction *models.Transaction `json:"transaction"`
+	Status      string              `json:"status"`
+	// Block 交易所在区块，Position 为交易在区块中的位置，交易池中的交易不返回
+	Block    *models.Block `json:"block,omitempty"`
+	Position *int          `json:"position,omitempty"`
+	// Confirmations 交易所在区块及其后的区块数，交易池中的交易为 0
+	Confirmations int `json:"confirmations"`
+}
 
-	if err := bc.db.SaveBlock(block); err != nil {
+// GetTransactionInfo 根据交易哈希查询交易、所在区块、确认数和状态
+func (bc *Blockchain) GetTransactionInfo(hash common.Hash) (*TransactionInfo, error) {
+	location, err := bc.GetTransactionByHash(hash)
+	if err != nil {
 		return nil, err
 	}
+	tx := location.Transaction
+
+	if location.Block == nil {
+		confirmed, err := bc.db.GetNonce(tx.FromAddr)
+		if err != nil {
+			return nil, err
+		}
+		status := TransferStatusPending
+		if tx.Nonce >= bc.mempool.NextNonce(tx.FromAddr, confirmed) {
+			status = TransferStatusQueued
+		}
+		return &TransactionInfo{Transaction: tx, Status: status}, nil
+	}
 
-	return block, nil
+	latest, err := bc.db.GetLatestBlock()
+	if err != nil {
+		return nil, err
+	}
+	return &TransactionInfo{
+		Transaction:   tx,
+		Status:        TransactionStatusConfirmed,
+		Block:         location.Block,
+		Position:      &location.Index,
+		Confirmations: latest.Index - location.Block.Index + 1,
+	}, nil
 }
 
-// 挖矿
-func (bc *Blockchain) mineBlock(block *models.Block) *models.Block {
-	target := strings.Repeat("0", block.Difficulty)
+// 每批补全哈希的交易数
+const backfillBatchSize = 500
 
+// BackfillTransactionHashes 为引入交易哈希之前上链的交易补全哈希，返回补全的交易数
+// 重放产生的重复交易与先上链的交易哈希相同，保持为空，只能通过ID查询
+func (bc *Blockchain) BackfillTransactionHashes() (int, error) {
+	var afterID int64
+	filled := 0
 	for {
-		block.Hash = calculateHash(block)
-		if strings.HasPrefix(block.Hash, target) {
-			break
+		txs, err := bc.db.GetTransactionsWithoutHash(afterID, backfillBatchSize)
+		if err != nil {
+			return filled, err
+		}
+		if len(txs) == 0 {
+			return filled, nil
+		}
+
+		for _, tx := range txs {
+			afterID = tx.ID
+			hash, err := TransactionHash(tx)
+			if err != nil {
+				log.Printf("无法计算交易 %d 的哈希: %v", tx.ID, err)
+				continue
+			}
+			err = bc.db.SetTransactionHash(tx.ID, hash.Hex())
+			if apperr.CodeOf(err) == apperr.CodeDuplicateTransaction {
+				log.Printf("交易 %d 与已有交易的哈希 %s 重复，保持为空", tx.ID, hash.Hex())
+				continue
+			}
+			if err != nil {
+				return filled, err
+			}
+			filled++
 		}
-		block.Nonce++
 	}
+}
 
-	return block
+// GetNonce 地址的下一个交易序号，即已上链的转出交易数
+// pending 为 true 时跳过交易池中连续占用的序号，返回下一笔交易应使用的序号
+func (bc *Blockchain) GetNonce(address string, pending bool) (uint64, error) {
+	nonce, err := bc.db.GetNonce(address)
+	if err != nil {
+		return 0, err
+	}
+	if pending {
+		nonce = bc.mempool.NextNonce(address, nonce)
+	}
+	return nonce, nil
 }
 
-// 验证区块链
-func (bc *Blockchain) ValidateChain() (bool, error) {
-	blocks, err := bc.db.GetAllBlocks()
+// TransactionProof 交易的 Merkle 包含证明
+type TransactionProof struct {
+	Transaction *models.Transaction `json:"transaction"`
+	BlockID     int64               `json:"block_id"`
+	BlockIndex  int                 `json:"block_index"`
+	BlockHash   string              `json:"block_hash"`
+	MerkleRoot  string              `json:"merkle_root"`
+	Leaf        string              `json:"leaf"`
+	LeafIndex   int                 `json:"leaf_index"`
+	Proof       []MerkleProofStep   `json:"proof"`
+	Verified    bool                `json:"verified"`
+}
+
+// GetTransactionProof 生成已上链交易的 Merkle 包含证明
+func (bc *Blockchain) GetTransactionProof(txID int64) (*TransactionProof, error) {
+	tx, err := bc.db.GetTransactionByID(txID)
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+	if tx.BlockID == 0 {
+		return nil, apperr.NewNotFound(apperr.CodeTransactionNotFound, "transaction %d is not included in any block", txID)
 	}
 
-	for i := 1; i < len(blocks); i++ {
-		currentBlock := blocks[i]
-		prevBlock := blocks[i-1]
+	block, err := bc.db.GetBlockByID(tx.BlockID)
+	if err != nil {
+		return nil, err
+	}
+	txs, err := bc.db.GetTransactionsByBlockID(block.ID)
+	if err != nil {
+		return nil, err
+	}
 
-		// 验证哈希
-		if currentBlock.PrevHash != prevBlock.Hash {
-			return false, nil
+	leafIndex := -1
+	for i, t := range txs {
+		if t.ID == tx.ID {
+			leafIndex = i
+			break
 		}
+	}
+	if leafIndex < 0 {
+		return nil, fmt.Errorf("transaction %d not found in block %d", txID, block.ID)
+	}
 
-		// 验证当前区块哈希
-		if currentBlock.Hash != calculateHash(currentBlock) {
-			return false, nil
-		}
+	leaf, proof, err := BuildMerkleProof(txs, leafIndex)
+	if err != nil {
+		return nil, err
 	}
 
-	return true, nil
+	return &TransactionProof{
+		Transaction: tx,
+		BlockID:     block.ID,
+		BlockIndex:  block.Index,
+		BlockHash:   block.Hash,
+		MerkleRoot:  block.MerkleRoot,
+		Leaf:        leaf,
+		LeafIndex:   leafIndex,
+		Proof:       proof,
+		Verified:    VerifyMerkleProof(leaf, proof, block.MerkleRoot),
+	}, nil
 }
 
-func (bc *Blockchain) CreateNewWallet() (*models.Wallet, error) {
-	privateKey, err := crypto.GenerateKey()
+// CreateNewWallet 创建新钱包，私钥使用口令加密后保存在 keystore 中，不会写入数据库
+func (bc *Blockchain) CreateNewWallet(passphrase string) (*models.Wallet, error) {
+	account, err := bc.keystore.NewAccount(passphrase)
 	if err != nil {
 		return nil, err
 	}
-	privateKeyBytes := crypto.FromECDSA(privateKey)
-	address := crypto.PubkeyToAddress(privateKey.PublicKey).Hex()
 	wallet := &models.Wallet{
-		Address:    address,
-		PrivateKey: fmt.Sprintf("%x", privateKeyBytes),
-		Balance:    0,
+		Address: account.Address.Hex(),
 	}
 
 	if err := bc.db.SaveWallet(wallet); err != nil {
+		// 钱包未写入数据库时删除刚创建的 keystore 文件，避免留下无法使用的账户
+		if delErr := bc.keystore.Delete(account, passphrase); delErr != nil {
+			log.Println("删除 keystore 账户失败:", delErr)
+		}
 		return nil, err
 	}
 
 	return wallet, nil
 }
 
-func (bc *Blockchain) TopUpWallet(address string) error {
-	err := bc.db.TopUpWallet(address)
+// 转账回执的状态
+const (
+	// TransferStatusPending 交易已进入交易池，等待打包上链
+	TransferStatusPending = "pending"
+	// TransferStatusQueued 交易序号不连续，在交易池中等待前面的序号补齐后才会打包
+	TransferStatusQueued = "queued"
+)
+
+// 交易序号最多可以超前下一个可用序号的数量，避免单个地址在交易池中排队过多交易
+const maxNonceGap = 64
+
+// TransferReceipt 转账回执
+// FromBalance 和 ToBalance 为交易池中可以执行的交易（包括本笔）全部上链后双方的预计余额，
+// 序号不连续而排队（queued）的交易可能永远不会上链，不计入
+type TransferReceipt struct {
+	Hash        string        `json:"hash"`
+	Status      string        `json:"status"`
+	FromAddress string        `json:"from_address"`
+	ToAddress   string        `json:"to_address"`
+	Amount      models.Amount `json:"amount"`
+	Fee         models.Amount `json:"fee"`
+	Nonce       uint64        `json:"nonce"`
+	Timestamp   time.Time     `json:"timestamp"`
+	FromBalance models.Amount `json:"from_balance"`
+	ToBalance   models.Amount `json:"to_balance"`
+}
+
+// Transfer 校验已签名的转账交易并提交到交易池，等待矿工打包上链
+// 任何一步失败都返回错误，此时交易不会进入交易池
+func (bc *Blockchain) Transfer(tx *models.Transaction) (*TransferReceipt, error) {
+	return bc.submit(tx, true)
+}
+
+// ImportTransaction 校验其他节点广播的已签名交易并加入交易池
+// 钱包由各节点各自创建，收款地址在本地可以没有钱包，交易上链时创建；coinbase 地址不会创建钱包，不能作为收款地址
+func (bc *Blockchain) ImportTransaction(tx *models.Transaction) (*TransferReceipt, error) {
+	return bc.submit(tx, false)
+}
+
+// isCoinbaseAddress 判断地址是否为 coinbase 交易的转出地址
+func isCoinbaseAddress(address string) bool {
+	return strings.EqualFold(address, models.CoinbaseAddress)
+}
+
+// submit 校验交易并加入交易池，requireRecipient 为 true 时要求收款地址在本地已有钱包
+func (bc *Blockchain) submit(tx *models.Transaction, requireRecipient bool) (*TransferReceipt, error) {
+	if err := VerifyTransaction(tx, bc.chain.ChainID); err != nil {
+		log.Println("转账失败:", err)
+		return nil, err
+	}
+	hash, err := TransactionHash(tx)
 	if err != nil {
-		fmt.Printf("TopUpWallet error: %v\n", err)
-		return err
+		log.Println("转账失败:", err)
+		return nil, err
+	}
+
+	bc.submitMu.Lock()
+	defer bc.submitMu.Unlock()
+
+	fromBalance, err := bc.db.GetBalance(tx.FromAddr)
+	if err != nil {
+		log.Println("转账失败:", err)
+		return nil, err
+	}
+	toBalance, err := bc.db.GetBalance(tx.ToAddr)
+	switch {
+	case err == nil:
+	case apperr.KindOf(err) != apperr.NotFound:
+		log.Println("转账失败:", err)
+		return nil, err
+	case requireRecipient || isCoinbaseAddress(tx.ToAddr):
+		log.Println("转账失败: 收款钱包不存在:", tx.ToAddr)
+		return nil, apperr.New(apperr.NotFound, apperr.CodeWalletNotFound, "收款钱包不存在: %s", tx.ToAddr)
 	}
-	return nil
-}
 
-func (bc *Blockchain) Transfer(addressFrom string, addressTo string, balance float64) error {
-	err := bc.db.Transfer(addressFrom, addressTo, balance)
+	// 已上链的序号不能再次使用，防止重放
+	confirmed, err := bc.db.GetNonce(tx.FromAddr)
 	if err != nil {
 		log.Println("转账失败:", err)
-	} else {
-		log.Println("转账成功")
+		return nil, err
 	}
-	return nil
+	if tx.Nonce < confirmed {
+		log.Println("转账失败: 交易序号已使用")
+		return nil, apperr.New(apperr.Conflict, apperr.CodeNonceTooLow,
+			"nonce %d of %s has already been used, next nonce is %d", tx.Nonce, tx.FromAddr, confirmed)
+	}
+	next := bc.mempool.NextNonce(tx.FromAddr, confirmed)
+	if tx.Nonce >= next+maxNonceGap {
+		log.Println("转账失败: 交易序号超前过多")
+		return nil, apperr.New(apperr.Conflict, apperr.CodeNonceTooHigh,
+			"nonce %d of %s is too far ahead, next nonce is %d", tx.Nonce, tx.FromAddr, next)
+	}
+
+	// 可用余额需扣除交易池中尚未打包的转出金额和手续费
+	cost, ok := tx.Amount.Add(tx.Fee)
+	if !ok {
+		log.Println("转账失败: 金额加手续费溢出")
+		return nil, invalidTransaction("amount plus fee overflows")
+	}
+	available, ok := fromBalance.Sub(bc.mempool.PendingOutgoing(tx.FromAddr))
+	if !ok || available.Cmp(cost) < 0 {
+		log.Println("转账失败: 余额不足")
+		return nil, apperr.New(apperr.InsufficientFunds, "", "余额不足")
+	}
+
+	tx.Hash = hash.Hex()
+	tx.Timestamp = time.Now()
+	if err := bc.mempool.Add(tx); err != nil {
+		log.Println("转账失败:", err)
+		return nil, err
+	}
+	log.Println("转账已提交到交易池")
+	bc.emitTransaction(tx)
+
+	status := TransferStatusPending
+	if tx.Nonce > next {
+		status = TransferStatusQueued
+	}
+	return &TransferReceipt{
+		Hash:        hash.Hex(),
+		Status:      status,
+		FromAddress: tx.FromAddr,
+		ToAddress:   tx.ToAddr,
+		Amount:      tx.Amount,
+		Fee:         tx.Fee,
+		Nonce:       tx.Nonce,
+		Timestamp:   tx.Timestamp,
+		FromBalance: bc.pendingBalance(tx.FromAddr, fromBalance),
+		ToBalance:   bc.pendingBalance(tx.ToAddr, toBalance),
+	}, nil
+}
+
+// pendingBalance 已上链余额加上交易池中可执行交易的转入、减去转出，即这些交易全部上链后的余额
+// 可执行的交易指转出地址从已上链序号开始、序号连续的交易；无法查询转出地址序号时按不可执行处理
+// 提交交易时已保证转出总额不超过已上链余额，因此不会下溢
+func (bc *Blockchain) pendingBalance(address string, confirmed models.Amount) models.Amount {
+	address = poolAddress(address)
+
+	// 各转出地址可执行的序号范围 [first, next)
+	type nonceRange struct{ first, next uint64 }
+	ranges := make(map[string]*nonceRange)
+	executable := func(tx *models.Transaction) bool {
+		r, ok := ranges[tx.FromAddr]
+		if !ok {
+			if first, err := bc.db.GetNonce(tx.FromAddr); err == nil {
+				r = &nonceRange{first: first, next: bc.mempool.NextNonce(tx.FromAddr, first)}
+			}
+			ranges[tx.FromAddr] = r
+		}
+		return r != nil && tx.Nonce >= r.first && tx.Nonce < r.next
+	}
+
+	balance := confirmed
+	var outgoing models.Amount
+	for _, tx := range bc.mempool.Involving(address) {
+		if !executable(tx) {
+			continue
+		}
+		if tx.ToAddr == address {
+			balance, _ = balance.Add(tx.Amount)
+		}
+		if tx.FromAddr == address {
+			outgoing, _ = outgoing.Add(tx.Amount)
+			outgoing, _ = outgoing.Add(tx.Fee)
+		}
+	}
+	balance, _ = balance.Sub(outgoing)
+	return balance
 }
 
-func (bc *Blockchain) GetBalance(address string) (float64, error) {
+// PendingTransactions 获取交易池中等待打包的交易
+func (bc *Blockchain) PendingTransactions() []*models.Transaction {
+	return bc.mempool.Pending()
+}
+
+func (bc *Blockchain) GetBalance(address string) (models.Amount, error) {
 	balance, err := bc.db.GetBalance(address)
 	if err != nil {
-		return 0, err
+		return models.Amount{}, err
 	}
 	return balance, nil
 }
@@ -0,0 +1,319 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"hello-go/apperr"
+	"hello-go/config"
+	"hello-go/database"
+	"hello-go/models"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const testChainID = 1337
+
+func newTestBlockchain(t *testing.T, db Database) (*Blockchain, *keystore.KeyStore) {
+	t.Helper()
+
+	ks := keystore.NewKeyStore(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP)
+	chain := &config.ChainConfig{ChainID: testChainID, GenesisTime: time.Unix(0, 0), Decimals: 18, BlockReward: "0"}
+	mining := &config.MiningConfig{InitialDifficulty: 1, MinDifficulty: 1, MaxDifficulty: 1, Workers: 1, Timeout: 10 * time.Second}
+	bc := NewBlockchain(db, ks, chain, mining)
+	if _, err := bc.CreateGenesisBlock(); err != nil {
+		t.Fatal(err)
+	}
+	return bc, ks
+}
+
+func TestTransferRejectsInvalidTransactions(t *testing.T) {
+	const confirmedNonce = 5
+	recipient := "0x00000000000000000000000000000000000000b0"
+
+	tests := []struct {
+		name string
+		// setup 在提交交易前修改链状态
+		setup func(t *testing.T, bc *Blockchain, tx *models.Transaction)
+		// modify 在签名前修改交易字段
+		modify func(tx *models.Transaction)
+		// signer 不为空时用该私钥签名，而不是转出钱包的私钥
+		signer   *ecdsa.PrivateKey
+		wantKind apperr.Kind
+		wantCode string
+	}{
+		{
+			name:     "unknown recipient",
+			modify:   func(tx *models.Transaction) { tx.ToAddr = "0x00000000000000000000000000000000000000c0" },
+			wantKind: apperr.NotFound,
+			wantCode: apperr.CodeWalletNotFound,
+		},
+		{
+			name:     "insufficient funds",
+			modify:   func(tx *models.Transaction) { tx.Amount = models.NewAmount(101) },
+			wantKind: apperr.InsufficientFunds,
+			wantCode: apperr.CodeInsufficientFunds,
+		},
+		{
+			name:     "insufficient funds with fee",
+			modify:   func(tx *models.Transaction) { tx.Amount, tx.Fee = models.NewAmount(100), models.NewAmount(1) },
+			wantKind: apperr.InsufficientFunds,
+			wantCode: apperr.CodeInsufficientFunds,
+		},
+		{
+			name:     "bad signature",
+			signer:   mustGenerateKey(t),
+			wantKind: apperr.Validation,
+			wantCode: apperr.CodeInvalidSignature,
+		},
+		{
+			name:     "wrong chain id",
+			modify:   func(tx *models.Transaction) { tx.ChainID = testChainID + 1 },
+			wantKind: apperr.Validation,
+			wantCode: apperr.CodeInvalidTransaction,
+		},
+		{
+			name:     "nonce too low",
+			modify:   func(tx *models.Transaction) { tx.Nonce = confirmedNonce - 1 },
+			wantKind: apperr.Conflict,
+			wantCode: apperr.CodeNonceTooLow,
+		},
+		{
+			name:     "nonce too high",
+			modify:   func(tx *models.Transaction) { tx.Nonce = confirmedNonce + maxNonceGap },
+			wantKind: apperr.Conflict,
+			wantCode: apperr.CodeNonceTooHigh,
+		},
+		{
+			name: "duplicate submission",
+			setup: func(t *testing.T, bc *Blockchain, tx *models.Transaction) {
+				first := *tx
+				if _, err := bc.Transfer(&first); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantKind: apperr.Conflict,
+			wantCode: apperr.CodeDuplicateTransaction,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := database.NewBlockchainMemory()
+			bc, _ := newTestBlockchain(t, db)
+
+			key := mustGenerateKey(t)
+			sender := crypto.PubkeyToAddress(key.PublicKey).Hex()
+			if err := db.SaveWallet(&models.Wallet{Address: sender, Balance: models.NewAmount(100), Nonce: confirmedNonce}); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.SaveWallet(&models.Wallet{Address: recipient}); err != nil {
+				t.Fatal(err)
+			}
+
+			tx := &models.Transaction{
+				FromAddr: sender,
+				ToAddr:   recipient,
+				Amount:   models.NewAmount(10),
+				Nonce:    confirmedNonce,
+				ChainID:  testChainID,
+			}
+			if tt.modify != nil {
+				tt.modify(tx)
+			}
+			signer := key
+			if tt.signer != nil {
+				signer = tt.signer
+			}
+			if err := SignTransaction(tx, signer); err != nil {
+				t.Fatal(err)
+			}
+			if tt.setup != nil {
+				tt.setup(t, bc, tx)
+			}
+			pending := len(bc.PendingTransactions())
+
+			receipt, err := bc.Transfer(tx)
+			if err == nil {
+				t.Fatalf("Transfer succeeded with receipt %+v", receipt)
+			}
+			if kind, code := apperr.KindOf(err), apperr.CodeOf(err); kind != tt.wantKind || code != tt.wantCode {
+				t.Errorf("Transfer error = %v (kind %v, code %q), want kind %v, code %q", err, kind, code, tt.wantKind, tt.wantCode)
+			}
+			if got := len(bc.PendingTransactions()); got != pending {
+				t.Errorf("mempool has %d transactions after rejected transfer, want %d", got, pending)
+			}
+		})
+	}
+}
+
+func TestTransferAcceptsValidTransaction(t *testing.T) {
+	db := database.NewBlockchainMemory()
+	bc, _ := newTestBlockchain(t, db)
+
+	key := mustGenerateKey(t)
+	sender := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	recipient := "0x00000000000000000000000000000000000000b0"
+	if err := db.SaveWallet(&models.Wallet{Address: sender, Balance: models.NewAmount(100)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveWallet(&models.Wallet{Address: recipient}); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := &models.Transaction{FromAddr: sender, ToAddr: recipient, Amount: models.NewAmount(100), ChainID: testChainID}
+	if err := SignTransaction(tx, key); err != nil {
+		t.Fatal(err)
+	}
+	receipt, err := bc.Transfer(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Status != TransferStatusPending {
+		t.Errorf("status = %q, want %q", receipt.Status, TransferStatusPending)
+	}
+	if !receipt.FromBalance.IsZero() || receipt.ToBalance.Cmp(models.NewAmount(100)) != 0 {
+		t.Errorf("projected balances = %s / %s, want 0 / 100", receipt.FromBalance, receipt.ToBalance)
+	}
+}
+
+// TestTransferReceiptProjection 回执中的余额只计入交易池中可执行的交易，序号不连续而排队的交易不计入
+func TestTransferReceiptProjection(t *testing.T) {
+	// transfer 以钱包名描述的转账：a 余额 100，b 余额 50，r 为空钱包
+	type transfer struct {
+		from, to           string
+		amount, fee, nonce uint64
+	}
+
+	tests := []struct {
+		name string
+		// prior 在 final 之前提交到交易池的交易
+		prior      []transfer
+		final      transfer
+		wantStatus string
+		wantFrom   uint64
+		wantTo     uint64
+	}{
+		{
+			name:       "single transaction with fee",
+			final:      transfer{from: "a", to: "r", amount: 10, fee: 1},
+			wantStatus: TransferStatusPending, wantFrom: 89, wantTo: 10,
+		},
+		{
+			name:       "earlier transactions of sender",
+			prior:      []transfer{{from: "a", to: "r", amount: 10}},
+			final:      transfer{from: "a", to: "r", amount: 5, nonce: 1},
+			wantStatus: TransferStatusPending, wantFrom: 85, wantTo: 15,
+		},
+		{
+			name:       "queued transaction is not counted",
+			final:      transfer{from: "a", to: "r", amount: 10, nonce: 2},
+			wantStatus: TransferStatusQueued, wantFrom: 100, wantTo: 0,
+		},
+		{
+			name:       "filling the gap makes queued transactions executable",
+			prior:      []transfer{{from: "a", to: "r", amount: 10, nonce: 1}},
+			final:      transfer{from: "a", to: "r", amount: 5},
+			wantStatus: TransferStatusPending, wantFrom: 85, wantTo: 15,
+		},
+		{
+			name:       "incoming transaction of sender",
+			prior:      []transfer{{from: "b", to: "a", amount: 20}},
+			final:      transfer{from: "a", to: "r", amount: 10},
+			wantStatus: TransferStatusPending, wantFrom: 110, wantTo: 10,
+		},
+		{
+			name:       "queued incoming transaction is not counted",
+			prior:      []transfer{{from: "b", to: "a", amount: 20, nonce: 1}},
+			final:      transfer{from: "a", to: "r", amount: 10},
+			wantStatus: TransferStatusPending, wantFrom: 90, wantTo: 10,
+		},
+		{
+			name:       "outgoing transactions of recipient",
+			prior:      []transfer{{from: "b", to: "r", amount: 5, fee: 1}},
+			final:      transfer{from: "a", to: "b", amount: 10},
+			wantStatus: TransferStatusPending, wantFrom: 90, wantTo: 54,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := database.NewBlockchainMemory()
+			bc, _ := newTestBlockchain(t, db)
+
+			keys := map[string]*ecdsa.PrivateKey{"a": mustGenerateKey(t), "b": mustGenerateKey(t)}
+			addresses := map[string]string{
+				"a": crypto.PubkeyToAddress(keys["a"].PublicKey).Hex(),
+				"b": crypto.PubkeyToAddress(keys["b"].PublicKey).Hex(),
+				"r": "0x00000000000000000000000000000000000000b0",
+			}
+			balances := map[string]uint64{"a": 100, "b": 50, "r": 0}
+			for name, address := range addresses {
+				if err := db.SaveWallet(&models.Wallet{Address: address, Balance: models.NewAmount(balances[name])}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			submit := func(tr transfer) *TransferReceipt {
+				tx := &models.Transaction{
+					FromAddr: addresses[tr.from],
+					ToAddr:   addresses[tr.to],
+					Amount:   models.NewAmount(tr.amount),
+					Fee:      models.NewAmount(tr.fee),
+					Nonce:    tr.nonce,
+					ChainID:  testChainID,
+				}
+				if err := SignTransaction(tx, keys[tr.from]); err != nil {
+					t.Fatal(err)
+				}
+				receipt, err := bc.Transfer(tx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return receipt
+			}
+			for _, tr := range tt.prior {
+				submit(tr)
+			}
+			receipt := submit(tt.final)
+
+			if receipt.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", receipt.Status, tt.wantStatus)
+			}
+			if receipt.FromBalance.Cmp(models.NewAmount(tt.wantFrom)) != 0 || receipt.ToBalance.Cmp(models.NewAmount(tt.wantTo)) != 0 {
+				t.Errorf("projected balances = %s / %s, want %d / %d", receipt.FromBalance, receipt.ToBalance, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+// failingSaveStore 保存钱包总是失败的内存数据库
+type failingSaveStore struct {
+	*database.BlockchainMemory
+}
+
+func (s failingSaveStore) SaveWallet(*models.Wallet) error {
+	return apperr.New(apperr.Unavailable, "", "database unavailable")
+}
+
+func TestCreateNewWalletRemovesAccountWhenSaveFails(t *testing.T) {
+	bc, ks := newTestBlockchain(t, failingSaveStore{database.NewBlockchainMemory()})
+
+	if _, err := bc.CreateNewWallet("passphrase"); apperr.KindOf(err) != apperr.Unavailable {
+		t.Fatalf("CreateNewWallet error = %v, want unavailable", err)
+	}
+	if accounts := ks.Accounts(); len(accounts) != 0 {
+		t.Errorf("keystore has %d accounts after failed save, want 0", len(accounts))
+	}
+}
+
+func mustGenerateKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
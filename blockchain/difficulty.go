@@ -0,0 +1,67 @@
+package blockchain
+
+import (
+	"hello-go/config"
+	"hello-go/models"
+	"time"
+)
+
+// 难度以十六进制前导零个数表示，每调整一级出块所需算力变化 16 倍，
+// 因此实际出块时间偏离目标 4 倍以上（16 的几何中点）才调整一级，避免在相邻难度间来回震荡
+const retargetThreshold = 4
+
+// retarget 根据调整窗口内首尾两个区块的时间差计算新难度
+func retarget(cfg *config.MiningConfig, first, last *models.Block) int {
+	difficulty := last.Difficulty
+
+	// 窗口内共 AdjustmentWindow 个区块，即 AdjustmentWindow-1 个出块间隔
+	expected := cfg.TargetBlockTime * time.Duration(cfg.AdjustmentWindow-1)
+	actual := last.Timestamp.Sub(first.Timestamp)
+
+	switch {
+	case actual*retargetThreshold < expected:
+		difficulty++
+	case actual > expected*retargetThreshold:
+		difficulty--
+	}
+
+	if difficulty < cfg.MinDifficulty {
+		difficulty = cfg.MinDifficulty
+	}
+	if difficulty > cfg.MaxDifficulty {
+		difficulty = cfg.MaxDifficulty
+	}
+	return difficulty
+}
+
+// isRetargetHeight 判断该高度的区块是否需要重新计算难度
+func isRetargetHeight(cfg *config.MiningConfig, index int) bool {
+	return cfg.AdjustmentWindow > 1 && index >= cfg.AdjustmentWindow && index%cfg.AdjustmentWindow == 0
+}
+
+// expectedDifficulty 计算按索引排列的区块链中第 index 个区块应有的难度
+func expectedDifficulty(cfg *config.MiningConfig, blocks []*models.Block, index int) int {
+	if index == 0 {
+		return cfg.InitialDifficulty
+	}
+
+	prev := blocks[index-1]
+	if !isRetargetHeight(cfg, index) {
+		return prev.Difficulty
+	}
+	return retarget(cfg, blocks[index-cfg.AdjustmentWindow], prev)
+}
+
+// NextDifficulty 计算下一个区块所需的难度
+func (bc *Blockchain) NextDifficulty(prev *models.Block) (int, error) {
+	index := prev.Index + 1
+	if !isRetargetHeight(bc.mining, index) {
+		return prev.Difficulty, nil
+	}
+
+	first, err := bc.db.GetBlockByIndex(index - bc.mining.AdjustmentWindow)
+	if err != nil {
+		return 0, err
+	}
+	return retarget(bc.mining, first, prev), nil
+}
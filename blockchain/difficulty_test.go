@@ -0,0 +1,121 @@
+package blockchain
+
+import (
+	"hello-go/config"
+	"hello-go/database"
+	"hello-go/models"
+	"testing"
+	"time"
+)
+
+// testRetargetConfig 每 10 个区块调整一次难度，目标出块时间 10s，窗口内 9 个出块间隔的期望总时长为 90s
+func testRetargetConfig() *config.MiningConfig {
+	return &config.MiningConfig{
+		InitialDifficulty: 3,
+		MinDifficulty:     2,
+		MaxDifficulty:     5,
+		TargetBlockTime:   10 * time.Second,
+		AdjustmentWindow:  10,
+	}
+}
+
+func TestRetarget(t *testing.T) {
+	tests := []struct {
+		name       string
+		difficulty int
+		// actual 窗口首尾区块的时间差
+		actual time.Duration
+		want   int
+	}{
+		{name: "on target", difficulty: 3, actual: 90 * time.Second, want: 3},
+		{name: "more than 4x faster", difficulty: 3, actual: 22 * time.Second, want: 4},
+		{name: "just under 4x faster", difficulty: 3, actual: 23 * time.Second, want: 3},
+		{name: "exactly 4x slower", difficulty: 3, actual: 360 * time.Second, want: 3},
+		{name: "more than 4x slower", difficulty: 3, actual: 361 * time.Second, want: 2},
+		{name: "adjusts one level at most", difficulty: 3, actual: time.Second, want: 4},
+		{name: "clamped to max", difficulty: 5, actual: time.Second, want: 5},
+		{name: "clamped to min", difficulty: 2, actual: time.Hour, want: 2},
+		{name: "raised to min", difficulty: 1, actual: 90 * time.Second, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := &models.Block{Timestamp: time.Unix(1000, 0)}
+			last := &models.Block{Timestamp: first.Timestamp.Add(tt.actual), Difficulty: tt.difficulty}
+			if got := retarget(testRetargetConfig(), first, last); got != tt.want {
+				t.Errorf("retarget = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRetargetHeight(t *testing.T) {
+	tests := []struct {
+		window int
+		index  int
+		want   bool
+	}{
+		{window: 10, index: 0, want: false},
+		{window: 10, index: 9, want: false},
+		{window: 10, index: 10, want: true},
+		{window: 10, index: 15, want: false},
+		{window: 10, index: 20, want: true},
+		{window: 1, index: 10, want: false},
+		{window: 0, index: 10, want: false},
+	}
+
+	for _, tt := range tests {
+		cfg := &config.MiningConfig{AdjustmentWindow: tt.window}
+		if got := isRetargetHeight(cfg, tt.index); got != tt.want {
+			t.Errorf("isRetargetHeight(window %d, index %d) = %v, want %v", tt.window, tt.index, got, tt.want)
+		}
+	}
+}
+
+// TestNextDifficulty 只在窗口边界按窗口首尾区块调整难度，其他高度沿用父区块的难度
+func TestNextDifficulty(t *testing.T) {
+	tests := []struct {
+		name string
+		// interval 主链上相邻区块的时间间隔
+		interval time.Duration
+		// height 父区块高度，计算高度 height+1 的难度
+		height int
+		want   int
+	}{
+		{name: "inside window", interval: time.Second, height: 5, want: 3},
+		{name: "fast window", interval: time.Second, height: 9, want: 4},
+		{name: "on target window", interval: 10 * time.Second, height: 9, want: 3},
+		{name: "slow window", interval: time.Minute, height: 9, want: 2},
+		{name: "second window", interval: time.Second, height: 19, want: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testRetargetConfig()
+			store := database.NewBlockchainMemory()
+			bc := &Blockchain{db: store, mining: cfg}
+
+			// 主链区块难度依次由 expectedDifficulty 计算，与逐块调用 NextDifficulty 的结果一致
+			var blocks []*models.Block
+			for i := 0; i <= tt.height; i++ {
+				block := &models.Block{Index: i, Timestamp: time.Unix(0, 0).Add(time.Duration(i) * tt.interval)}
+				blocks = append(blocks, block)
+				block.Difficulty = expectedDifficulty(cfg, blocks, i)
+				if err := store.SaveBlock(block); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := bc.NextDifficulty(blocks[tt.height])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("NextDifficulty = %d, want %d", got, tt.want)
+			}
+			if want := expectedDifficulty(cfg, append(blocks, &models.Block{}), tt.height+1); got != want {
+				t.Errorf("NextDifficulty = %d, expectedDifficulty = %d", got, want)
+			}
+		})
+	}
+}
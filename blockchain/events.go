@@ -0,0 +1,108 @@
+package blockchain
+
+import (
+	"hello-go/models"
+)
+
+// BlockListener 新区块上链（本地挖出或从其他节点导入）后的回调
+// 监听者收到的是区块和交易的副本，可以在其他协程中继续使用
+type BlockListener func(block *models.Block, txs []*models.Transaction)
+
+// TransactionListener 新交易进入交易池后的回调，监听者收到的是交易的副本
+type TransactionListener func(tx *models.Transaction)
+
+// BalanceListener 钱包余额变化（区块上链或链重组）后的回调
+type BalanceListener func(address string, balance models.Amount)
+
+// OnBlock 注册新区块回调，回调在提交区块的协程中同步执行，耗时操作应自行异步处理
+func (bc *Blockchain) OnBlock(listener BlockListener) {
+	bc.listenersMu.Lock()
+	defer bc.listenersMu.Unlock()
+
+	bc.blockListeners = append(bc.blockListeners, listener)
+}
+
+// OnTransaction 注册新交易回调，回调在提交交易的协程中同步执行，耗时操作应自行异步处理
+func (bc *Blockchain) OnTransaction(listener TransactionListener) {
+	bc.listenersMu.Lock()
+	defer bc.listenersMu.Unlock()
+
+	bc.txListeners = append(bc.txListeners, listener)
+}
+
+// OnBalanceChange 注册余额变化回调，回调在修改余额的协程中同步执行，耗时操作应自行异步处理
+func (bc *Blockchain) OnBalanceChange(listener BalanceListener) {
+	bc.listenersMu.Lock()
+	defer bc.listenersMu.Unlock()
+
+	bc.balanceListeners = append(bc.balanceListeners, listener)
+}
+
+func (bc *Blockchain) emitBlock(block *models.Block, txs []*models.Transaction) {
+	bc.listenersMu.RLock()
+	listeners := bc.blockListeners
+	bc.listenersMu.RUnlock()
+
+	if len(listeners) == 0 {
+		return
+	}
+	// 区块和交易之后可能被链的其他部分修改，监听者可能在其他协程中序列化，因此传递副本
+	blockCopy := *block
+	txsCopy := copyTransactions(txs)
+	for _, listener := range listeners {
+		listener(&blockCopy, txsCopy)
+	}
+}
+
+func (bc *Blockchain) emitTransaction(tx *models.Transaction) {
+	bc.listenersMu.RLock()
+	listeners := bc.txListeners
+	bc.listenersMu.RUnlock()
+
+	if len(listeners) == 0 {
+		return
+	}
+	txCopy := *tx
+	for _, listener := range listeners {
+		listener(&txCopy)
+	}
+}
+
+// copyTransactions 复制交易值，返回的交易与原交易不共享内存
+func copyTransactions(txs []*models.Transaction) []*models.Transaction {
+	copies := make([]*models.Transaction, len(txs))
+	for i, tx := range txs {
+		t := *tx
+		copies[i] = &t
+	}
+	return copies
+}
+
+// emitBalances 查询交易涉及地址的最新余额并通知监听者，没有监听者时不查询
+func (bc *Blockchain) emitBalances(txs []*models.Transaction) {
+	bc.listenersMu.RLock()
+	listeners := bc.balanceListeners
+	bc.listenersMu.RUnlock()
+
+	if len(listeners) == 0 {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, tx := range txs {
+		for _, address := range []string{tx.FromAddr, tx.ToAddr} {
+			if seen[address] {
+				continue
+			}
+			seen[address] = true
+
+			balance, err := bc.db.GetBalance(address)
+			if err != nil {
+				continue
+			}
+			for _, listener := range listeners {
+				listener(address, balance)
+			}
+		}
+	}
+}
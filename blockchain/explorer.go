@@ -0,0 +1,120 @@
+package blockchain
+
+import (
+	"hello-go/models"
+)
+
+// BlockSummary 区块浏览器展示的区块信息
+type BlockSummary struct {
+	*models.Block
+	TransactionCount int `json:"transaction_count"`
+	// Miner coinbase 交易的收款地址，区块没有 coinbase 交易时为空
+	Miner string `json:"miner"`
+	// Size 区块头（参与哈希计算的字段）与全部交易规范 RLP 编码的字节数之和
+	Size int `json:"size"`
+	// Confirmations 该区块及其后的区块数，链头区块为 1
+	Confirmations int `json:"confirmations"`
+	// Transactions 查询单个区块时返回区块中的全部交易
+	Transactions []*models.Transaction `json:"transactions,omitempty"`
+}
+
+// BlockPage 按高度递减分页的区块列表
+type BlockPage struct {
+	Blocks []*BlockSummary `json:"blocks"`
+	Height int             `json:"height"`
+	// NextFrom 下一页的起始高度，已到创世区块时为 nil
+	NextFrom *int `json:"next_from"`
+}
+
+// newBlockSummary 统计区块的交易数、矿工和大小，height 为当前链高度
+// 无法编码的交易（例如签名缺失的旧数据）不计入大小
+func newBlockSummary(block *models.Block, txs []*models.Transaction, height int) *BlockSummary {
+	summary := &BlockSummary{
+		Block:            block,
+		TransactionCount: len(txs),
+		Size:             len(headerRecord(block)),
+		Confirmations:    height - block.Index + 1,
+	}
+	for _, tx := range txs {
+		if tx.IsCoinbase() {
+			summary.Miner = tx.ToAddr
+		}
+		if encoded, err := EncodeTransaction(tx); err == nil {
+			summary.Size += len(encoded)
+		}
+	}
+	return summary
+}
+
+// ListBlocks 从高度 from 开始按高度递减获取至多 limit 个主链区块，from 小于 0 或超过链高度时从链头开始
+func (bc *Blockchain) ListBlocks(from, limit int) (*BlockPage, error) {
+	latest, err := bc.db.GetLatestBlock()
+	if err != nil {
+		return nil, err
+	}
+	if from < 0 || from > latest.Index {
+		from = latest.Index
+	}
+	to := from - limit + 1
+	if to < 0 {
+		to = 0
+	}
+
+	blocks, txsByBlock, err := bc.blockRange(to, from)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &BlockPage{Blocks: make([]*BlockSummary, 0, len(blocks)), Height: latest.Index}
+	for i := len(blocks) - 1; i >= 0; i-- {
+		page.Blocks = append(page.Blocks, newBlockSummary(blocks[i], txsByBlock[blocks[i].ID], latest.Index))
+	}
+	if to > 0 {
+		next := to - 1
+		page.NextFrom = &next
+	}
+	return page, nil
+}
+
+// GetBlockSummary 获取指定高度的主链区块及其交易
+func (bc *Blockchain) GetBlockSummary(index int) (*BlockSummary, error) {
+	block, err := bc.db.GetBlockByIndex(index)
+	if err != nil {
+		return nil, err
+	}
+	return bc.blockDetails(block)
+}
+
+// GetBlockSummaryByHash 根据哈希获取主链区块及其交易
+func (bc *Blockchain) GetBlockSummaryByHash(hash string) (*BlockSummary, error) {
+	block, err := bc.db.GetBlockByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	return bc.blockDetails(block)
+}
+
+// GetLatestBlockSummary 获取链头区块及其交易
+func (bc *Blockchain) GetLatestBlockSummary() (*BlockSummary, error) {
+	block, err := bc.db.GetLatestBlock()
+	if err != nil {
+		return nil, err
+	}
+	return bc.blockDetails(block)
+}
+
+// blockDetails 统计单个区块的信息并附带其交易
+func (bc *Blockchain) blockDetails(block *models.Block) (*BlockSummary, error) {
+	txs, err := bc.db.GetTransactionsByBlockID(block.ID)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := bc.db.GetLatestBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	summary := newBlockSummary(block, txs, latest.Index)
+	summary.Transactions = txs
+	return summary, nil
+}
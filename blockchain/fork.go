@@ -0,0 +1,295 @@
+package blockchain
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"hello-go/models"
+	"log"
+	"math/big"
+)
+
+// chainUpdate 一次主链变化中新上链和被撤销的区块，均按高度升序排列
+type chainUpdate struct {
+	attached []*BlockWithTransactions
+	detached []*BlockWithTransactions
+}
+
+// BlockWork 区块的工作量，即找到满足难度的哈希所需的期望计算次数
+// 难度以十六进制前导零个数表示，每个零对应 4 位，因此工作量为 2^(4*difficulty)
+func BlockWork(difficulty int) *big.Int {
+	if difficulty < 0 {
+		difficulty = 0
+	}
+	return new(big.Int).Lsh(big.NewInt(1), uint(4*difficulty))
+}
+
+func chainWork(blocks []*models.Block) *big.Int {
+	work := new(big.Int)
+	for _, block := range blocks {
+		work.Add(work, BlockWork(block.Difficulty))
+	}
+	return work
+}
+
+// TotalWork 主链的累计工作量，分叉时累计工作量更大的链为主链
+func (bc *Blockchain) TotalWork() (*big.Int, error) {
+	blocks, err := bc.db.GetAllBlocks()
+	if err != nil {
+		return nil, err
+	}
+	return chainWork(blocks), nil
+}
+
+// addSideBlock 保存不接在链头之后的区块，侧链累计工作量超过主链时切换到侧链
+// 调用方需持有 chainMu
+func (bc *Blockchain) addSideBlock(block *models.Block, txs []*models.Transaction) (*chainUpdate, error) {
+	ancestor, branch, err := bc.sideBranch(block, txs)
+	if err != nil {
+		return nil, err
+	}
+	if err := bc.verifySideBlock(ancestor, branch); err != nil {
+		return nil, err
+	}
+	if err := bc.db.SaveSideBlock(block, txs); err != nil {
+		return nil, err
+	}
+	log.Printf("Stored side block: Index=%d, Hash=%s, ForkPoint=%d", block.Index, block.Hash, ancestor.Index)
+
+	branchBlocks := make([]*models.Block, 0, len(branch))
+	for _, item := range branch {
+		branchBlocks = append(branchBlocks, item.Block)
+	}
+	mainBlocks, err := bc.blocksAbove(ancestor.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	// 工作量相同时保留先收到的主链
+	if chainWork(branchBlocks).Cmp(chainWork(mainBlocks)) <= 0 {
+		return nil, nil
+	}
+	return bc.reorganize(ancestor, branch)
+}
+
+// sideBranch 沿 prev_hash 从区块回溯侧链，直到主链上的分叉点
+// 返回分叉点区块和按高度升序排列的侧链分支（包含 block 本身）
+func (bc *Blockchain) sideBranch(block *models.Block, txs []*models.Transaction) (*models.Block, []*BlockWithTransactions, error) {
+	branch := []*BlockWithTransactions{{Block: block, Transactions: txs}}
+	prevHash := block.PrevHash
+
+	var ancestor *models.Block
+	for ancestor == nil {
+		parent, err := bc.db.GetBlockByHash(prevHash)
+		if err == nil {
+			ancestor = parent
+			break
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, err
+		}
+
+		side, sideTxs, err := bc.db.GetSideBlock(prevHash)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrUnknownParent
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		branch = append(branch, &BlockWithTransactions{Block: side, Transactions: sideTxs})
+		prevHash = side.PrevHash
+	}
+
+	for i, j := 0, len(branch)-1; i < j; i, j = i+1, j-1 {
+		branch[i], branch[j] = branch[j], branch[i]
+	}
+	for i, item := range branch {
+		if item.Block.Index != ancestor.Index+1+i {
+			return nil, nil, fmt.Errorf("invalid index %d in side chain", item.Block.Index)
+		}
+	}
+	return ancestor, branch, nil
+}
+
+// verifySideBlock 检查侧链分支末尾新收到的区块的难度、工作量证明和时间戳
+// 分支中其他区块在保存时已经检查过。难度按侧链自身的历史计算，防止以过低的难度伪造侧链
+func (bc *Blockchain) verifySideBlock(ancestor *models.Block, branch []*BlockWithTransactions) error {
+	i := len(branch) - 1
+	block := branch[i].Block
+	parent := ancestor
+	if i > 0 {
+		parent = branch[i-1].Block
+	}
+
+	difficulty, err := bc.sideDifficulty(ancestor, branch, i)
+	if err != nil {
+		return err
+	}
+	if block.Difficulty != difficulty {
+		return fmt.Errorf("invalid difficulty %d, expected %d", block.Difficulty, difficulty)
+	}
+	if !meetsDifficulty(block.Hash, difficulty) {
+		return fmt.Errorf("block hash does not meet difficulty %d", difficulty)
+	}
+	if block.Timestamp.Before(parent.Timestamp) {
+		return fmt.Errorf("block timestamp is before its parent")
+	}
+	return nil
+}
+
+// sideDifficulty 计算侧链分支中第 i 个区块应有的难度，重新计算难度时窗口起点可能在主链或侧链上
+func (bc *Blockchain) sideDifficulty(ancestor *models.Block, branch []*BlockWithTransactions, i int) (int, error) {
+	parent := ancestor
+	if i > 0 {
+		parent = branch[i-1].Block
+	}
+	index := parent.Index + 1
+	if !isRetargetHeight(bc.mining, index) {
+		return parent.Difficulty, nil
+	}
+
+	firstIndex := index - bc.mining.AdjustmentWindow
+	if firstIndex > ancestor.Index {
+		return retarget(bc.mining, branch[firstIndex-ancestor.Index-1].Block, parent), nil
+	}
+	first, err := bc.db.GetBlockByIndex(firstIndex)
+	if err != nil {
+		return 0, err
+	}
+	return retarget(bc.mining, first, parent), nil
+}
+
+// blocksAbove 获取主链上高度大于 index 的全部区块
+func (bc *Blockchain) blocksAbove(index int) ([]*models.Block, error) {
+	var blocks []*models.Block
+	for i := index + 1; ; i++ {
+		block, err := bc.db.GetBlockByIndex(i)
+		if errors.Is(err, sql.ErrNoRows) {
+			return blocks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+}
+
+// reorganize 链重组：从链头依次撤销分叉点之后的主链区块，回滚余额并解除交易的区块关联，
+// 再按顺序执行侧链分支。分支中任一区块无效时丢弃该分支并恢复原主链。调用方需持有 chainMu
+func (bc *Blockchain) reorganize(ancestor *models.Block, branch []*BlockWithTransactions) (*chainUpdate, error) {
+	detached, err := bc.detachTo(ancestor.Index)
+	if err != nil {
+		if restoreErr := bc.restoreChain(nil, detached); restoreErr != nil {
+			return nil, fmt.Errorf("chain reorganization failed: %v; restoring main chain failed: %v", err, restoreErr)
+		}
+		return nil, fmt.Errorf("chain reorganization failed: %v", err)
+	}
+
+	parent := ancestor
+	var attached []*BlockWithTransactions
+	for i, item := range branch {
+		if err := bc.attachBlock(item.Block, item.Transactions, parent); err != nil {
+			log.Printf("Discarding invalid side chain at block %d: %v", item.Block.Index, err)
+			for _, bad := range branch[i:] {
+				if err := bc.db.DeleteSideBlock(bad.Block.Hash); err != nil {
+					log.Println("删除侧链区块失败:", err)
+				}
+			}
+			if restoreErr := bc.restoreChain(attached, detached); restoreErr != nil {
+				return nil, fmt.Errorf("chain reorganization failed: %v; restoring main chain failed: %v", err, restoreErr)
+			}
+			return nil, fmt.Errorf("chain reorganization failed at block %d: %v", item.Block.Index, err)
+		}
+		attached = append(attached, item)
+		parent = item.Block
+	}
+
+	// 被替换的主链区块转为侧链保存，之后可能重新成为主链
+	for _, item := range detached {
+		if err := bc.db.SaveSideBlock(item.Block, item.Transactions); err != nil {
+			log.Println("保存侧链区块失败:", err)
+		}
+	}
+	for _, item := range attached {
+		if err := bc.db.DeleteSideBlock(item.Block.Hash); err != nil {
+			log.Println("删除侧链区块失败:", err)
+		}
+	}
+
+	log.Printf("Chain reorganized at block %d: %d blocks detached, %d blocks attached, new tip %s",
+		ancestor.Index, len(detached), len(attached), parent.Hash)
+	return &chainUpdate{attached: attached, detached: detached}, nil
+}
+
+// detachTo 从链头开始依次撤销高度大于 index 的主链区块，返回按高度升序排列的被撤销区块
+// 出错时返回已撤销的区块，由调用方恢复
+func (bc *Blockchain) detachTo(index int) ([]*BlockWithTransactions, error) {
+	var detached []*BlockWithTransactions
+	for {
+		latest, err := bc.db.GetLatestBlock()
+		if err != nil {
+			return detached, err
+		}
+		if latest.Index <= index {
+			return detached, nil
+		}
+
+		txs, err := bc.db.RollbackBlock(latest)
+		if err != nil {
+			return detached, err
+		}
+		detached = append([]*BlockWithTransactions{{Block: latest, Transactions: txs}}, detached...)
+	}
+}
+
+// restoreChain 撤销已执行的侧链区块，并按顺序重新执行原主链区块
+func (bc *Blockchain) restoreChain(attached, detached []*BlockWithTransactions) error {
+	for i := len(attached) - 1; i >= 0; i-- {
+		if _, err := bc.db.RollbackBlock(attached[i].Block); err != nil {
+			return err
+		}
+	}
+	for _, item := range detached {
+		resetIDs(item.Block, item.Transactions)
+		if err := setHashes(item.Transactions); err != nil {
+			return err
+		}
+		if err := bc.db.CommitBlock(item.Block, item.Transactions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// afterUpdate 主链变化后，将被撤销区块中未被新主链包含的交易放回交易池，
+// 并对每个新上链的区块通知挖矿任务、清理交易池和通知监听者
+func (bc *Blockchain) afterUpdate(update *chainUpdate) {
+	included := make(map[string]bool)
+	for _, item := range update.attached {
+		for _, tx := range item.Transactions {
+			included[tx.Signature] = true
+		}
+	}
+
+	for _, item := range update.detached {
+		for _, tx := range item.Transactions {
+			// coinbase 交易随区块失效，不放回交易池
+			if tx.IsCoinbase() || included[tx.Signature] {
+				continue
+			}
+			tx.ID = 0
+			tx.BlockID = 0
+			// 交易仍在交易池中时忽略，余额不再足够的交易会在打包时被丢弃
+			if err := bc.mempool.Add(tx); err == nil {
+				bc.emitTransaction(tx)
+			}
+		}
+	}
+
+	for _, item := range update.detached {
+		bc.emitBalances(item.Transactions)
+	}
+	for _, item := range update.attached {
+		bc.afterCommit(item.Block, item.Transactions)
+	}
+}
@@ -0,0 +1,93 @@
+package blockchain
+
+import (
+	"hello-go/database"
+	"hello-go/models"
+	"testing"
+	"time"
+)
+
+// solveBlock 构造接在 parent 之后的空区块并找到满足 difficulty 的 nonce
+func solveBlock(parent *models.Block, data string, difficulty int, timestamp time.Time) *models.Block {
+	block := &models.Block{
+		Index:      parent.Index + 1,
+		PrevHash:   parent.Hash,
+		Data:       data,
+		MerkleRoot: emptyMerkleRoot,
+		Timestamp:  timestamp,
+		Difficulty: difficulty,
+	}
+	solve(block)
+	return block
+}
+
+// solve 从当前 nonce 开始查找满足区块难度的 nonce，并设置区块哈希
+func solve(block *models.Block) {
+	for {
+		block.Hash = calculateHash(block)
+		if meetsDifficulty(block.Hash, block.Difficulty) {
+			return
+		}
+		block.Nonce++
+	}
+}
+
+func TestAddSideBlockChecksDifficulty(t *testing.T) {
+	tests := []struct {
+		name string
+		// parentIndex 侧链区块接在主链的哪个区块之后
+		parentIndex int
+		difficulty  int
+		// offset 侧链区块时间戳相对父区块的偏移
+		offset  time.Duration
+		wantErr bool
+	}{
+		{name: "parent difficulty", parentIndex: 0, difficulty: 1, offset: time.Second},
+		{name: "difficulty below schedule", parentIndex: 0, difficulty: 0, offset: time.Second, wantErr: true},
+		{name: "retargeted difficulty", parentIndex: 1, difficulty: 2, offset: time.Second},
+		{name: "difficulty not retargeted", parentIndex: 1, difficulty: 1, offset: time.Second, wantErr: true},
+		{name: "timestamp before parent", parentIndex: 1, difficulty: 2, offset: -time.Second, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc, _ := newTestBlockchain(t, database.NewBlockchainMemory())
+			// 每两个区块调整一次难度，出块远快于目标时间，高度 2 的难度上调为 2
+			bc.mining.AdjustmentWindow = 2
+			bc.mining.TargetBlockTime = time.Hour
+			bc.mining.MaxDifficulty = 2
+
+			main := []*models.Block{mustLatestBlock(t, bc)}
+			for _, difficulty := range []int{1, 2} {
+				parent := main[len(main)-1]
+				block := solveBlock(parent, "main", difficulty, parent.Timestamp.Add(time.Second))
+				if err := bc.AddBlock(block, nil); err != nil {
+					t.Fatalf("main chain block %d: %v", block.Index, err)
+				}
+				main = append(main, block)
+			}
+
+			parent := main[tt.parentIndex]
+			side := solveBlock(parent, "side", tt.difficulty, parent.Timestamp.Add(tt.offset))
+			err := bc.AddBlock(side, nil)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Fatalf("AddBlock error = %v, want error = %v", err, tt.wantErr)
+			}
+
+			_, _, err = bc.db.GetSideBlock(side.Hash)
+			if stored := err == nil; stored == tt.wantErr {
+				t.Errorf("side block stored = %v, want %v", stored, !tt.wantErr)
+			}
+		})
+	}
+}
+
+func mustLatestBlock(t *testing.T, bc *Blockchain) *models.Block {
+	t.Helper()
+
+	block, err := bc.db.GetLatestBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return block
+}
@@ -0,0 +1,183 @@
+package blockchain
+
+import (
+	"hello-go/apperr"
+	"hello-go/models"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrDuplicateTransaction 交易已在交易池中
+var ErrDuplicateTransaction error = apperr.New(apperr.Conflict, apperr.CodeDuplicateTransaction, "transaction already in mempool")
+
+// Mempool 待打包交易池，以交易签名唯一标识一笔交易
+// 同一转出地址的每个序号只能有一笔交易，序号不连续的交易留在交易池中，等待前面的序号补齐后再打包
+// 交易的地址在进入交易池时统一为校验和格式，池内一律按校验和格式比较
+type Mempool struct {
+	mu      sync.Mutex
+	pending []*models.Transaction
+	known   map[string]bool
+	// 按转出地址和序号索引的交易
+	nonces map[string]map[uint64]*models.Transaction
+	// 按交易哈希索引的交易
+	hashes map[string]*models.Transaction
+}
+
+// poolAddress 交易池内使用的地址格式，调用方传入的地址大小写不影响比较结果
+func poolAddress(address string) string {
+	return common.HexToAddress(address).Hex()
+}
+
+func NewMempool() *Mempool {
+	return &Mempool{
+		known:  make(map[string]bool),
+		nonces: make(map[string]map[uint64]*models.Transaction),
+		hashes: make(map[string]*models.Transaction),
+	}
+}
+
+// Add 加入一笔待打包交易，重复提交同一笔交易时返回 ErrDuplicateTransaction，
+// 转出地址的同一序号已有其他交易时返回 nonce_too_low 错误
+// 交易的转出和收款地址会被改写为校验和格式，签名和交易哈希不受地址大小写影响
+func (mp *Mempool) Add(tx *models.Transaction) error {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	if mp.known[tx.Signature] {
+		return ErrDuplicateTransaction
+	}
+	sender := poolAddress(tx.FromAddr)
+	if _, ok := mp.nonces[sender][tx.Nonce]; ok {
+		return apperr.New(apperr.Conflict, apperr.CodeNonceTooLow,
+			"nonce %d of %s is already used by a pending transaction", tx.Nonce, tx.FromAddr)
+	}
+
+	if mp.nonces[sender] == nil {
+		mp.nonces[sender] = make(map[uint64]*models.Transaction)
+	}
+	tx.FromAddr = sender
+	tx.ToAddr = poolAddress(tx.ToAddr)
+	mp.nonces[sender][tx.Nonce] = tx
+	if tx.Hash != "" {
+		mp.hashes[tx.Hash] = tx
+	}
+	mp.known[tx.Signature] = true
+	mp.pending = append(mp.pending, tx)
+	return nil
+}
+
+// NextNonce 转出地址的下一个可用序号：从已上链的序号 confirmed 开始，跳过交易池中连续占用的序号
+func (mp *Mempool) NextNonce(address string, confirmed uint64) uint64 {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	nonces := mp.nonces[poolAddress(address)]
+	next := confirmed
+	for {
+		if _, ok := nonces[next]; !ok {
+			return next
+		}
+		next++
+	}
+}
+
+// Pending 按提交顺序返回待打包交易的快照
+// 快照中的交易是在持有锁时复制的值，调用方可以修改或在其他协程中读取，不影响交易池
+func (mp *Mempool) Pending() []*models.Transaction {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	return copyTransactions(mp.pending)
+}
+
+// Get 根据交易哈希查找待打包交易，返回交易的副本
+func (mp *Mempool) Get(hash string) (*models.Transaction, bool) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	tx, ok := mp.hashes[hash]
+	if !ok {
+		return nil, false
+	}
+	t := *tx
+	return &t, true
+}
+
+// Remove 移除已打包或已丢弃的交易，包括其他节点打包的同一笔交易
+func (mp *Mempool) Remove(txs []*models.Transaction) {
+	if len(txs) == 0 {
+		return
+	}
+
+	removed := make(map[string]bool, len(txs))
+	for _, tx := range txs {
+		removed[tx.Signature] = true
+	}
+
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	kept := mp.pending[:0]
+	for _, tx := range mp.pending {
+		if removed[tx.Signature] {
+			delete(mp.known, tx.Signature)
+			sender := tx.FromAddr
+			delete(mp.nonces[sender], tx.Nonce)
+			if len(mp.nonces[sender]) == 0 {
+				delete(mp.nonces, sender)
+			}
+			delete(mp.hashes, tx.Hash)
+			continue
+		}
+		kept = append(kept, tx)
+	}
+	// 清理尾部引用，避免已移除的交易无法被回收
+	for i := len(kept); i < len(mp.pending); i++ {
+		mp.pending[i] = nil
+	}
+	mp.pending = kept
+}
+
+// PendingOutgoing 统计某地址在交易池中尚未打包的转出总额，包括手续费
+// 交易进入交易池前已确认转出总额不超过余额，因此求和不会溢出
+func (mp *Mempool) PendingOutgoing(address string) models.Amount {
+	address = poolAddress(address)
+
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	var total models.Amount
+	for _, tx := range mp.pending {
+		if tx.FromAddr == address {
+			total, _ = total.Add(tx.Amount)
+			total, _ = total.Add(tx.Fee)
+		}
+	}
+	return total
+}
+
+// Involving 按提交顺序返回转出或收款地址为 address 的待打包交易的快照
+func (mp *Mempool) Involving(address string) []*models.Transaction {
+	address = poolAddress(address)
+
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	var txs []*models.Transaction
+	for _, tx := range mp.pending {
+		if tx.FromAddr == address || tx.ToAddr == address {
+			t := *tx
+			txs = append(txs, &t)
+		}
+	}
+	return txs
+}
+
+// Size 待打包交易数量
+func (mp *Mempool) Size() int {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	return len(mp.pending)
+}
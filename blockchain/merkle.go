@@ -0,0 +1,141 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hello-go/models"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// 没有交易的区块使用全零的 Merkle 根
+var emptyMerkleRoot = strings.Repeat("0", 64)
+
+// MerkleProofStep Merkle 证明中的一个兄弟节点
+type MerkleProofStep struct {
+	Hash string `json:"hash"`
+	// Position 兄弟节点位于左侧还是右侧："left" 或 "right"
+	Position string `json:"position"`
+}
+
+// TransactionLeaf 计算交易在 Merkle 树中的叶子哈希：sha256(签名哈希 || 签名)
+func TransactionLeaf(tx *models.Transaction) ([]byte, error) {
+	hash, err := SigningHash(tx)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := hexutil.Decode(tx.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha256.New()
+	h.Write(hash.Bytes())
+	h.Write(sig)
+	return h.Sum(nil), nil
+}
+
+func hashPair(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// merkleLeaves 按区块内顺序计算所有交易的叶子哈希
+func merkleLeaves(txs []*models.Transaction) ([][]byte, error) {
+	leaves := make([][]byte, 0, len(txs))
+	for _, tx := range txs {
+		leaf, err := TransactionLeaf(tx)
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, leaf)
+	}
+	return leaves, nil
+}
+
+// nextLevel 两两合并得到上一层节点，节点数为奇数时复制最后一个节点
+func nextLevel(level [][]byte) [][]byte {
+	parents := make([][]byte, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		right := level[i]
+		if i+1 < len(level) {
+			right = level[i+1]
+		}
+		parents = append(parents, hashPair(level[i], right))
+	}
+	return parents
+}
+
+// ComputeMerkleRoot 计算交易列表的 Merkle 根
+func ComputeMerkleRoot(txs []*models.Transaction) (string, error) {
+	if len(txs) == 0 {
+		return emptyMerkleRoot, nil
+	}
+
+	level, err := merkleLeaves(txs)
+	if err != nil {
+		return "", err
+	}
+	for len(level) > 1 {
+		level = nextLevel(level)
+	}
+
+	return hex.EncodeToString(level[0]), nil
+}
+
+// BuildMerkleProof 生成第 index 笔交易的 Merkle 包含证明，返回叶子哈希和自底向上的兄弟节点
+func BuildMerkleProof(txs []*models.Transaction, index int) (string, []MerkleProofStep, error) {
+	level, err := merkleLeaves(txs)
+	if err != nil {
+		return "", nil, err
+	}
+	leaf := hex.EncodeToString(level[index])
+
+	proof := []MerkleProofStep{}
+	for len(level) > 1 {
+		var step MerkleProofStep
+		if index%2 == 0 {
+			sibling := index
+			if index+1 < len(level) {
+				sibling = index + 1
+			}
+			step = MerkleProofStep{Hash: hex.EncodeToString(level[sibling]), Position: "right"}
+		} else {
+			step = MerkleProofStep{Hash: hex.EncodeToString(level[index-1]), Position: "left"}
+		}
+		proof = append(proof, step)
+
+		level = nextLevel(level)
+		index /= 2
+	}
+
+	return leaf, proof, nil
+}
+
+// VerifyMerkleProof 使用证明从叶子哈希重新计算根，并与给定的 Merkle 根比较
+func VerifyMerkleProof(leaf string, proof []MerkleProofStep, root string) bool {
+	current, err := hex.DecodeString(leaf)
+	if err != nil {
+		return false
+	}
+
+	for _, step := range proof {
+		sibling, err := hex.DecodeString(step.Hash)
+		if err != nil {
+			return false
+		}
+		switch step.Position {
+		case "left":
+			current = hashPair(sibling, current)
+		case "right":
+			current = hashPair(current, sibling)
+		default:
+			return false
+		}
+	}
+
+	return hex.EncodeToString(current) == root
+}
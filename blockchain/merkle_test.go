@@ -0,0 +1,180 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"hello-go/models"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// signedTransactions 生成 n 笔由同一钱包签名、序号依次递增的交易
+func signedTransactions(t *testing.T, n int) []*models.Transaction {
+	t.Helper()
+
+	key := mustGenerateKey(t)
+	txs := make([]*models.Transaction, n)
+	for i := range txs {
+		txs[i] = &models.Transaction{
+			FromAddr: crypto.PubkeyToAddress(key.PublicKey).Hex(),
+			ToAddr:   "0x00000000000000000000000000000000000000b0",
+			Amount:   models.NewAmount(uint64(i + 1)),
+			Nonce:    uint64(i),
+			ChainID:  testChainID,
+		}
+		if err := SignTransaction(txs[i], key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return txs
+}
+
+func mustLeaves(t *testing.T, txs []*models.Transaction) [][]byte {
+	t.Helper()
+
+	leaves, err := merkleLeaves(txs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaves
+}
+
+func TestComputeMerkleRoot(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+		// want 根据叶子哈希手工计算期望的根
+		want func(leaves [][]byte) string
+	}{
+		{name: "no transactions", n: 0, want: func([][]byte) string { return emptyMerkleRoot }},
+		{name: "single transaction", n: 1, want: func(l [][]byte) string { return hex.EncodeToString(l[0]) }},
+		{name: "two transactions", n: 2, want: func(l [][]byte) string { return hex.EncodeToString(hashPair(l[0], l[1])) }},
+		{
+			name: "odd count duplicates last node",
+			n:    3,
+			want: func(l [][]byte) string {
+				return hex.EncodeToString(hashPair(hashPair(l[0], l[1]), hashPair(l[2], l[2])))
+			},
+		},
+		{
+			name: "odd count on upper level",
+			n:    5,
+			want: func(l [][]byte) string {
+				left := hashPair(hashPair(l[0], l[1]), hashPair(l[2], l[3]))
+				right := hashPair(hashPair(l[4], l[4]), hashPair(l[4], l[4]))
+				return hex.EncodeToString(hashPair(left, right))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txs := signedTransactions(t, tt.n)
+			root, err := ComputeMerkleRoot(txs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := tt.want(mustLeaves(t, txs)); root != want {
+				t.Errorf("root = %s, want %s", root, want)
+			}
+		})
+	}
+}
+
+func TestMerkleProofRoundTrip(t *testing.T) {
+	tests := []struct {
+		n         int
+		wantSteps int
+	}{
+		{n: 1, wantSteps: 0},
+		{n: 2, wantSteps: 1},
+		{n: 3, wantSteps: 2},
+		{n: 4, wantSteps: 2},
+		{n: 5, wantSteps: 3},
+		{n: 7, wantSteps: 3},
+	}
+
+	for _, tt := range tests {
+		txs := signedTransactions(t, tt.n)
+		root, err := ComputeMerkleRoot(txs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for index := range txs {
+			leaf, proof, err := BuildMerkleProof(txs, index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(proof) != tt.wantSteps {
+				t.Errorf("n=%d index=%d: proof has %d steps, want %d", tt.n, index, len(proof), tt.wantSteps)
+			}
+			if !VerifyMerkleProof(leaf, proof, root) {
+				t.Errorf("n=%d index=%d: proof does not verify", tt.n, index)
+			}
+		}
+	}
+}
+
+// TestMerkleProofOddLeafCount 奇数个叶子时最后一笔交易的兄弟节点是它自己
+func TestMerkleProofOddLeafCount(t *testing.T) {
+	txs := signedTransactions(t, 3)
+	leaf, proof, err := BuildMerkleProof(txs, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaves := mustLeaves(t, txs)
+	want := []MerkleProofStep{
+		{Hash: hex.EncodeToString(leaves[2]), Position: "right"},
+		{Hash: hex.EncodeToString(hashPair(leaves[0], leaves[1])), Position: "left"},
+	}
+	if leaf != hex.EncodeToString(leaves[2]) {
+		t.Errorf("leaf = %s, want %x", leaf, leaves[2])
+	}
+	if len(proof) != len(want) {
+		t.Fatalf("proof = %v, want %v", proof, want)
+	}
+	for i := range want {
+		if proof[i] != want[i] {
+			t.Errorf("step %d = %+v, want %+v", i, proof[i], want[i])
+		}
+	}
+}
+
+func TestVerifyMerkleProofRejectsTampering(t *testing.T) {
+	txs := signedTransactions(t, 4)
+	root, err := ComputeMerkleRoot(txs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherLeaf, _, err := BuildMerkleProof(txs, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(leaf *string, proof []MerkleProofStep, root *string)
+	}{
+		{name: "other leaf", modify: func(leaf *string, _ []MerkleProofStep, _ *string) { *leaf = otherLeaf }},
+		{name: "wrong root", modify: func(_ *string, _ []MerkleProofStep, root *string) { *root = emptyMerkleRoot }},
+		{name: "swapped position", modify: func(_ *string, proof []MerkleProofStep, _ *string) { proof[0].Position = "right" }},
+		{name: "unknown position", modify: func(_ *string, proof []MerkleProofStep, _ *string) { proof[1].Position = "up" }},
+		{name: "sibling not hex", modify: func(_ *string, proof []MerkleProofStep, _ *string) { proof[0].Hash = "zz" }},
+		{name: "leaf not hex", modify: func(leaf *string, _ []MerkleProofStep, _ *string) { *leaf = "zz" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leaf, proof, err := BuildMerkleProof(txs, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := root
+			tt.modify(&leaf, proof, &r)
+			if VerifyMerkleProof(leaf, proof, r) {
+				t.Error("tampered proof verifies")
+			}
+		})
+	}
+}
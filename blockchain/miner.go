@@ -0,0 +1,163 @@
+package blockchain
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hello-go/models"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MinePendingTransactions 将交易池中的待打包交易打包进新区块
+// 序号已使用、余额不足或转出钱包不存在的交易会被丢弃，不会进入区块；收款地址没有钱包时在区块上链时创建
+// 配置了矿工地址时，区块首位的 coinbase 交易向矿工发放出块奖励和区块内交易的手续费，返回的交易包含 coinbase 交易
+// ctx 取消、超时或挖矿期间出现新区块时返回错误，交易保留在交易池中等待下次打包
+func (bc *Blockchain) MinePendingTransactions(ctx context.Context) (*models.Block, []*models.Transaction, error) {
+	bc.mineMu.Lock()
+	defer bc.mineMu.Unlock()
+
+	selected, dropped := bc.selectTransactions(bc.mempool.Pending(), bc.mining.MaxTransactionsPerBlock)
+	for _, tx := range dropped {
+		log.Printf("丢弃无效交易: %s -> %s %s", tx.FromAddr, tx.ToAddr, tx.Amount)
+	}
+	bc.mempool.Remove(dropped)
+
+	// 区块与交易在同一事务中落库，交易记录关联到区块ID
+	data := fmt.Sprintf("%d transactions", len(selected))
+	block, txs, err := bc.createBlock(ctx, data, selected)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Printf("Mined block: Index=%d, Hash=%s, Transactions=%d", block.Index, block.Hash, len(selected))
+	return block, txs, nil
+}
+
+// selectTransactions 按提交顺序模拟执行交易，挑选出序号连续且余额足够的交易
+// 序号已被使用的交易会被丢弃，序号不连续的交易留在交易池中等待下次打包
+func (bc *Blockchain) selectTransactions(pending []*models.Transaction, limit int) (selected, dropped []*models.Transaction) {
+	balances := make(map[string]models.Amount)
+	nonces := make(map[string]uint64)
+	known := make(map[string]bool)
+
+	lookup := func(address string) (bool, error) {
+		if _, ok := known[address]; ok {
+			return known[address], nil
+		}
+		balance, err := bc.db.GetBalance(address)
+		if errors.Is(err, sql.ErrNoRows) {
+			known[address] = false
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		nonce, err := bc.db.GetNonce(address)
+		if err != nil {
+			return false, err
+		}
+		known[address] = true
+		balances[address] = balance
+		nonces[address] = nonce
+		return true, nil
+	}
+
+	for _, tx := range orderByNonce(pending) {
+		if limit > 0 && len(selected) >= limit {
+			break
+		}
+
+		ok, err := lookup(tx.FromAddr)
+		if err != nil {
+			// 数据库暂时不可用时保留交易，等待下次打包
+			log.Printf("查询余额失败: %v", err)
+			continue
+		}
+		if !ok || tx.Nonce < nonces[tx.FromAddr] {
+			dropped = append(dropped, tx)
+			continue
+		}
+		if tx.Nonce > nonces[tx.FromAddr] {
+			continue
+		}
+		cost, ok := tx.Amount.Add(tx.Fee)
+		if !ok || balances[tx.FromAddr].Cmp(cost) < 0 {
+			dropped = append(dropped, tx)
+			continue
+		}
+
+		ok, err = lookup(tx.ToAddr)
+		if err != nil {
+			log.Printf("查询余额失败: %v", err)
+			continue
+		}
+		if !ok {
+			// 其他节点广播的交易可以转入本地没有的地址，区块上链时创建空钱包
+			if isCoinbaseAddress(tx.ToAddr) {
+				dropped = append(dropped, tx)
+				continue
+			}
+			known[tx.ToAddr] = true
+		}
+
+		// 余额已确认足够；金额总量远小于 uint256 上限，增加余额不会溢出
+		balances[tx.FromAddr], _ = balances[tx.FromAddr].Sub(cost)
+		balances[tx.ToAddr], _ = balances[tx.ToAddr].Add(tx.Amount)
+		nonces[tx.FromAddr]++
+		selected = append(selected, tx)
+	}
+
+	return selected, dropped
+}
+
+// orderByNonce 同一转出地址的交易按序号排列，并依次占用该地址的交易原先所在的位置，
+// 不同地址之间仍保持提交顺序
+func orderByNonce(pending []*models.Transaction) []*models.Transaction {
+	bySender := make(map[string][]*models.Transaction)
+	for _, tx := range pending {
+		sender := strings.ToLower(tx.FromAddr)
+		bySender[sender] = append(bySender[sender], tx)
+	}
+	for _, txs := range bySender {
+		sort.SliceStable(txs, func(i, j int) bool { return txs[i].Nonce < txs[j].Nonce })
+	}
+
+	ordered := make([]*models.Transaction, 0, len(pending))
+	for _, tx := range pending {
+		sender := strings.ToLower(tx.FromAddr)
+		ordered = append(ordered, bySender[sender][0])
+		bySender[sender] = bySender[sender][1:]
+	}
+	return ordered
+}
+
+// StartMiner 启动后台矿工，按固定间隔打包交易池中的交易
+// 交易池为空时跳过本轮，返回的函数用于停止矿工并中断正在进行的挖矿
+func (bc *Blockchain) StartMiner() (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ticker := time.NewTicker(bc.mining.BlockInterval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if bc.mempool.Size() == 0 {
+					continue
+				}
+				if _, _, err := bc.MinePendingTransactions(ctx); err != nil {
+					log.Println("挖矿失败:", err)
+				}
+			}
+		}
+	}()
+
+	log.Printf("Miner started, block interval %s, %d workers", bc.mining.BlockInterval, bc.mining.Workers)
+	return cancel
+}
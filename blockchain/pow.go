@@ -0,0 +1,169 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"hello-go/apperr"
+	"hello-go/models"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrStaleTip 挖矿期间链上出现了新区块，正在挖的区块已过期
+var ErrStaleTip error = apperr.New(apperr.Conflict, apperr.CodeStaleTip, "a competing block arrived, mining aborted")
+
+// 每个工作协程每计算多少次哈希检查一次是否需要停止
+const cancelCheckInterval = 256
+
+// MinerStats 挖矿统计信息
+type MinerStats struct {
+	Workers     int           `json:"workers"`
+	BlocksMined int           `json:"blocks_mined"`
+	TotalHashes uint64        `json:"total_hashes"`
+	LastHashes  uint64        `json:"last_hashes"`
+	LastElapsed time.Duration `json:"last_elapsed_ns"`
+	// LastHashRate 最近一次挖矿的算力（次/秒）
+	LastHashRate float64 `json:"last_hash_rate"`
+	Mining       bool    `json:"mining"`
+}
+
+// tipNotifier 在链头变化时通知正在进行的挖矿任务
+type tipNotifier struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+// wait 返回一个在下一次链头变化时关闭的通道
+func (n *tipNotifier) wait() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.ch == nil {
+		n.ch = make(chan struct{})
+	}
+	return n.ch
+}
+
+// notify 通知所有等待者链头已变化
+func (n *tipNotifier) notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.ch != nil {
+		close(n.ch)
+		n.ch = nil
+	}
+}
+
+// MinerStats 获取挖矿统计信息
+func (bc *Blockchain) MinerStats() MinerStats {
+	bc.statsMu.Lock()
+	defer bc.statsMu.Unlock()
+
+	stats := bc.stats
+	stats.Workers = bc.mining.Workers
+	return stats
+}
+
+// mineBlock 使用多个工作协程并行搜索满足难度的 nonce
+// 第 w 个协程依次尝试 w, w+N, w+2N... 的 nonce，ctx 取消或链头变化时提前返回
+func (bc *Blockchain) mineBlock(ctx context.Context, block *models.Block) (*models.Block, error) {
+	workers := bc.mining.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	tipChanged := bc.tip.wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	bc.setMining(true)
+	defer bc.setMining(false)
+
+	var (
+		hashes uint64
+		found  = make(chan *models.Block, 1)
+		wg     sync.WaitGroup
+	)
+	start := time.Now()
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(nonce int) {
+			defer wg.Done()
+
+			candidate := *block
+			var local uint64
+			defer func() { atomic.AddUint64(&hashes, local) }()
+
+			for ; ; nonce += workers {
+				if local%cancelCheckInterval == 0 {
+					select {
+					case <-ctx.Done():
+						return
+					case <-tipChanged:
+						return
+					default:
+					}
+				}
+
+				candidate.Nonce = nonce
+				candidate.Hash = calculateHash(&candidate)
+				local++
+
+				if meetsDifficulty(candidate.Hash, candidate.Difficulty) {
+					select {
+					case found <- &candidate:
+					default:
+					}
+					cancel()
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	bc.recordMining(atomic.LoadUint64(&hashes), time.Since(start))
+
+	select {
+	case mined := <-found:
+		bc.recordBlockMined()
+		return mined, nil
+	default:
+	}
+
+	select {
+	case <-tipChanged:
+		return nil, ErrStaleTip
+	default:
+		return nil, apperr.Wrap(apperr.Unavailable, apperr.CodeMiningTimeout, fmt.Errorf("mining aborted: %w", ctx.Err()))
+	}
+}
+
+func (bc *Blockchain) setMining(mining bool) {
+	bc.statsMu.Lock()
+	defer bc.statsMu.Unlock()
+
+	bc.stats.Mining = mining
+}
+
+func (bc *Blockchain) recordMining(hashes uint64, elapsed time.Duration) {
+	bc.statsMu.Lock()
+	defer bc.statsMu.Unlock()
+
+	bc.stats.TotalHashes += hashes
+	bc.stats.LastHashes = hashes
+	bc.stats.LastElapsed = elapsed
+	if elapsed > 0 {
+		bc.stats.LastHashRate = float64(hashes) / elapsed.Seconds()
+	}
+}
+
+func (bc *Blockchain) recordBlockMined() {
+	bc.statsMu.Lock()
+	defer bc.statsMu.Unlock()
+
+	bc.stats.BlocksMined++
+}
@@ -0,0 +1,184 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"hello-go/apperr"
+	"hello-go/config"
+	"hello-go/database"
+	"hello-go/models"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// newUnminableBlockchain 创建难度为 64 的区块链，挖矿永远找不到满足难度的哈希，只能被取消或因链头变化而中止
+// 交易池中有一笔待打包的交易
+func newUnminableBlockchain(t *testing.T, workers int) *Blockchain {
+	t.Helper()
+
+	db := database.NewBlockchainMemory()
+	ks := keystore.NewKeyStore(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP)
+	chain := &config.ChainConfig{ChainID: testChainID, GenesisTime: time.Unix(0, 0), Decimals: 18, BlockReward: "0"}
+	mining := &config.MiningConfig{
+		InitialDifficulty: 64, MinDifficulty: 1, MaxDifficulty: 64,
+		MaxTransactionsPerBlock: 10, Workers: workers, Timeout: time.Minute,
+	}
+	bc := NewBlockchain(db, ks, chain, mining)
+	if _, err := bc.CreateGenesisBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	key := mustGenerateKey(t)
+	sender := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	recipient := "0x00000000000000000000000000000000000000b0"
+	for _, wallet := range []*models.Wallet{{Address: sender, Balance: models.NewAmount(100)}, {Address: recipient}} {
+		if err := db.SaveWallet(wallet); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tx := &models.Transaction{FromAddr: sender, ToAddr: recipient, Amount: models.NewAmount(10), ChainID: testChainID}
+	if err := SignTransaction(tx, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bc.Transfer(tx); err != nil {
+		t.Fatal(err)
+	}
+	return bc
+}
+
+// waitMining 等待后台挖矿开始
+func waitMining(t *testing.T, bc *Blockchain) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !bc.MinerStats().Mining {
+		if time.Now().After(deadline) {
+			t.Fatal("mining did not start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMiningAborts(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers int
+		// interrupt 在挖矿开始后中止挖矿：提交竞争区块或取消 ctx
+		interrupt func(t *testing.T, bc *Blockchain, cancel context.CancelFunc)
+		wantErr   func(err error) bool
+		// wantHeight 中止后的链高度，竞争区块上链时为 1
+		wantHeight int
+	}{
+		{
+			name:    "tip changes with one worker",
+			workers: 1,
+			interrupt: func(t *testing.T, bc *Blockchain, _ context.CancelFunc) {
+				commitCompetingBlock(t, bc)
+			},
+			wantErr:    func(err error) bool { return errors.Is(err, ErrStaleTip) },
+			wantHeight: 1,
+		},
+		{
+			name:    "tip changes with parallel workers",
+			workers: 4,
+			interrupt: func(t *testing.T, bc *Blockchain, _ context.CancelFunc) {
+				commitCompetingBlock(t, bc)
+			},
+			wantErr:    func(err error) bool { return errors.Is(err, ErrStaleTip) },
+			wantHeight: 1,
+		},
+		{
+			name:    "context cancelled",
+			workers: 4,
+			interrupt: func(_ *testing.T, _ *Blockchain, cancel context.CancelFunc) {
+				cancel()
+			},
+			wantErr:    func(err error) bool { return apperr.CodeOf(err) == apperr.CodeMiningTimeout },
+			wantHeight: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := newUnminableBlockchain(t, tt.workers)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			done := make(chan error, 1)
+			go func() {
+				_, _, err := bc.MinePendingTransactions(ctx)
+				done <- err
+			}()
+			waitMining(t, bc)
+			tt.interrupt(t, bc, cancel)
+
+			select {
+			case err := <-done:
+				if !tt.wantErr(err) {
+					t.Fatalf("MinePendingTransactions error = %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("mining did not stop")
+			}
+
+			if bc.MinerStats().Mining {
+				t.Error("miner still reports mining")
+			}
+			if n := len(bc.PendingTransactions()); n != 1 {
+				t.Errorf("%d pending transactions, want the transaction to stay in the mempool", n)
+			}
+			latest, err := bc.db.GetLatestBlock()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if latest.Index != tt.wantHeight {
+				t.Errorf("height = %d, want %d", latest.Index, tt.wantHeight)
+			}
+		})
+	}
+}
+
+// commitCompetingBlock 将另一个区块接在链头之后，模拟挖矿期间收到其他节点的区块
+func commitCompetingBlock(t *testing.T, bc *Blockchain) {
+	t.Helper()
+
+	latest, err := bc.db.GetLatestBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := &models.Block{
+		Index:      latest.Index + 1,
+		PrevHash:   latest.Hash,
+		Data:       "competing block",
+		MerkleRoot: emptyMerkleRoot,
+		Timestamp:  latest.Timestamp.Add(time.Second),
+		Difficulty: latest.Difficulty,
+	}
+	block.Hash = calculateHash(block)
+	if err := bc.commitBlock(block, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestParallelMiningFindsValidNonce 多个工作协程并行搜索，找到的区块满足难度且哈希与内容一致
+func TestParallelMiningFindsValidNonce(t *testing.T) {
+	for _, workers := range []int{1, 3, 8} {
+		bc, _ := newTestBlockchain(t, database.NewBlockchainMemory())
+		bc.mining.Workers = workers
+
+		block := &models.Block{Index: 1, PrevHash: "parent", MerkleRoot: emptyMerkleRoot, Timestamp: time.Unix(1, 0), Difficulty: 2}
+		mined, err := bc.mineBlock(context.Background(), block)
+		if err != nil {
+			t.Fatalf("%d workers: %v", workers, err)
+		}
+		if mined.Hash != calculateHash(mined) || !meetsDifficulty(mined.Hash, 2) {
+			t.Errorf("%d workers: mined hash %s with nonce %d is invalid", workers, mined.Hash, mined.Nonce)
+		}
+		if stats := bc.MinerStats(); stats.BlocksMined != 1 || stats.Mining || stats.LastHashes == 0 {
+			t.Errorf("%d workers: stats = %+v", workers, stats)
+		}
+	}
+}
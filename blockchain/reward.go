@@ -0,0 +1,121 @@
+package blockchain
+
+import (
+	"fmt"
+	"hello-go/models"
+)
+
+// BlockSubsidy 高度为 index 的区块的出块奖励，每 HalvingInterval 个区块减半，创世区块没有出块奖励
+func (bc *Blockchain) BlockSubsidy(index int) models.Amount {
+	if index <= 0 {
+		return models.Amount{}
+	}
+	if bc.chain.HalvingInterval == 0 {
+		return bc.blockReward
+	}
+	return bc.blockReward.Rsh(uint(index / bc.chain.HalvingInterval))
+}
+
+// maxCoinbase coinbase 交易最多可以发放的金额：出块奖励加区块内全部交易的手续费
+func (bc *Blockchain) maxCoinbase(index int, txs []*models.Transaction) (models.Amount, error) {
+	total := bc.BlockSubsidy(index)
+	for _, tx := range txs {
+		if tx.IsCoinbase() {
+			continue
+		}
+		var ok bool
+		if total, ok = total.Add(tx.Fee); !ok {
+			return models.Amount{}, fmt.Errorf("block reward and fees overflow")
+		}
+	}
+	return total, nil
+}
+
+// newCoinbase 创建向矿工发放出块奖励和区块内手续费的 coinbase 交易
+// 未配置矿工地址或可发放金额为 0 时返回 nil
+func (bc *Blockchain) newCoinbase(index int, txs []*models.Transaction) (*models.Transaction, error) {
+	if bc.mining.MinerAddress == "" {
+		return nil, nil
+	}
+	amount, err := bc.maxCoinbase(index, txs)
+	if err != nil {
+		return nil, err
+	}
+	if amount.IsZero() {
+		return nil, nil
+	}
+	return NewCoinbase(index, bc.mining.MinerAddress, amount, bc.chain.ChainID), nil
+}
+
+// verifyCoinbase 校验区块的 coinbase 交易：最多一笔且位于区块首位，
+// 序号等于区块高度，金额不超过出块奖励与区块内手续费之和，未领取的部分被销毁
+func (bc *Blockchain) verifyCoinbase(index int, txs []*models.Transaction) error {
+	for i, tx := range txs {
+		if tx.IsCoinbase() && i != 0 {
+			return fmt.Errorf("coinbase transaction must be the first transaction in the block")
+		}
+	}
+	if len(txs) == 0 || !txs[0].IsCoinbase() {
+		return nil
+	}
+
+	coinbase := txs[0]
+	if coinbase.Signature != coinbaseSignature {
+		return fmt.Errorf("coinbase transaction must not be signed")
+	}
+	if coinbase.ChainID != bc.chain.ChainID {
+		return fmt.Errorf("coinbase chain id %d does not match %d", coinbase.ChainID, bc.chain.ChainID)
+	}
+	if coinbase.Nonce != uint64(index) {
+		return fmt.Errorf("coinbase nonce %d does not match block index %d", coinbase.Nonce, index)
+	}
+	if !coinbase.Fee.IsZero() {
+		return fmt.Errorf("coinbase transaction must not pay a fee")
+	}
+	if coinbase.Amount.IsZero() {
+		return fmt.Errorf("coinbase amount must be positive")
+	}
+
+	limit, err := bc.maxCoinbase(index, txs)
+	if err != nil {
+		return err
+	}
+	if coinbase.Amount.Cmp(limit) > 0 {
+		return fmt.Errorf("coinbase amount %s exceeds block reward and fees %s", coinbase.Amount, limit)
+	}
+	return nil
+}
+
+// SupplyInfo 代币供应情况
+type SupplyInfo struct {
+	// TotalSupply 全部钱包的余额之和
+	TotalSupply models.Amount `json:"total_supply"`
+	// BlockReward 下一个区块的出块奖励
+	BlockReward     models.Amount `json:"block_reward"`
+	HalvingInterval int           `json:"halving_interval"`
+	// NextHalving 下一次出块奖励减半的区块高度，不减半时为 0
+	NextHalving int `json:"next_halving"`
+}
+
+// Supply 统计当前的代币供应量和出块奖励
+func (bc *Blockchain) Supply() (*SupplyInfo, error) {
+	total, err := bc.db.GetTotalSupply()
+	if err != nil {
+		return nil, err
+	}
+	latest, err := bc.db.GetLatestBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	next := latest.Index + 1
+	info := &SupplyInfo{
+		TotalSupply:     total,
+		BlockReward:     bc.BlockSubsidy(next),
+		HalvingInterval: bc.chain.HalvingInterval,
+	}
+	if interval := bc.chain.HalvingInterval; interval > 0 {
+		info.NextHalving = (next/interval + 1) * interval
+	}
+	return info, nil
+}
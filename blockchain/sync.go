@@ -0,0 +1,224 @@
+package blockchain
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"hello-go/models"
+	"log"
+	"math/big"
+	"time"
+)
+
+var (
+	// ErrKnownBlock 区块已在本地链上
+	ErrKnownBlock = errors.New("block already known")
+	// ErrUnknownParent 区块的父区块不在主链或侧链上，需要先同步缺失的区块
+	ErrUnknownParent = errors.New("unknown parent block")
+)
+
+// ChainStatus 链状态摘要，节点间据此判断是否需要同步
+type ChainStatus struct {
+	ChainID     uint64   `json:"chain_id"`
+	Height      int      `json:"height"`
+	TipHash     string   `json:"tip_hash"`
+	GenesisHash string   `json:"genesis_hash"`
+	TotalWork   *big.Int `json:"total_work"`
+}
+
+// BlockWithTransactions 区块及其包含的交易，用于节点间传输
+type BlockWithTransactions struct {
+	Block        *models.Block         `json:"block"`
+	Transactions []*models.Transaction `json:"transactions"`
+}
+
+// Status 获取本地链状态
+func (bc *Blockchain) Status() (*ChainStatus, error) {
+	latest, err := bc.db.GetLatestBlock()
+	if err != nil {
+		return nil, err
+	}
+	genesis, err := bc.db.GetBlockByIndex(0)
+	if err != nil {
+		return nil, err
+	}
+	work, err := bc.TotalWork()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChainStatus{
+		ChainID:     bc.chain.ChainID,
+		Height:      latest.Index,
+		TipHash:     latest.Hash,
+		GenesisHash: genesis.Hash,
+		TotalWork:   work,
+	}, nil
+}
+
+// GetBlocksFrom 从索引 from 开始按顺序获取至多 limit 个区块及其交易
+func (bc *Blockchain) GetBlocksFrom(from, limit int) ([]*BlockWithTransactions, error) {
+	result := []*BlockWithTransactions{}
+	if limit <= 0 {
+		return result, nil
+	}
+
+	blocks, txsByBlock, err := bc.blockRange(from, from+limit-1)
+	if err != nil {
+		return nil, err
+	}
+	for _, block := range blocks {
+		result = append(result, &BlockWithTransactions{Block: block, Transactions: txsByBlock[block.ID]})
+	}
+	return result, nil
+}
+
+// blockRange 获取高度在 [from, to] 内的主链区块及按区块ID分组的交易
+func (bc *Blockchain) blockRange(from, to int) ([]*models.Block, map[int64][]*models.Transaction, error) {
+	blocks, err := bc.db.GetBlocksByRange(from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+	txs, err := bc.db.GetTransactionsByBlockRange(from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	txsByBlock := make(map[int64][]*models.Transaction, len(blocks))
+	for _, tx := range txs {
+		txsByBlock[tx.BlockID] = append(txsByBlock[tx.BlockID], tx)
+	}
+	return blocks, txsByBlock, nil
+}
+
+// AddBlock 校验并导入其他节点产生的区块
+// 接在链头之后的区块直接上链；接在主链其他位置或侧链上的区块作为侧链保存，
+// 侧链累计工作量超过主链时进行链重组
+func (bc *Blockchain) AddBlock(block *models.Block, txs []*models.Transaction) error {
+	if err := bc.verifyBlockContents(block, txs); err != nil {
+		return err
+	}
+
+	bc.chainMu.Lock()
+	update, err := bc.addBlock(block, txs)
+	bc.chainMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if update != nil {
+		bc.afterUpdate(update)
+	}
+	return nil
+}
+
+// addBlock 将区块接到链头之后或保存为侧链，调用方需持有 chainMu
+// 主链未发生变化时返回的 chainUpdate 为 nil
+func (bc *Blockchain) addBlock(block *models.Block, txs []*models.Transaction) (*chainUpdate, error) {
+	if err := bc.checkKnown(block.Hash); err != nil {
+		return nil, err
+	}
+
+	latest, err := bc.db.GetLatestBlock()
+	if err != nil {
+		return nil, err
+	}
+	if block.PrevHash != latest.Hash {
+		return bc.addSideBlock(block, txs)
+	}
+
+	if err := bc.attachBlock(block, txs, latest); err != nil {
+		return nil, err
+	}
+	log.Printf("Imported block: Index=%d, Hash=%s, Transactions=%d", block.Index, block.Hash, len(txs))
+	return &chainUpdate{attached: []*BlockWithTransactions{{Block: block, Transactions: txs}}}, nil
+}
+
+// checkKnown 区块已在主链或侧链上时返回 ErrKnownBlock
+func (bc *Blockchain) checkKnown(hash string) error {
+	_, err := bc.db.GetBlockByHash(hash)
+	if err == nil {
+		return ErrKnownBlock
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	_, _, err = bc.db.GetSideBlock(hash)
+	if err == nil {
+		return ErrKnownBlock
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	return nil
+}
+
+// attachBlock 检查区块的高度、难度和时间戳后将其接在 parent（当前链头）之后
+func (bc *Blockchain) attachBlock(block *models.Block, txs []*models.Transaction, parent *models.Block) error {
+	if block.Index != parent.Index+1 {
+		return fmt.Errorf("invalid index %d, expected %d", block.Index, parent.Index+1)
+	}
+
+	difficulty, err := bc.NextDifficulty(parent)
+	if err != nil {
+		return err
+	}
+	if block.Difficulty != difficulty {
+		return fmt.Errorf("invalid difficulty %d, expected %d", block.Difficulty, difficulty)
+	}
+	if block.Timestamp.Before(parent.Timestamp) {
+		return fmt.Errorf("block timestamp is before its parent")
+	}
+
+	// 区块ID和交易ID由本地数据库分配
+	resetIDs(block, txs)
+
+	if err := setHashes(txs); err != nil {
+		return err
+	}
+	return bc.db.CommitBlock(block, txs)
+}
+
+// resetIDs 清除区块和交易在其他数据库中分配的ID
+func resetIDs(block *models.Block, txs []*models.Transaction) {
+	block.ID = 0
+	for _, tx := range txs {
+		tx.ID = 0
+		tx.BlockID = 0
+	}
+}
+
+// verifyBlockContents 校验不依赖本地链状态的区块内容：哈希、工作量证明、Merkle 根和交易签名
+func (bc *Blockchain) verifyBlockContents(block *models.Block, txs []*models.Transaction) error {
+	if block.Hash != calculateHash(block) {
+		return fmt.Errorf("invalid block hash")
+	}
+	if !meetsDifficulty(block.Hash, block.Difficulty) {
+		return fmt.Errorf("block hash does not meet difficulty %d", block.Difficulty)
+	}
+	if block.Timestamp.After(time.Now().Add(maxFutureBlockTime)) {
+		return fmt.Errorf("block timestamp is too far in the future")
+	}
+
+	merkleRoot, err := ComputeMerkleRoot(txs)
+	if err != nil {
+		return err
+	}
+	if merkleRoot != block.MerkleRoot {
+		return fmt.Errorf("merkle root does not match transactions")
+	}
+
+	if err := bc.verifyCoinbase(block.Index, txs); err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		if tx.IsCoinbase() {
+			continue
+		}
+		if err := VerifyTransaction(tx, bc.chain.ChainID); err != nil {
+			return fmt.Errorf("invalid transaction: %v", err)
+		}
+	}
+	return nil
+}
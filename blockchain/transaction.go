@@ -0,0 +1,235 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"hello-go/apperr"
+	"hello-go/models"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// invalidTransaction 交易字段或编码无效
+func invalidTransaction(format string, args ...interface{}) error {
+	return apperr.New(apperr.Validation, apperr.CodeInvalidTransaction, format, args...)
+}
+
+// invalidSignature 签名无效或与 from 地址不符
+func invalidSignature(format string, args ...interface{}) error {
+	return apperr.New(apperr.Validation, apperr.CodeInvalidSignature, format, args...)
+}
+
+// signingPayload 参与签名的交易字段，按固定顺序进行 RLP 编码
+// 手续费为 0 时省略 Fee，编码与引入手续费之前相同，已有交易的签名保持有效
+type signingPayload struct {
+	ChainID uint64
+	Nonce   uint64
+	From    common.Address
+	To      common.Address
+	Amount  string
+	Fee     string `rlp:"optional"`
+}
+
+// signedTransaction 已签名交易的规范编码：签名字段之后附加 65 字节签名，手续费不为 0 时再附加手续费
+type signedTransaction struct {
+	ChainID   uint64
+	Nonce     uint64
+	From      common.Address
+	To        common.Address
+	Amount    string
+	Signature []byte
+	Fee       string `rlp:"optional"`
+}
+
+// coinbaseSignature coinbase 交易没有签名
+const coinbaseSignature = "0x"
+
+// NewCoinbase 创建高度为 index 的区块中向矿工发放 amount 的 coinbase 交易
+// 序号为区块高度，保证不同区块的 coinbase 交易哈希不同
+func NewCoinbase(index int, miner string, amount models.Amount, chainID uint64) *models.Transaction {
+	return &models.Transaction{
+		FromAddr:  models.CoinbaseAddress,
+		ToAddr:    miner,
+		Amount:    amount,
+		Nonce:     uint64(index),
+		ChainID:   chainID,
+		Signature: coinbaseSignature,
+		Timestamp: time.Now(),
+	}
+}
+
+func newSigningPayload(tx *models.Transaction) (*signingPayload, error) {
+	if !common.IsHexAddress(tx.FromAddr) {
+		return nil, invalidTransaction("invalid from address: %s", tx.FromAddr)
+	}
+	if !common.IsHexAddress(tx.ToAddr) {
+		return nil, invalidTransaction("invalid to address: %s", tx.ToAddr)
+	}
+
+	payload := &signingPayload{
+		ChainID: tx.ChainID,
+		Nonce:   tx.Nonce,
+		From:    common.HexToAddress(tx.FromAddr),
+		To:      common.HexToAddress(tx.ToAddr),
+		Amount:  tx.Amount.String(),
+	}
+	if !tx.Fee.IsZero() {
+		payload.Fee = tx.Fee.String()
+	}
+	return payload, nil
+}
+
+// SigningHash 计算交易的签名哈希
+// keccak256(rlp([chain_id, nonce, from, to, amount, fee]))，amount 和 fee 使用以最小单位表示的十进制字符串编码，
+// fee 为 0 时省略
+func SigningHash(tx *models.Transaction) (common.Hash, error) {
+	payload, err := newSigningPayload(tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	encoded, err := rlp.EncodeToBytes(payload)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	return crypto.Keccak256Hash(encoded), nil
+}
+
+// EncodeTransaction 对已签名交易进行规范 RLP 编码
+// rlp([chain_id, nonce, from, to, amount, signature, fee])，即 eth_sendRawTransaction 接受的原始交易格式，fee 为 0 时省略
+func EncodeTransaction(tx *models.Transaction) ([]byte, error) {
+	payload, err := newSigningPayload(tx)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := hexutil.Decode(tx.Signature)
+	if err != nil {
+		return nil, invalidSignature("invalid signature encoding: %v", err)
+	}
+
+	return rlp.EncodeToBytes(&signedTransaction{
+		ChainID:   payload.ChainID,
+		Nonce:     payload.Nonce,
+		From:      payload.From,
+		To:        payload.To,
+		Amount:    payload.Amount,
+		Signature: sig,
+		Fee:       payload.Fee,
+	})
+}
+
+// DecodeTransaction 解析 EncodeTransaction 编码的原始交易，不校验签名
+func DecodeTransaction(data []byte) (*models.Transaction, error) {
+	var decoded signedTransaction
+	if err := rlp.DecodeBytes(data, &decoded); err != nil {
+		return nil, invalidTransaction("invalid transaction encoding: %v", err)
+	}
+
+	amount, err := models.ParseAmount(decoded.Amount)
+	if err != nil {
+		return nil, err
+	}
+	var fee models.Amount
+	if decoded.Fee != "" {
+		if fee, err = models.ParseAmount(decoded.Fee); err != nil {
+			return nil, err
+		}
+		// 手续费为 0 时必须省略，保证同一笔交易只有一种编码
+		if fee.IsZero() {
+			return nil, invalidTransaction("invalid transaction encoding: zero fee must be omitted")
+		}
+	}
+
+	return &models.Transaction{
+		FromAddr:  decoded.From.Hex(),
+		ToAddr:    decoded.To.Hex(),
+		Amount:    amount,
+		Fee:       fee,
+		Nonce:     decoded.Nonce,
+		ChainID:   decoded.ChainID,
+		Signature: hexutil.Encode(decoded.Signature),
+	}, nil
+}
+
+// TransactionHash 计算交易哈希：keccak256(规范 RLP 编码)
+func TransactionHash(tx *models.Transaction) (common.Hash, error) {
+	encoded, err := EncodeTransaction(tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(encoded), nil
+}
+
+// setHashes 计算并设置交易哈希，区块交易保存前调用，不使用其他节点或数据库中已有的哈希
+func setHashes(txs []*models.Transaction) error {
+	for _, tx := range txs {
+		hash, err := TransactionHash(tx)
+		if err != nil {
+			return err
+		}
+		tx.Hash = hash.Hex()
+	}
+	return nil
+}
+
+// SignTransaction 使用私钥对交易签名，签名以 0x 开头的 65 字节 [R || S || V] 十六进制保存
+func SignTransaction(tx *models.Transaction, key *ecdsa.PrivateKey) error {
+	hash, err := SigningHash(tx)
+	if err != nil {
+		return err
+	}
+
+	sig, err := crypto.Sign(hash.Bytes(), key)
+	if err != nil {
+		return err
+	}
+
+	tx.Signature = hexutil.Encode(sig)
+	return nil
+}
+
+// RecoverSender 从交易签名中恢复签名者地址
+func RecoverSender(tx *models.Transaction) (common.Address, error) {
+	hash, err := SigningHash(tx)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	sig, err := hexutil.Decode(tx.Signature)
+	if err != nil {
+		return common.Address{}, invalidSignature("invalid signature encoding: %v", err)
+	}
+	if len(sig) != crypto.SignatureLength {
+		return common.Address{}, invalidSignature("invalid signature length: %d", len(sig))
+	}
+
+	pub, err := crypto.SigToPub(hash.Bytes(), sig)
+	if err != nil {
+		return common.Address{}, invalidSignature("invalid signature: %v", err)
+	}
+
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// VerifyTransaction 校验交易的链ID、金额和签名，签名者必须与 from 地址一致
+func VerifyTransaction(tx *models.Transaction, chainID uint64) error {
+	if tx.Amount.IsZero() {
+		return invalidTransaction("invalid amount: %s", tx.Amount)
+	}
+	if tx.ChainID != chainID {
+		return invalidTransaction("invalid chain id: expected %d, got %d", chainID, tx.ChainID)
+	}
+
+	signer, err := RecoverSender(tx)
+	if err != nil {
+		return err
+	}
+	if signer != common.HexToAddress(tx.FromAddr) {
+		return invalidSignature("signer %s does not match from address %s", signer.Hex(), tx.FromAddr)
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package blockchain
+
+import (
+	"fmt"
+	"hello-go/models"
+	"strings"
+	"time"
+)
+
+// 区块校验规则
+const (
+	RuleGenesis     = "genesis"
+	RuleIndex       = "index"
+	RulePrevHash    = "prev_hash"
+	RuleHash        = "hash"
+	RuleProofOfWork = "proof_of_work"
+	RuleDifficulty  = "difficulty"
+	RuleTimestamp   = "timestamp"
+	RuleMerkleRoot  = "merkle_root"
+	RuleNonce       = "nonce"
+	RuleCoinbase    = "coinbase"
+)
+
+// 区块时间戳允许超前本地时间的最大值
+const maxFutureBlockTime = 2 * time.Minute
+
+// ValidationIssue 某个区块违反的一条校验规则
+type ValidationIssue struct {
+	Index  int    `json:"index"`
+	Hash   string `json:"hash"`
+	Rule   string `json:"rule"`
+	Reason string `json:"reason"`
+}
+
+// ValidationReport 区块链完整校验结果
+type ValidationReport struct {
+	Valid         bool `json:"valid"`
+	BlocksChecked int  `json:"blocks_checked"`
+	// FirstBadIndex 第一个未通过校验的区块索引，全部通过时为 -1
+	FirstBadIndex int               `json:"first_bad_index"`
+	Issues        []ValidationIssue `json:"issues"`
+	CheckedAt     time.Time         `json:"checked_at"`
+}
+
+func (r *ValidationReport) addIssue(block *models.Block, rule, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, ValidationIssue{
+		Index:  block.Index,
+		Hash:   block.Hash,
+		Rule:   rule,
+		Reason: fmt.Sprintf(format, args...),
+	})
+	if r.Valid || block.Index < r.FirstBadIndex {
+		r.FirstBadIndex = block.Index
+	}
+	r.Valid = false
+}
+
+// ValidateChain 按全部规则校验区块链，收集每个区块违反的规则而不是遇到第一个错误就停止
+func (bc *Blockchain) ValidateChain() (*ValidationReport, error) {
+	blocks, err := bc.db.GetAllBlocks()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ValidationReport{
+		Valid:         true,
+		BlocksChecked: len(blocks),
+		FirstBadIndex: -1,
+		Issues:        []ValidationIssue{},
+		CheckedAt:     time.Now(),
+	}
+	if len(blocks) == 0 {
+		return report, nil
+	}
+
+	bc.validateGenesis(report, blocks[0])
+
+	now := time.Now()
+	// 每个转出地址（小写）的下一个交易序号
+	nonces := make(map[string]uint64)
+	for i, block := range blocks {
+		// 验证当前区块哈希
+		if block.Hash != calculateHash(block) {
+			report.addIssue(block, RuleHash, "stored hash %s does not match computed hash %s", block.Hash, calculateHash(block))
+		}
+
+		// 验证 Merkle 根与区块中的交易一致
+		txs, err := bc.db.GetTransactionsByBlockID(block.ID)
+		if err != nil {
+			return nil, err
+		}
+		merkleRoot, err := ComputeMerkleRoot(txs)
+		if err != nil {
+			report.addIssue(block, RuleMerkleRoot, "cannot compute merkle root: %v", err)
+		} else if merkleRoot != block.MerkleRoot {
+			report.addIssue(block, RuleMerkleRoot, "merkle root %s does not match transactions (%s)", block.MerkleRoot, merkleRoot)
+		}
+
+		// 验证每个转出地址的交易序号从 0 开始连续递增
+		for _, tx := range txs {
+			if tx.IsCoinbase() {
+				continue
+			}
+			sender := strings.ToLower(tx.FromAddr)
+			if tx.Nonce != nonces[sender] {
+				report.addIssue(block, RuleNonce, "transaction %d from %s has nonce %d, expected %d",
+					tx.ID, tx.FromAddr, tx.Nonce, nonces[sender])
+			}
+			nonces[sender] = tx.Nonce + 1
+		}
+
+		// 验证 coinbase 交易的位置和金额
+		if err := bc.verifyCoinbase(block.Index, txs); err != nil {
+			report.addIssue(block, RuleCoinbase, "%v", err)
+		}
+
+		if block.Timestamp.After(now.Add(maxFutureBlockTime)) {
+			report.addIssue(block, RuleTimestamp, "timestamp %s is too far in the future", block.Timestamp.Format(time.RFC3339))
+		}
+
+		if i == 0 {
+			continue
+		}
+		prevBlock := blocks[i-1]
+
+		// 验证索引连续
+		if block.Index != prevBlock.Index+1 {
+			report.addIssue(block, RuleIndex, "expected index %d after %d", prevBlock.Index+1, prevBlock.Index)
+		}
+
+		// 验证与前一个区块的哈希链接
+		if block.PrevHash != prevBlock.Hash {
+			report.addIssue(block, RulePrevHash, "prev_hash %s does not match previous block hash %s", block.PrevHash, prevBlock.Hash)
+		}
+
+		// 验证工作量证明
+		if !meetsDifficulty(block.Hash, block.Difficulty) {
+			report.addIssue(block, RuleProofOfWork, "hash does not have %d leading zeros", block.Difficulty)
+		}
+
+		// 验证难度符合调整规则
+		if expected := expectedDifficulty(bc.mining, blocks, i); block.Difficulty != expected {
+			report.addIssue(block, RuleDifficulty, "difficulty %d, expected %d", block.Difficulty, expected)
+		}
+
+		// 验证时间戳不早于前一个区块
+		if block.Timestamp.Before(prevBlock.Timestamp) {
+			report.addIssue(block, RuleTimestamp, "timestamp %s is before previous block %s",
+				block.Timestamp.Format(time.RFC3339), prevBlock.Timestamp.Format(time.RFC3339))
+		}
+	}
+
+	return report, nil
+}
+
+// validateGenesis 校验创世区块的固定字段
+func (bc *Blockchain) validateGenesis(report *ValidationReport, genesis *models.Block) {
+	if genesis.Index != 0 {
+		report.addIssue(genesis, RuleGenesis, "first block has index %d, expected 0", genesis.Index)
+	}
+	if genesis.PrevHash != "" {
+		report.addIssue(genesis, RuleGenesis, "genesis prev_hash must be empty")
+	}
+	if genesis.Data != genesisData {
+		report.addIssue(genesis, RuleGenesis, "unexpected genesis data %q", genesis.Data)
+	}
+	if genesis.Difficulty != bc.mining.InitialDifficulty {
+		report.addIssue(genesis, RuleGenesis, "genesis difficulty %d, expected %d", genesis.Difficulty, bc.mining.InitialDifficulty)
+	}
+	if bc.chain.GenesisHash != "" && genesis.Hash != bc.chain.GenesisHash {
+		report.addIssue(genesis, RuleGenesis, "genesis hash %s does not match configured %s", genesis.Hash, bc.chain.GenesisHash)
+	}
+}
+
+// meetsDifficulty 判断哈希是否满足难度要求的前导零个数
+func meetsDifficulty(hash string, difficulty int) bool {
+	return strings.HasPrefix(hash, strings.Repeat("0", difficulty))
+}
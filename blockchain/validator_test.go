@@ -0,0 +1,133 @@
+package blockchain
+
+import (
+	"hello-go/database"
+	"hello-go/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateChain(t *testing.T) {
+	type issue struct {
+		index int
+		rule  string
+	}
+
+	tests := []struct {
+		name string
+		// tamperIndexes 被篡改的区块高度（1~3），tamper 在区块求解之后、保存之前修改区块，返回 true 时重新求解工作量证明
+		tamperIndexes []int
+		tamper        func(block *models.Block) bool
+		wantIssues    []issue
+		wantFirstBad  int
+	}{
+		{name: "valid chain", wantFirstBad: -1},
+		{
+			name:          "data changed after mining",
+			tamperIndexes: []int{2},
+			tamper:        func(b *models.Block) bool { b.Data = "tampered"; return false },
+			wantIssues:    []issue{{2, RuleHash}},
+			wantFirstBad:  2,
+		},
+		{
+			name:          "hash recomputed without proof of work",
+			tamperIndexes: []int{2},
+			tamper: func(b *models.Block) bool {
+				// 找到一个不满足难度的 nonce，哈希与内容一致但工作量证明无效
+				for b.Hash = calculateHash(b); meetsDifficulty(b.Hash, b.Difficulty); b.Hash = calculateHash(b) {
+					b.Nonce++
+				}
+				return false
+			},
+			wantIssues:   []issue{{2, RuleProofOfWork}},
+			wantFirstBad: 2,
+		},
+		{
+			name:          "broken link",
+			tamperIndexes: []int{2},
+			tamper:        func(b *models.Block) bool { b.PrevHash = strings.Repeat("f", 64); return true },
+			wantIssues:    []issue{{2, RulePrevHash}},
+			wantFirstBad:  2,
+		},
+		{
+			// 之后的区块应沿用被篡改区块的难度，因此篡改链头区块
+			name:          "difficulty off schedule",
+			tamperIndexes: []int{3},
+			tamper:        func(b *models.Block) bool { b.Difficulty = 2; return true },
+			wantIssues:    []issue{{3, RuleDifficulty}},
+			wantFirstBad:  3,
+		},
+		{
+			name:          "merkle root does not match transactions",
+			tamperIndexes: []int{2},
+			tamper:        func(b *models.Block) bool { b.MerkleRoot = strings.Repeat("a", 64); return true },
+			wantIssues:    []issue{{2, RuleMerkleRoot}},
+			wantFirstBad:  2,
+		},
+		{
+			name:          "timestamp before parent",
+			tamperIndexes: []int{2},
+			tamper:        func(b *models.Block) bool { b.Timestamp = b.Timestamp.Add(-time.Hour); return true },
+			wantIssues:    []issue{{2, RuleTimestamp}},
+			wantFirstBad:  2,
+		},
+		{
+			name:          "issues in several blocks",
+			tamperIndexes: []int{3, 1},
+			tamper:        func(b *models.Block) bool { b.Data = "tampered"; return false },
+			wantIssues:    []issue{{1, RuleHash}, {3, RuleHash}},
+			wantFirstBad:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := database.NewBlockchainMemory()
+			bc, _ := newTestBlockchain(t, store)
+
+			tampered := make(map[int]bool)
+			for _, index := range tt.tamperIndexes {
+				tampered[index] = true
+			}
+			parent, err := store.GetLatestBlock()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 1; i <= 3; i++ {
+				block := solveBlock(parent, "block", 1, parent.Timestamp.Add(time.Minute))
+				if tampered[i] && tt.tamper(block) {
+					solve(block)
+				}
+				if err := store.SaveBlock(block); err != nil {
+					t.Fatal(err)
+				}
+				parent = block
+			}
+
+			report, err := bc.ValidateChain()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.BlocksChecked != 4 {
+				t.Errorf("blocks checked = %d, want 4", report.BlocksChecked)
+			}
+			if report.Valid != (len(tt.wantIssues) == 0) || report.FirstBadIndex != tt.wantFirstBad {
+				t.Errorf("valid = %v, first bad index = %d; want %v, %d", report.Valid, report.FirstBadIndex, len(tt.wantIssues) == 0, tt.wantFirstBad)
+			}
+
+			var got []issue
+			for _, i := range report.Issues {
+				got = append(got, issue{i.Index, i.Rule})
+			}
+			if len(got) != len(tt.wantIssues) {
+				t.Fatalf("issues = %+v, want %v", report.Issues, tt.wantIssues)
+			}
+			for i := range got {
+				if got[i] != tt.wantIssues[i] {
+					t.Errorf("issue %d = %v, want %v", i, got[i], tt.wantIssues[i])
+				}
+			}
+		})
+	}
+}
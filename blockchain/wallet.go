@@ -0,0 +1,126 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"hello-go/apperr"
+	"hello-go/models"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// findAccount 在 keystore 中查找地址对应的账户
+func (bc *Blockchain) findAccount(address string) (accounts.Account, error) {
+	if !common.IsHexAddress(address) {
+		return accounts.Account{}, apperr.New(apperr.Validation, "", "invalid address: %s", address)
+	}
+	account, err := bc.keystore.Find(accounts.Account{Address: common.HexToAddress(address)})
+	if err != nil {
+		return accounts.Account{}, keystoreError(err)
+	}
+	return account, nil
+}
+
+// keystoreError 为 keystore 返回的错误标注类别
+func keystoreError(err error) error {
+	switch {
+	case errors.Is(err, keystore.ErrNoMatch):
+		return apperr.Wrap(apperr.NotFound, apperr.CodeWalletNotFound, err)
+	case errors.Is(err, keystore.ErrDecrypt):
+		return apperr.Wrap(apperr.Validation, apperr.CodeInvalidPassphrase, err)
+	case errors.Is(err, keystore.ErrLocked):
+		return apperr.Wrap(apperr.Conflict, apperr.CodeWalletLocked, err)
+	default:
+		return err
+	}
+}
+
+// UnlockWallet 使用口令解锁钱包，超过 timeout 后自动重新锁定
+func (bc *Blockchain) UnlockWallet(address, passphrase string, timeout time.Duration) error {
+	account, err := bc.findAccount(address)
+	if err != nil {
+		return err
+	}
+	return keystoreError(bc.keystore.TimedUnlock(account, passphrase, timeout))
+}
+
+// LockWallet 立即锁定钱包，从内存中清除解密后的私钥
+func (bc *Blockchain) LockWallet(address string) error {
+	account, err := bc.findAccount(address)
+	if err != nil {
+		return err
+	}
+	return keystoreError(bc.keystore.Lock(account.Address))
+}
+
+// ExportWallet 导出加密的 keystore JSON，导出文件使用 newPassphrase 重新加密
+func (bc *Blockchain) ExportWallet(address, passphrase, newPassphrase string) ([]byte, error) {
+	account, err := bc.findAccount(address)
+	if err != nil {
+		return nil, err
+	}
+	keyJSON, err := bc.keystore.Export(account, passphrase, newPassphrase)
+	if err != nil {
+		return nil, keystoreError(err)
+	}
+	return keyJSON, nil
+}
+
+// WalletKey 使用口令解密 keystore 中钱包的私钥，私钥由调用方自行保管，keystore 账户保持锁定
+func (bc *Blockchain) WalletKey(address, passphrase string) (*ecdsa.PrivateKey, error) {
+	account, err := bc.findAccount(address)
+	if err != nil {
+		return nil, err
+	}
+	keyJSON, err := os.ReadFile(account.URL.Path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := keystore.DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return nil, keystoreError(err)
+	}
+	return key.PrivateKey, nil
+}
+
+// ReserveWallet 禁止使用 keystore 为地址代签交易，即使钱包已解锁，该地址的交易也必须由持有私钥的一方签名
+func (bc *Blockchain) ReserveWallet(address string) {
+	bc.reservedMu.Lock()
+	defer bc.reservedMu.Unlock()
+
+	bc.reserved[common.HexToAddress(address).Hex()] = true
+}
+
+// SignWithWallet 使用已解锁的 keystore 账户对交易签名
+func (bc *Blockchain) SignWithWallet(tx *models.Transaction) error {
+	account, err := bc.findAccount(tx.FromAddr)
+	if err != nil {
+		return err
+	}
+
+	bc.reservedMu.RLock()
+	reserved := bc.reserved[account.Address.Hex()]
+	bc.reservedMu.RUnlock()
+	if reserved {
+		return apperr.New(apperr.Validation, apperr.CodeInvalidSignature,
+			"transactions from %s must be signed by the client", tx.FromAddr)
+	}
+
+	hash, err := SigningHash(tx)
+	if err != nil {
+		return err
+	}
+
+	sig, err := bc.keystore.SignHash(account, hash.Bytes())
+	if err != nil {
+		return keystoreError(err)
+	}
+
+	tx.Signature = hexutil.Encode(sig)
+	return nil
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"hello-go/config"
+	"hello-go/database"
+	"hello-go/webhook"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// runCommand 执行命令行子命令，返回进程退出码
+func runCommand(name string, args []string) int {
+	switch name {
+	case "validate":
+		return validateCommand(args)
+	case "webhook-receiver":
+		return webhookReceiverCommand(args)
+	case "config":
+		return configCommand(args)
+	case "migrate":
+		return migrateCommand(args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n", name)
+		fmt.Fprintln(os.Stderr, "usage: blockchain-server [flags] [validate [-json] | config print | migrate up|down|status | webhook-receiver [-addr :9090] [-secret s] [-fail n]]")
+		return 2
+	}
+}
+
+// configCommand 输出生效的配置，数据库密码等敏感信息已隐藏
+func configCommand(args []string) int {
+	if len(args) != 1 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "usage: blockchain-server [flags] config print")
+		return 2
+	}
+
+	if err := config.Print(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to print configuration:", err)
+		return 1
+	}
+	return 0
+}
+
+// migrateCommand 执行或回滚数据库迁移，查看迁移状态
+// up 执行全部未执行的迁移（-to 指定目标版本），down 回滚最近的 -steps 个迁移（默认 1 个）
+func migrateCommand(args []string) int {
+	usage := "usage: blockchain-server [flags] migrate up [-to version] | down [-steps n] | status"
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	dbConfig := config.GetDBConfig()
+	if dbConfig.Driver != config.DriverMySQL {
+		fmt.Fprintf(os.Stderr, "migrate requires the %s driver, current driver is %s\n", config.DriverMySQL, dbConfig.Driver)
+		return 2
+	}
+
+	fs := flag.NewFlagSet("migrate "+args[0], flag.ExitOnError)
+	to := fs.Int64("to", 0, "migrate up to this version, 0 applies all pending migrations")
+	steps := fs.Int("steps", 1, "number of migrations to roll back")
+	fs.Parse(args[1:])
+
+	db, err := database.NewMySQLDB(dbConfig)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to connect to database:", err)
+		return 1
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "up":
+		applied, err := database.MigrateUp(db, *to, config.GetChainConfig().Decimals)
+		for _, m := range applied {
+			fmt.Printf("Applied %d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to migrate database:", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database is up to date")
+		}
+	case "down":
+		if *steps < 1 {
+			fmt.Fprintln(os.Stderr, "-steps must be at least 1")
+			return 2
+		}
+		reverted, err := database.MigrateDown(db, *steps)
+		for _, m := range reverted {
+			fmt.Printf("Rolled back %d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to roll back migration:", err)
+			return 1
+		}
+		if len(reverted) == 0 {
+			fmt.Println("No migrations to roll back")
+		}
+	case "status":
+		statuses, err := database.GetMigrationStatus(db)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get migration status:", err)
+			return 1
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.AppliedAt != nil {
+				state = "applied " + s.AppliedAt.Format(time.RFC3339)
+			}
+			if s.Unknown {
+				state += " (unknown to this binary)"
+			}
+			fmt.Printf("%04d_%-24s %s\n", s.Version, s.Name, state)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	return 0
+}
+
+// validateCommand 完整校验区块链，链无效时以退出码 1 退出
+func validateCommand(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the validation report as JSON")
+	fs.Parse(args)
+
+	a, err := newApp()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer a.Close()
+
+	report, err := a.bc.ValidateChain()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to validate chain:", err)
+		return 2
+	}
+
+	if *asJSON {
+		out, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		fmt.Printf("Blocks checked: %d\n", report.BlocksChecked)
+		if report.Valid {
+			fmt.Println("Result: valid")
+		} else {
+			fmt.Printf("Result: INVALID (first bad block index: %d)\n", report.FirstBadIndex)
+			for _, issue := range report.Issues {
+				fmt.Printf("  block %d [%s] %s\n", issue.Index, issue.Rule, issue.Reason)
+			}
+		}
+	}
+
+	if !report.Valid {
+		return 1
+	}
+	return 0
+}
+
+// webhookReceiverCommand 启动本地回调接收端，打印收到的回调并校验签名，用于调试回调投递
+// -fail 指定前 n 次请求返回 500，用于观察重试
+func webhookReceiverCommand(args []string) int {
+	fs := flag.NewFlagSet("webhook-receiver", flag.ExitOnError)
+	addr := fs.String("addr", ":9090", "listen address")
+	secret := fs.String("secret", "", "webhook secret used to verify signatures")
+	fail := fs.Int64("fail", 0, "respond with 500 to the first n requests")
+	fs.Parse(args)
+
+	var received int64
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		n := atomic.AddInt64(&received, 1)
+		signature := r.Header.Get(webhook.HeaderSignature)
+		verified := "not checked"
+		if *secret != "" {
+			if !webhook.Verify(*secret, body, signature) {
+				log.Printf("#%d %s delivery=%s: invalid signature %q", n, r.Header.Get(webhook.HeaderEvent), r.Header.Get(webhook.HeaderDelivery), signature)
+				http.Error(w, "invalid signature", http.StatusUnauthorized)
+				return
+			}
+			verified = "ok"
+		}
+
+		log.Printf("#%d %s delivery=%s signature=%s\n%s", n, r.Header.Get(webhook.HeaderEvent), r.Header.Get(webhook.HeaderDelivery), verified, body)
+		if n <= *fail {
+			http.Error(w, "simulated failure", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	log.Printf("Webhook receiver listening on %s", *addr)
+	if err := http.ListenAndServe(*addr, nil); err != nil {
+		fmt.Fprintln(os.Stderr, "Webhook receiver failed:", err)
+		return 1
+	}
+	return 0
+}
@@ -1,19 +1,205 @@
 package config
 
+import (
+	"fmt"
+	"time"
+)
+
+// 数据库驱动类型
+const (
+	DriverMySQL  = "mysql"
+	DriverMemory = "memory"
+)
+
+// 日志级别
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+// Config 服务的全部配置，按默认值、配置文件、环境变量、命令行参数的顺序加载，后者覆盖前者
+type Config struct {
+	Server   ServerConfig
+	Database DatabaseConfig
+	Mining   MiningConfig
+	Chain    ChainConfig
+	P2P      P2PConfig
+	KeyStore KeyStoreConfig
+	Webhook  WebhookConfig
+	Faucet   FaucetConfig
+}
+
+// ServerConfig HTTP 服务配置
+type ServerConfig struct {
+	Port int
+	// CORSOrigins 允许跨域访问接口和建立 WebSocket 连接的来源，为空时只允许同源访问，"*" 表示允许所有来源
+	CORSOrigins []string
+	// LogLevel 为 debug 时 Gin 以调试模式运行，warn 和 error 不记录每个请求的访问日志
+	LogLevel string
+	// TrustedProxies 可信反向代理的 IP 或 CIDR，只有来自这些地址的请求才按 X-Forwarded-For 识别客户端 IP
+	TrustedProxies []string
+}
+
+func GetServerConfig() *ServerConfig {
+	c := current().Server
+	c.CORSOrigins = append([]string(nil), c.CORSOrigins...)
+	c.TrustedProxies = append([]string(nil), c.TrustedProxies...)
+	return &c
+}
+
 type DatabaseConfig struct {
+	Driver string
+	// DSN 完整的 MySQL 连接串，设置后忽略 Host、Port、User、Password 和 DBName
+	DSN      string
 	Host     string
 	Port     int
 	User     string
 	Password string
 	DBName   string
+
+	// 连接池配置，0 表示不限制
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+
+	// AutoMigrate 启动时自动执行尚未执行的数据库迁移，关闭后需通过 migrate up 子命令手工执行
+	AutoMigrate bool
 }
 
-func GetDBConfig() *DatabaseConfig {
-	return &DatabaseConfig{
-		Host:     "localhost",
-		Port:     3306,
-		User:     "root",
-		Password: "123456",
-		DBName:   "blockchain_db",
+// DataSourceName MySQL 驱动使用的连接串
+func (c *DatabaseConfig) DataSourceName() string {
+	if c.DSN != "" {
+		return c.DSN
 	}
+	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+		c.User, c.Password, c.Host, c.Port, c.DBName)
+}
+
+func GetDBConfig() *DatabaseConfig {
+	c := current().Database
+	return &c
+}
+
+// MiningConfig 挖矿相关配置
+type MiningConfig struct {
+	// 创世区块及调整前使用的难度（哈希前导零个数）
+	InitialDifficulty int
+	MinDifficulty     int
+	MaxDifficulty     int
+	// 期望的平均出块时间，每 AdjustmentWindow 个区块根据实际出块时间调整一次难度
+	TargetBlockTime  time.Duration
+	AdjustmentWindow int
+
+	BlockInterval           time.Duration
+	MaxTransactionsPerBlock int
+	// 并行搜索 nonce 的工作协程数
+	Workers int
+	// 通过接口触发挖矿时的默认超时时间
+	Timeout time.Duration
+	// MinerAddress 出块奖励和手续费的收款地址，为空时挖出的区块不包含 coinbase 交易，手续费被销毁
+	MinerAddress string
+}
+
+func GetMiningConfig() *MiningConfig {
+	c := current().Mining
+	return &c
+}
+
+// MaxDecimals 支持的最大小数位数，保证金额能以 DECIMAL(65,0) 保存
+const MaxDecimals = 18
+
+// ChainConfig 链相关配置
+type ChainConfig struct {
+	// ChainID 参与交易签名，防止交易在其他链上重放
+	ChainID uint64
+	// GenesisHash 期望的创世区块哈希，为空时不校验
+	GenesisHash string
+	// GenesisTime 创世区块时间戳，所有节点必须一致才能生成相同的创世区块
+	GenesisTime time.Time
+	// Decimals 金额的小数位数，金额以 10^-Decimals 为最小单位保存
+	Decimals int
+	// BlockReward 第一个减半周期内每个区块的出块奖励，以完整单位表示，可以带小数
+	BlockReward string
+	// HalvingInterval 每隔多少个区块出块奖励减半，0 表示不减半
+	HalvingInterval int
+}
+
+func GetChainConfig() *ChainConfig {
+	c := current().Chain
+	return &c
+}
+
+// P2PConfig 节点间网络配置
+type P2PConfig struct {
+	// AdvertiseURL 本节点对外公布的地址，其他节点通过该地址访问本节点
+	AdvertiseURL string
+	// Peers 启动时连接的种子节点地址
+	Peers        []string
+	MaxPeers     int
+	SyncInterval time.Duration
+	// 单次同步请求最多获取的区块数
+	SyncBatchSize  int
+	RequestTimeout time.Duration
+}
+
+func GetP2PConfig() *P2PConfig {
+	c := current().P2P
+	c.Peers = append([]string(nil), c.Peers...)
+	return &c
+}
+
+// KeyStoreConfig 加密 keystore 配置
+type KeyStoreConfig struct {
+	Dir string
+	// scrypt 参数，参见 go-ethereum keystore.StandardScryptN / LightScryptN
+	ScryptN int
+	ScryptP int
+	// 解锁未指定时长时使用的默认时长及允许的最长时长
+	DefaultUnlockTimeout time.Duration
+	MaxUnlockTimeout     time.Duration
+}
+
+func GetKeyStoreConfig() *KeyStoreConfig {
+	c := current().KeyStore
+	return &c
+}
+
+// WebhookConfig 回调投递配置
+type WebhookConfig struct {
+	// 投递失败后按指数退避重试，第 n 次重试前等待 InitialBackoff * 2^(n-1)，最长 MaxBackoff
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// 检查待投递回调的间隔
+	PollInterval   time.Duration
+	RequestTimeout time.Duration
+}
+
+func GetWebhookConfig() *WebhookConfig {
+	c := current().Webhook
+	return &c
+}
+
+// FaucetConfig 水龙头配置，水龙头从已有余额的钱包发起链上转账
+type FaucetConfig struct {
+	// Address 水龙头钱包地址，为空时不启用水龙头；钱包需在本节点 keystore 中
+	Address    string
+	Passphrase string
+	// Amount 每次发放的金额，Fee 为水龙头转账支付的手续费，均以完整单位表示，可以带小数
+	Amount string
+	Fee    string
+	// 同一地址、同一 IP 两次领取的最短间隔
+	AddressCooldown time.Duration
+	IPCooldown      time.Duration
+	// DailyCap 每个自然日（UTC）最多发放的总金额，以完整单位表示，"0" 表示不限制
+	DailyCap string
+	// PowDifficulty 领取前需完成的工作量证明难度（哈希前导零个数），0 表示不需要
+	PowDifficulty int
+}
+
+func GetFaucetConfig() *FaucetConfig {
+	c := current().Faucet
+	return &c
 }
@@ -0,0 +1,318 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// clearEnv 清除所有配置项对应的环境变量，测试结束后恢复
+func clearEnv(t *testing.T) {
+	t.Helper()
+
+	for _, s := range defaults().settings() {
+		t.Setenv(s.env, "")
+		os.Unsetenv(s.env)
+	}
+	t.Setenv(ConfigFileEnv, "")
+	os.Unsetenv(ConfigFileEnv)
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	const yamlFile = "server:\n  port: 9000\n  cors_origins: [\"https://a.example\", \"https://b.example\"]\nmining:\n  timeout: 2m\n"
+	const tomlFile = "[server]\nport = 9000\ncors_origins = [\"https://a.example\", \"https://b.example\"]\n\n[mining]\ntimeout = \"2m\"\n"
+
+	tests := []struct {
+		name string
+		// file 配置文件名和内容，fileFromEnv 为 true 时通过 CONFIG_FILE 而不是 -config 指定
+		file        [2]string
+		fileFromEnv bool
+		env         map[string]string
+		args        []string
+		wantPort    int
+		wantOrigins []string
+		wantTimeout time.Duration
+	}{
+		{name: "defaults", wantPort: 8080, wantTimeout: time.Minute},
+		{
+			name:        "yaml file overrides defaults",
+			file:        [2]string{"config.yaml", yamlFile},
+			wantPort:    9000,
+			wantOrigins: []string{"https://a.example", "https://b.example"},
+			wantTimeout: 2 * time.Minute,
+		},
+		{
+			name:        "toml file from environment",
+			file:        [2]string{"config.toml", tomlFile},
+			fileFromEnv: true,
+			wantPort:    9000,
+			wantOrigins: []string{"https://a.example", "https://b.example"},
+			wantTimeout: 2 * time.Minute,
+		},
+		{
+			name:        "environment overrides file",
+			file:        [2]string{"config.yml", yamlFile},
+			env:         map[string]string{"PORT": "9001", "CORS_ORIGINS": "https://c.example"},
+			wantPort:    9001,
+			wantOrigins: []string{"https://c.example"},
+			wantTimeout: 2 * time.Minute,
+		},
+		{
+			name:        "flag overrides environment and file",
+			file:        [2]string{"config.yaml", yamlFile},
+			env:         map[string]string{"PORT": "9001", "MINING_TIMEOUT": "3m"},
+			args:        []string{"-server-port", "9002"},
+			wantPort:    9002,
+			wantOrigins: []string{"https://a.example", "https://b.example"},
+			wantTimeout: 3 * time.Minute,
+		},
+		{
+			name:        "flag without file",
+			args:        []string{"-server-port=9003", "-mining-timeout", "30s"},
+			wantPort:    9003,
+			wantTimeout: 30 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			args := tt.args
+			if tt.file[0] != "" {
+				path := writeFile(t, tt.file[0], tt.file[1])
+				if tt.fileFromEnv {
+					t.Setenv(ConfigFileEnv, path)
+				} else {
+					args = append([]string{"-config", path}, args...)
+				}
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			if _, err := Load(args); err != nil {
+				t.Fatal(err)
+			}
+			server, mining := GetServerConfig(), GetMiningConfig()
+			if server.Port != tt.wantPort {
+				t.Errorf("port = %d, want %d", server.Port, tt.wantPort)
+			}
+			if strings.Join(server.CORSOrigins, ",") != strings.Join(tt.wantOrigins, ",") {
+				t.Errorf("cors origins = %v, want %v", server.CORSOrigins, tt.wantOrigins)
+			}
+			if mining.Timeout != tt.wantTimeout {
+				t.Errorf("mining timeout = %s, want %s", mining.Timeout, tt.wantTimeout)
+			}
+		})
+	}
+}
+
+func TestLoadReturnsRemainingArgs(t *testing.T) {
+	clearEnv(t)
+
+	rest, err := Load([]string{"-database-auto-migrate=false", "migrate", "up", "-n", "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(rest, " ") != "migrate up -n 1" {
+		t.Errorf("remaining args = %v", rest)
+	}
+	if GetDBConfig().AutoMigrate {
+		t.Error("auto migrate still enabled")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file [2]string
+		env  map[string]string
+		args []string
+		// want 错误信息中应包含的内容
+		want string
+	}{
+		{name: "unknown file setting", file: [2]string{"config.yaml", "server:\n  listen: 1\n"}, want: "unknown setting server.listen"},
+		{name: "value where section expected", file: [2]string{"config.yaml", "server: 1\n"}, want: "server must be a section"},
+		{name: "unsupported format", file: [2]string{"config.json", "{}"}, want: "unsupported format"},
+		{name: "invalid file value", file: [2]string{"config.toml", "[mining]\ntimeout = \"soon\"\n"}, want: "mining.timeout"},
+		{name: "invalid environment value", env: map[string]string{"PORT": "http"}, want: "environment variable PORT"},
+		{name: "invalid flag value", args: []string{"-mining-workers", "many"}, want: "flag -mining-workers"},
+		{name: "unknown flag", args: []string{"-no-such-flag"}, want: "no-such-flag"},
+		{name: "validation after all sources", env: map[string]string{"PORT": "0"}, want: "server.port must be between 1 and 65535"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			args := tt.args
+			if tt.file[0] != "" {
+				args = append([]string{"-config", writeFile(t, tt.file[0], tt.file[1])}, args...)
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			_, err := Load(args)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		// want 为空表示配置有效，否则为错误信息中应包含的内容
+		want []string
+	}{
+		{name: "defaults", modify: func(*Config) {}},
+		{name: "memory driver ignores mysql settings", modify: func(c *Config) { c.Database.Driver = DriverMemory; c.Database.Host = "" }},
+		{name: "dsn replaces mysql fields", modify: func(c *Config) { c.Database.DSN = "u:p@tcp(db:3306)/x"; c.Database.Host = "" }},
+		{name: "unknown driver", modify: func(c *Config) { c.Database.Driver = "sqlite" }, want: []string{"database.driver"}},
+		{name: "missing mysql host", modify: func(c *Config) { c.Database.Host = "" }, want: []string{"database.host is required"}},
+		{name: "invalid origin", modify: func(c *Config) { c.Server.CORSOrigins = []string{"example.com"} }, want: []string{`invalid origin "example.com"`}},
+		{name: "any origin", modify: func(c *Config) { c.Server.CORSOrigins = []string{"*"} }},
+		{name: "invalid log level", modify: func(c *Config) { c.Server.LogLevel = "trace" }, want: []string{"server.log_level"}},
+		{name: "invalid proxy", modify: func(c *Config) { c.Server.TrustedProxies = []string{"10.0.0.0/33"} }, want: []string{"server.trusted_proxies"}},
+		{
+			name:   "initial difficulty out of range",
+			modify: func(c *Config) { c.Mining.InitialDifficulty = 9 },
+			want:   []string{"mining.initial_difficulty must be between"},
+		},
+		{name: "max difficulty above hash length", modify: func(c *Config) { c.Mining.MaxDifficulty = 65 }, want: []string{"mining.max_difficulty"}},
+		{name: "invalid miner address", modify: func(c *Config) { c.Mining.MinerAddress = "0x123" }, want: []string{"mining.miner_address must be"}},
+		{name: "too many decimals", modify: func(c *Config) { c.Chain.Decimals = 19 }, want: []string{"chain.decimals"}},
+		{name: "block reward precision", modify: func(c *Config) { c.Chain.Decimals = 2; c.Chain.BlockReward = "0.001" }, want: []string{"chain.block_reward"}},
+		{name: "invalid peer", modify: func(c *Config) { c.P2P.Peers = []string{"ftp://peer"} }, want: []string{"p2p.peers"}},
+		{
+			name:   "backoff order",
+			modify: func(c *Config) { c.Webhook.MaxBackoff = time.Second },
+			want:   []string{"webhook.max_backoff must not be less than webhook.initial_backoff"},
+		},
+		{name: "faucet disabled skips faucet settings", modify: func(c *Config) { c.Faucet.Amount = "x" }},
+		{
+			name: "faucet settings",
+			modify: func(c *Config) {
+				c.Faucet.Address = "0x00000000000000000000000000000000000000f1"
+				c.Faucet.Amount = "0"
+				c.Faucet.DailyCap = "-1"
+				c.Faucet.PowDifficulty = 65
+			},
+			want: []string{"faucet.amount must be positive", "faucet.daily_cap", "faucet.pow_difficulty"},
+		},
+		{
+			name: "reports every problem",
+			modify: func(c *Config) {
+				c.Server.Port = 70000
+				c.Mining.Workers = 0
+				c.KeyStore.Dir = ""
+			},
+			want: []string{"server.port", "mining.workers", "keystore.dir"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := defaults()
+			c.complete()
+			tt.modify(c)
+
+			err := c.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate succeeded, want errors %q", tt.want)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate error %q does not mention %q", err, want)
+				}
+			}
+			if n := strings.Count(err.Error(), "\n"); n != len(tt.want) {
+				t.Errorf("Validate reported %d problems, want %d: %v", n, len(tt.want), err)
+			}
+		})
+	}
+}
+
+func TestMaskDSN(t *testing.T) {
+	tests := []struct {
+		dsn  string
+		want string
+	}{
+		{dsn: "", want: ""},
+		{dsn: "root:secret@tcp(db:3306)/chain?parseTime=true", want: "root:******@tcp(db:3306)/chain?parseTime=true"},
+		{dsn: "root:p@ss@tcp(db:3306)/chain", want: "root:******@tcp(db:3306)/chain"},
+		{dsn: "root:secret@tcp(db:3306)/chain?tls=a@b", want: "root:******@tcp(db:3306)/chain?tls=a@b"},
+		{dsn: "root@tcp(db:3306)/chain", want: "root@tcp(db:3306)/chain"},
+	}
+
+	for _, tt := range tests {
+		if got := maskDSN(tt.dsn); got != tt.want {
+			t.Errorf("maskDSN(%q) = %q, want %q", tt.dsn, got, tt.want)
+		}
+	}
+}
+
+func TestPrint(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("DB_PASSWORD", "db-secret")
+	t.Setenv("FAUCET_PASSPHRASE", "faucet-secret")
+	if _, err := Load([]string{"-database-dsn", "root:dsn-secret@tcp(db:3306)/chain", "-server-port", "9100"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := Print(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"db-secret", "faucet-secret", "dsn-secret"} {
+		if strings.Contains(out.String(), secret) {
+			t.Errorf("printed configuration contains %q:\n%s", secret, out.String())
+		}
+	}
+	if !strings.Contains(out.String(), "root:******@tcp(db:3306)/chain") {
+		t.Errorf("DSN not masked as expected:\n%s", out.String())
+	}
+
+	// 输出按配置段组织，每一项都注明对应的环境变量，可以直接作为配置文件加载
+	var doc map[string]map[string]interface{}
+	if err := yaml.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("printed configuration is not YAML: %v", err)
+	}
+	if doc["server"]["port"] != 9100 || doc["database"]["password"] != "******" {
+		t.Errorf("server.port = %v, database.password = %v", doc["server"]["port"], doc["database"]["password"])
+	}
+	if !strings.Contains(out.String(), "# env DB_PASSWORD") {
+		t.Errorf("environment variable comment missing:\n%s", out.String())
+	}
+
+	clearEnv(t)
+	if _, err := Load([]string{"-config", writeFile(t, "printed.yaml", out.String())}); err != nil {
+		t.Fatalf("load printed configuration: %v", err)
+	}
+	if port := GetServerConfig().Port; port != 9100 {
+		t.Errorf("port from printed configuration = %d, want 9100", port)
+	}
+}
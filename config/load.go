@@ -0,0 +1,347 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"hello-go/models"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileEnv 未通过 -config 指定配置文件时，从该环境变量读取配置文件路径
+const ConfigFileEnv = "CONFIG_FILE"
+
+var (
+	mu     sync.Mutex
+	loaded *Config
+)
+
+// Load 依次加载默认值、配置文件、环境变量和命令行参数并校验，成功后作为全局配置
+// args 为程序参数（不含程序名），返回第一个非参数项开始的剩余参数（子命令及其参数）
+func Load(args []string) ([]string, error) {
+	c := defaults()
+	settings := c.settings()
+
+	fs := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	configFile := fs.String("config", "", "configuration file (.yaml, .yml or .toml), also read from "+ConfigFileEnv)
+	flags := make(map[string]*flagValue, len(settings))
+	for _, s := range settings {
+		f := &flagValue{setting: s}
+		flags[s.flagName()] = f
+		fs.Var(f, s.flagName(), fmt.Sprintf("%s (env %s)", s.usage, s.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	path := *configFile
+	if path == "" {
+		path = os.Getenv(ConfigFileEnv)
+	}
+	if path != "" {
+		if err := c.loadFile(path, settings); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.loadEnv(settings); err != nil {
+		return nil, err
+	}
+
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if fv, ok := flags[f.Name]; ok && err == nil {
+			if setErr := fv.setting.value.Set(fv.raw); setErr != nil {
+				err = fmt.Errorf("flag -%s: %v", f.Name, setErr)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.complete()
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	loaded = c
+	mu.Unlock()
+	return fs.Args(), nil
+}
+
+// current 获取全局配置，未调用 Load 时（例如被其他程序引用）仅从配置文件和环境变量加载
+func current() *Config {
+	mu.Lock()
+	c := loaded
+	mu.Unlock()
+	if c != nil {
+		return c
+	}
+
+	if _, err := Load(nil); err != nil {
+		log.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return loaded
+}
+
+// flagValue 记录命令行参数的原始值，在配置文件和环境变量之后再应用，使命令行参数优先
+type flagValue struct {
+	setting *setting
+	raw     string
+}
+
+func (f *flagValue) String() string {
+	if f == nil || f.setting == nil {
+		return ""
+	}
+	return f.setting.value.String()
+}
+
+func (f *flagValue) Set(s string) error {
+	f.raw = s
+	return nil
+}
+
+// IsBoolFlag 布尔配置项可以省略取值，例如 -database-auto-migrate 等同于 -database-auto-migrate=true
+func (f *flagValue) IsBoolFlag() bool {
+	_, ok := f.setting.value.(boolValue)
+	return ok
+}
+
+// loadFile 按扩展名解析 YAML 或 TOML 配置文件，文件按 配置段.配置项 两级组织
+func (c *Config) loadFile(path string, settings []*setting) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %v", err)
+	}
+
+	var doc map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return fmt.Errorf("config file %s: unsupported format, expected .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %v", path, err)
+	}
+
+	byKey := make(map[string]*setting, len(settings))
+	for _, s := range settings {
+		byKey[s.key] = s
+	}
+
+	for section, entries := range doc {
+		values, ok := entries.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("config file %s: %s must be a section", path, section)
+		}
+		for name, raw := range values {
+			key := section + "." + name
+			s, ok := byKey[key]
+			if !ok {
+				return fmt.Errorf("config file %s: unknown setting %s", path, key)
+			}
+			v, err := fileValue(raw)
+			if err != nil {
+				return fmt.Errorf("config file %s: %s: %v", path, key, err)
+			}
+			if err := s.value.Set(v); err != nil {
+				return fmt.Errorf("config file %s: %s: %v", path, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// fileValue 将配置文件中的值转换为与环境变量相同的字符串形式，列表以逗号连接
+func fileValue(raw interface{}) (string, error) {
+	switch v := raw.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case time.Time:
+		return v.Format(time.RFC3339), nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := fileValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ","), nil
+	case map[string]interface{}:
+		return "", errors.New("unexpected nested section")
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// loadEnv 使用已设置的环境变量覆盖配置
+func (c *Config) loadEnv(settings []*setting) error {
+	for _, s := range settings {
+		v, ok := os.LookupEnv(s.env)
+		if !ok {
+			continue
+		}
+		if err := s.value.Set(v); err != nil {
+			return fmt.Errorf("environment variable %s: %v", s.env, err)
+		}
+	}
+	return nil
+}
+
+// complete 填充依赖其他配置项的默认值
+func (c *Config) complete() {
+	if c.Mining.TargetBlockTime == 0 {
+		c.Mining.TargetBlockTime = c.Mining.BlockInterval
+	}
+	if c.P2P.AdvertiseURL == "" {
+		c.P2P.AdvertiseURL = fmt.Sprintf("http://localhost:%d", c.Server.Port)
+	}
+	c.P2P.AdvertiseURL = strings.TrimRight(c.P2P.AdvertiseURL, "/")
+	for i, peer := range c.P2P.Peers {
+		c.P2P.Peers[i] = strings.TrimRight(peer, "/")
+	}
+}
+
+// Validate 校验配置，返回所有无效的配置项
+func (c *Config) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "server.port must be between 1 and 65535")
+	for _, origin := range c.Server.CORSOrigins {
+		check(origin == "*" || validURL(origin), "server.cors_origins: invalid origin %q", origin)
+	}
+	switch c.Server.LogLevel {
+	case LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError:
+	default:
+		check(false, "server.log_level must be debug, info, warn or error")
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		check(validProxy(proxy), "server.trusted_proxies: invalid IP or CIDR %q", proxy)
+	}
+
+	switch c.Database.Driver {
+	case DriverMemory:
+	case DriverMySQL:
+		if c.Database.DSN == "" {
+			check(c.Database.Host != "", "database.host is required for the mysql driver")
+			check(c.Database.Port > 0 && c.Database.Port <= 65535, "database.port must be between 1 and 65535")
+			check(c.Database.User != "", "database.user is required for the mysql driver")
+			check(c.Database.DBName != "", "database.name is required for the mysql driver")
+		}
+	default:
+		check(false, "database.driver must be mysql or memory")
+	}
+	check(c.Database.MaxOpenConns >= 0, "database.max_open_conns must not be negative")
+	check(c.Database.MaxIdleConns >= 0, "database.max_idle_conns must not be negative")
+	check(c.Database.ConnMaxLifetime >= 0, "database.conn_max_lifetime must not be negative")
+
+	// 区块哈希为 64 位十六进制，难度最多为 64 个前导零
+	check(c.Mining.MinDifficulty >= 1, "mining.min_difficulty must be at least 1")
+	check(c.Mining.MaxDifficulty <= 64, "mining.max_difficulty must be at most 64")
+	check(c.Mining.MinDifficulty <= c.Mining.InitialDifficulty && c.Mining.InitialDifficulty <= c.Mining.MaxDifficulty,
+		"mining.initial_difficulty must be between mining.min_difficulty and mining.max_difficulty")
+	check(c.Mining.BlockInterval > 0, "mining.block_interval must be positive")
+	check(c.Mining.TargetBlockTime > 0, "mining.target_block_time must be positive")
+	check(c.Mining.AdjustmentWindow > 0, "mining.adjustment_window must be positive")
+	check(c.Mining.MaxTransactionsPerBlock > 0, "mining.max_transactions must be positive")
+	check(c.Mining.Workers > 0, "mining.workers must be positive")
+	check(c.Mining.Timeout > 0, "mining.timeout must be positive")
+	check(c.Mining.MinerAddress == "" || isAddress(c.Mining.MinerAddress), "mining.miner_address must be a 0x-prefixed 40 hex character address")
+	check(!strings.EqualFold(c.Mining.MinerAddress, models.CoinbaseAddress), "mining.miner_address must not be the coinbase address")
+
+	check(c.Chain.ChainID > 0, "chain.id must be positive")
+	check(c.Chain.GenesisHash == "" || isHash(c.Chain.GenesisHash), "chain.genesis_hash must be 64 hex characters")
+	check(c.Chain.Decimals >= 0 && c.Chain.Decimals <= MaxDecimals, "chain.decimals must be between 0 and %d", MaxDecimals)
+	_, err := models.ParseUnits(c.Chain.BlockReward, c.Chain.Decimals)
+	check(err == nil, "chain.block_reward: %v", err)
+	check(c.Chain.HalvingInterval >= 0, "chain.halving_interval must not be negative")
+
+	check(validURL(c.P2P.AdvertiseURL), "p2p.advertise_url: invalid URL %q", c.P2P.AdvertiseURL)
+	for _, peer := range c.P2P.Peers {
+		check(validURL(peer), "p2p.peers: invalid URL %q", peer)
+	}
+	check(c.P2P.MaxPeers > 0, "p2p.max_peers must be positive")
+	check(c.P2P.SyncInterval > 0, "p2p.sync_interval must be positive")
+
+	check(c.KeyStore.Dir != "", "keystore.dir must not be empty")
+
+	check(c.Webhook.MaxAttempts > 0, "webhook.max_attempts must be positive")
+	check(c.Webhook.InitialBackoff > 0, "webhook.initial_backoff must be positive")
+	check(c.Webhook.MaxBackoff >= c.Webhook.InitialBackoff, "webhook.max_backoff must not be less than webhook.initial_backoff")
+
+	if c.Faucet.Address != "" {
+		check(isAddress(c.Faucet.Address), "faucet.address must be a 0x-prefixed 40 hex character address")
+		check(!strings.EqualFold(c.Faucet.Address, models.CoinbaseAddress), "faucet.address must not be the coinbase address")
+		amount, err := models.ParseUnits(c.Faucet.Amount, c.Chain.Decimals)
+		check(err == nil, "faucet.amount: %v", err)
+		check(err != nil || !amount.IsZero(), "faucet.amount must be positive")
+		_, err = models.ParseUnits(c.Faucet.Fee, c.Chain.Decimals)
+		check(err == nil, "faucet.fee: %v", err)
+		_, err = models.ParseUnits(c.Faucet.DailyCap, c.Chain.Decimals)
+		check(err == nil, "faucet.daily_cap: %v", err)
+		check(c.Faucet.AddressCooldown >= 0, "faucet.address_cooldown must not be negative")
+		check(c.Faucet.IPCooldown >= 0, "faucet.ip_cooldown must not be negative")
+		// 挑战哈希为 64 位十六进制
+		check(c.Faucet.PowDifficulty >= 0 && c.Faucet.PowDifficulty <= 64, "faucet.pow_difficulty must be between 0 and 64")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+func validURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func validProxy(s string) bool {
+	if _, _, err := net.ParseCIDR(s); err == nil {
+		return true
+	}
+	return net.ParseIP(s) != nil
+}
+
+func isHash(s string) bool {
+	return len(s) == 64 && isHex(s)
+}
+
+func isAddress(s string) bool {
+	return len(s) == 42 && strings.HasPrefix(s, "0x") && isHex(s[2:])
+}
+
+func isHex(s string) bool {
+	for _, r := range strings.ToLower(s) {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Print 以 YAML 格式输出当前生效的配置，敏感信息已隐藏，输出可以直接作为配置文件使用
+func Print(w io.Writer) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	sections := make(map[string]*yaml.Node)
+
+	for _, s := range current().settings() {
+		name, key, _ := strings.Cut(s.key, ".")
+		section, ok := sections[name]
+		if !ok {
+			section = &yaml.Node{Kind: yaml.MappingNode}
+			sections[name] = section
+			root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, section)
+		}
+
+		var v interface{} = s.value.get()
+		if s.mask != nil {
+			v = s.mask(s.value.String())
+		}
+		value := &yaml.Node{}
+		if err := value.Encode(v); err != nil {
+			return err
+		}
+		if value.Kind == yaml.SequenceNode {
+			value.Style = yaml.FlowStyle
+		}
+		value.LineComment = "env " + s.env
+		section.Content = append(section.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,277 @@
+package config
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting 一个配置项，可以在配置文件（key）、环境变量（env）和命令行参数中设置
+// 命令行参数名由 key 得到，例如 database.max_open_conns 对应 -database-max-open-conns
+type setting struct {
+	key   string
+	env   string
+	usage string
+	value value
+	// mask 不为空时该项为敏感信息，打印配置时用 mask 处理后的值代替
+	mask func(string) string
+}
+
+// flagName 配置项对应的命令行参数名
+func (s *setting) flagName() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(s.key)
+}
+
+// value 配置项的值，实现 flag.Value，从字符串解析
+type value interface {
+	String() string
+	Set(string) error
+	// get 返回用于输出的值
+	get() interface{}
+}
+
+type stringValue struct{ p *string }
+
+func (v stringValue) String() string     { return *v.p }
+func (v stringValue) Set(s string) error { *v.p = s; return nil }
+func (v stringValue) get() interface{}   { return *v.p }
+
+type intValue struct{ p *int }
+
+func (v intValue) String() string   { return strconv.Itoa(*v.p) }
+func (v intValue) get() interface{} { return *v.p }
+func (v intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("invalid integer %q", s)
+	}
+	*v.p = n
+	return nil
+}
+
+type uint64Value struct{ p *uint64 }
+
+func (v uint64Value) String() string   { return strconv.FormatUint(*v.p, 10) }
+func (v uint64Value) get() interface{} { return *v.p }
+func (v uint64Value) Set(s string) error {
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid unsigned integer %q", s)
+	}
+	*v.p = n
+	return nil
+}
+
+type durationValue struct{ p *time.Duration }
+
+func (v durationValue) String() string   { return v.p.String() }
+func (v durationValue) get() interface{} { return v.p.String() }
+func (v durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q, expected a value such as 10s or 1m30s", s)
+	}
+	*v.p = d
+	return nil
+}
+
+type boolValue struct{ p *bool }
+
+func (v boolValue) String() string   { return strconv.FormatBool(*v.p) }
+func (v boolValue) get() interface{} { return *v.p }
+func (v boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("invalid boolean %q, expected true or false", s)
+	}
+	*v.p = b
+	return nil
+}
+
+// timeValue RFC 3339 格式的时间
+type timeValue struct{ p *time.Time }
+
+func (v timeValue) String() string   { return v.p.Format(time.RFC3339) }
+func (v timeValue) get() interface{} { return v.p.Format(time.RFC3339) }
+func (v timeValue) Set(s string) error {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return fmt.Errorf("invalid time %q, expected RFC 3339 such as 2025-01-01T00:00:00Z", s)
+	}
+	*v.p = t
+	return nil
+}
+
+// listValue 逗号分隔的字符串列表，忽略空项
+type listValue struct{ p *[]string }
+
+func (v listValue) String() string { return strings.Join(*v.p, ",") }
+func (v listValue) get() interface{} {
+	if *v.p == nil {
+		return []string{}
+	}
+	return *v.p
+}
+func (v listValue) Set(s string) error {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*v.p = items
+	return nil
+}
+
+// maskSecret 隐藏敏感信息，未设置时保持为空
+func maskSecret(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "******"
+}
+
+// maskDSN 隐藏连接串中的密码
+// 与 MySQL 驱动解析连接串的方式一致，用户信息以最后一个 / 之前的最后一个 @ 结束，密码本身可以包含 @
+func maskDSN(s string) string {
+	slash := strings.LastIndex(s, "/")
+	if slash < 0 {
+		return s
+	}
+	at := strings.LastIndex(s[:slash], "@")
+	if at < 0 {
+		return s
+	}
+	user, _, ok := strings.Cut(s[:at], ":")
+	if !ok {
+		return s
+	}
+	return user + ":******" + s[at:]
+}
+
+// defaults 默认配置
+func defaults() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:     8080,
+			LogLevel: LogLevelInfo,
+		},
+		Database: DatabaseConfig{
+			Driver:          DriverMySQL,
+			Host:            "localhost",
+			Port:            3306,
+			User:            "root",
+			DBName:          "blockchain_db",
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 5 * time.Minute,
+			AutoMigrate:     true,
+		},
+		Mining: MiningConfig{
+			InitialDifficulty:       4,
+			MinDifficulty:           1,
+			MaxDifficulty:           8,
+			AdjustmentWindow:        10,
+			BlockInterval:           10 * time.Second,
+			MaxTransactionsPerBlock: 100,
+			Workers:                 runtime.NumCPU(),
+			Timeout:                 time.Minute,
+		},
+		Chain: ChainConfig{
+			ChainID:         1337,
+			GenesisTime:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			Decimals:        18,
+			BlockReward:     "50",
+			HalvingInterval: 210000,
+		},
+		P2P: P2PConfig{
+			MaxPeers:       25,
+			SyncInterval:   15 * time.Second,
+			SyncBatchSize:  100,
+			RequestTimeout: 5 * time.Second,
+		},
+		KeyStore: KeyStoreConfig{
+			Dir:                  "./keystore",
+			ScryptN:              1 << 18,
+			ScryptP:              1,
+			DefaultUnlockTimeout: 5 * time.Minute,
+			MaxUnlockTimeout:     24 * time.Hour,
+		},
+		Webhook: WebhookConfig{
+			MaxAttempts:    8,
+			InitialBackoff: 5 * time.Second,
+			MaxBackoff:     time.Hour,
+			PollInterval:   time.Second,
+			RequestTimeout: 10 * time.Second,
+		},
+		Faucet: FaucetConfig{
+			Amount:          "10",
+			Fee:             "0",
+			AddressCooldown: 24 * time.Hour,
+			IPCooldown:      time.Hour,
+			DailyCap:        "1000",
+		},
+	}
+}
+
+// settings 可配置项列表，顺序即打印配置时的顺序
+func (c *Config) settings() []*setting {
+	return []*setting{
+		{key: "server.port", env: "PORT", usage: "HTTP listen port", value: intValue{&c.Server.Port}},
+		{key: "server.cors_origins", env: "CORS_ORIGINS", usage: "comma-separated origins allowed for CORS and WebSocket connections, empty allows same-origin only, * allows any", value: listValue{&c.Server.CORSOrigins}},
+		{key: "server.log_level", env: "LOG_LEVEL", usage: "log level: debug, info, warn or error", value: stringValue{&c.Server.LogLevel}},
+		{key: "server.trusted_proxies", env: "TRUSTED_PROXIES", usage: "comma-separated reverse proxy IPs or CIDRs whose X-Forwarded-For header is trusted", value: listValue{&c.Server.TrustedProxies}},
+
+		{key: "database.driver", env: "DB_DRIVER", usage: "database driver: mysql or memory", value: stringValue{&c.Database.Driver}},
+		{key: "database.dsn", env: "DB_DSN", usage: "MySQL DSN, overrides host, port, user, password and name", value: stringValue{&c.Database.DSN}, mask: maskDSN},
+		{key: "database.host", env: "DB_HOST", usage: "MySQL host", value: stringValue{&c.Database.Host}},
+		{key: "database.port", env: "DB_PORT", usage: "MySQL port", value: intValue{&c.Database.Port}},
+		{key: "database.user", env: "DB_USER", usage: "MySQL user", value: stringValue{&c.Database.User}},
+		{key: "database.password", env: "DB_PASSWORD", usage: "MySQL password", value: stringValue{&c.Database.Password}, mask: maskSecret},
+		{key: "database.name", env: "DB_NAME", usage: "MySQL database name", value: stringValue{&c.Database.DBName}},
+		{key: "database.max_open_conns", env: "DB_MAX_OPEN_CONNS", usage: "maximum open connections, 0 means unlimited", value: intValue{&c.Database.MaxOpenConns}},
+		{key: "database.max_idle_conns", env: "DB_MAX_IDLE_CONNS", usage: "maximum idle connections", value: intValue{&c.Database.MaxIdleConns}},
+		{key: "database.conn_max_lifetime", env: "DB_CONN_MAX_LIFETIME", usage: "maximum connection lifetime, 0 means unlimited", value: durationValue{&c.Database.ConnMaxLifetime}},
+		{key: "database.auto_migrate", env: "DB_AUTO_MIGRATE", usage: "apply pending schema migrations on startup", value: boolValue{&c.Database.AutoMigrate}},
+
+		{key: "mining.initial_difficulty", env: "MINING_INITIAL_DIFFICULTY", usage: "difficulty of the genesis block", value: intValue{&c.Mining.InitialDifficulty}},
+		{key: "mining.min_difficulty", env: "MINING_MIN_DIFFICULTY", usage: "lower bound for difficulty adjustment", value: intValue{&c.Mining.MinDifficulty}},
+		{key: "mining.max_difficulty", env: "MINING_MAX_DIFFICULTY", usage: "upper bound for difficulty adjustment", value: intValue{&c.Mining.MaxDifficulty}},
+		{key: "mining.block_interval", env: "MINING_INTERVAL", usage: "interval of the background miner", value: durationValue{&c.Mining.BlockInterval}},
+		{key: "mining.target_block_time", env: "TARGET_BLOCK_TIME", usage: "expected block time for difficulty adjustment, defaults to the block interval", value: durationValue{&c.Mining.TargetBlockTime}},
+		{key: "mining.adjustment_window", env: "DIFFICULTY_ADJUSTMENT_WINDOW", usage: "number of blocks between difficulty adjustments", value: intValue{&c.Mining.AdjustmentWindow}},
+		{key: "mining.max_transactions", env: "MINING_MAX_TRANSACTIONS", usage: "maximum transactions per block", value: intValue{&c.Mining.MaxTransactionsPerBlock}},
+		{key: "mining.workers", env: "MINING_WORKERS", usage: "parallel proof-of-work workers", value: intValue{&c.Mining.Workers}},
+		{key: "mining.timeout", env: "MINING_TIMEOUT", usage: "default timeout of POST /api/v1/mine", value: durationValue{&c.Mining.Timeout}},
+		{key: "mining.miner_address", env: "MINER_ADDRESS", usage: "address receiving block rewards and fees, empty disables coinbase transactions", value: stringValue{&c.Mining.MinerAddress}},
+
+		{key: "chain.id", env: "CHAIN_ID", usage: "chain ID used in transaction signatures", value: uint64Value{&c.Chain.ChainID}},
+		{key: "chain.genesis_hash", env: "GENESIS_HASH", usage: "expected genesis block hash, empty disables the check", value: stringValue{&c.Chain.GenesisHash}},
+		{key: "chain.genesis_time", env: "GENESIS_TIME", usage: "genesis block timestamp (RFC 3339)", value: timeValue{&c.Chain.GenesisTime}},
+		{key: "chain.decimals", env: "TOKEN_DECIMALS", usage: "number of decimals of amounts", value: intValue{&c.Chain.Decimals}},
+		{key: "chain.block_reward", env: "BLOCK_REWARD", usage: "block reward in whole units before the first halving", value: stringValue{&c.Chain.BlockReward}},
+		{key: "chain.halving_interval", env: "HALVING_INTERVAL", usage: "number of blocks between block reward halvings, 0 disables halving", value: intValue{&c.Chain.HalvingInterval}},
+
+		{key: "p2p.advertise_url", env: "P2P_ADVERTISE_URL", usage: "URL other nodes use to reach this node, defaults to http://localhost:<port>", value: stringValue{&c.P2P.AdvertiseURL}},
+		{key: "p2p.peers", env: "P2P_PEERS", usage: "comma-separated seed node URLs", value: listValue{&c.P2P.Peers}},
+		{key: "p2p.max_peers", env: "P2P_MAX_PEERS", usage: "maximum number of known peers", value: intValue{&c.P2P.MaxPeers}},
+		{key: "p2p.sync_interval", env: "P2P_SYNC_INTERVAL", usage: "interval of peer discovery and chain sync", value: durationValue{&c.P2P.SyncInterval}},
+
+		{key: "keystore.dir", env: "KEYSTORE_DIR", usage: "encrypted keystore directory", value: stringValue{&c.KeyStore.Dir}},
+
+		{key: "webhook.max_attempts", env: "WEBHOOK_MAX_ATTEMPTS", usage: "delivery attempts before a webhook delivery fails", value: intValue{&c.Webhook.MaxAttempts}},
+		{key: "webhook.initial_backoff", env: "WEBHOOK_INITIAL_BACKOFF", usage: "wait before the first webhook retry", value: durationValue{&c.Webhook.InitialBackoff}},
+		{key: "webhook.max_backoff", env: "WEBHOOK_MAX_BACKOFF", usage: "maximum wait between webhook retries", value: durationValue{&c.Webhook.MaxBackoff}},
+
+		{key: "faucet.address", env: "FAUCET_ADDRESS", usage: "funded wallet paying faucet requests, empty disables the faucet", value: stringValue{&c.Faucet.Address}},
+		{key: "faucet.passphrase", env: "FAUCET_PASSPHRASE", usage: "passphrase of the faucet wallet", value: stringValue{&c.Faucet.Passphrase}, mask: maskSecret},
+		{key: "faucet.amount", env: "FAUCET_AMOUNT", usage: "amount in whole units paid per faucet request", value: stringValue{&c.Faucet.Amount}},
+		{key: "faucet.fee", env: "FAUCET_FEE", usage: "fee in whole units paid by faucet transactions", value: stringValue{&c.Faucet.Fee}},
+		{key: "faucet.address_cooldown", env: "FAUCET_ADDRESS_COOLDOWN", usage: "minimum wait between faucet payments to the same address", value: durationValue{&c.Faucet.AddressCooldown}},
+		{key: "faucet.ip_cooldown", env: "FAUCET_IP_COOLDOWN", usage: "minimum wait between faucet requests from the same IP", value: durationValue{&c.Faucet.IPCooldown}},
+		{key: "faucet.daily_cap", env: "FAUCET_DAILY_CAP", usage: "total amount in whole units the faucet pays per UTC day, 0 means unlimited", value: stringValue{&c.Faucet.DailyCap}},
+		{key: "faucet.pow_difficulty", env: "FAUCET_POW_DIFFICULTY", usage: "leading zero hex digits required in faucet proof-of-work, 0 disables the challenge", value: intValue{&c.Faucet.PowDifficulty}},
+	}
+}
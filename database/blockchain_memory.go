@@ -0,0 +1,619 @@
+package database
+
+import (
+	"hello-go/apperr"
+	"hello-go/models"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BlockchainMemory 基于内存的区块链数据访问层，供测试和本地开发使用
+// 语义与 BlockchainMySQL 保持一致，查询不到数据时返回 NotFound 错误，可以用 errors.Is(err, sql.ErrNoRows) 判断
+type BlockchainMemory struct {
+	mu sync.RWMutex
+
+	blocks       map[int]*models.Block
+	transactions []*models.Transaction
+	// txHashes 已上链交易的哈希，保证哈希唯一
+	txHashes map[string]bool
+	// wallets 以 walletKey 规范化后的地址为键
+	wallets    map[string]*models.Wallet
+	sideBlocks map[string]*sideBlock
+
+	webhooks   map[int64]*models.Webhook
+	deliveries []*models.WebhookDelivery
+
+	nextBlockID    int64
+	nextTxID       int64
+	nextWebhookID  int64
+	nextDeliveryID int64
+}
+
+// walletKey 钱包在 wallets 中的键，地址不区分大小写，与 MySQL 默认排序规则的比较方式一致
+func walletKey(address string) string {
+	return strings.ToLower(address)
+}
+
+// blockAddresses 区块交易涉及的地址，按小写形式去重并排序，不包括 coinbase 地址
+// 钱包由各节点各自创建，其他节点打包的交易可能涉及本地没有的地址，提交区块时需要先创建
+func blockAddresses(txs []*models.Transaction) []string {
+	seen := make(map[string]bool)
+	var addresses []string
+	for _, tx := range txs {
+		for _, address := range []string{tx.FromAddr, tx.ToAddr} {
+			key := walletKey(address)
+			if seen[key] || key == walletKey(models.CoinbaseAddress) {
+				continue
+			}
+			seen[key] = true
+			addresses = append(addresses, address)
+		}
+	}
+	sort.Slice(addresses, func(i, j int) bool { return walletKey(addresses[i]) < walletKey(addresses[j]) })
+	return addresses
+}
+
+// sideBlock 侧链区块及其交易
+type sideBlock struct {
+	block *models.Block
+	txs   []*models.Transaction
+}
+
+func NewBlockchainMemory() *BlockchainMemory {
+	return &BlockchainMemory{
+		blocks:         make(map[int]*models.Block),
+		txHashes:       make(map[string]bool),
+		wallets:        make(map[string]*models.Wallet),
+		sideBlocks:     make(map[string]*sideBlock),
+		webhooks:       make(map[int64]*models.Webhook),
+		nextBlockID:    1,
+		nextTxID:       1,
+		nextWebhookID:  1,
+		nextDeliveryID: 1,
+	}
+}
+
+// 保存区块
+func (m *BlockchainMemory) SaveBlock(block *models.Block) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.blocks[block.Index]; ok {
+		return apperr.New(apperr.Conflict, "", "duplicate block index %d", block.Index)
+	}
+
+	block.ID = m.nextBlockID
+	m.nextBlockID++
+
+	stored := *block
+	m.blocks[block.Index] = &stored
+	return nil
+}
+
+// 保存区块、创建交易涉及的本地不存在的钱包并执行区块中的全部交易，任一交易校验失败则不做任何修改
+func (m *BlockchainMemory) CommitBlock(block *models.Block, txs []*models.Transaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.blocks[block.Index]; ok {
+		return apperr.New(apperr.Conflict, "", "duplicate block index %d", block.Index)
+	}
+
+	// 先在钱包副本上依次执行全部交易，全部通过后再写入，新建的钱包只存在于副本中
+	working := make(map[string]*models.Wallet)
+	for _, address := range blockAddresses(txs) {
+		if _, ok := m.wallets[walletKey(address)]; !ok {
+			working[walletKey(address)] = &models.Wallet{Address: address}
+		}
+	}
+	hashes := make(map[string]bool)
+	for _, tx := range txs {
+		if tx.Hash != "" {
+			if m.txHashes[tx.Hash] || hashes[tx.Hash] {
+				return duplicateTransaction(tx.Hash)
+			}
+			hashes[tx.Hash] = true
+		}
+		if err := m.applyTransfer(tx, working); err != nil {
+			return err
+		}
+	}
+
+	block.ID = m.nextBlockID
+	m.nextBlockID++
+	stored := *block
+	m.blocks[block.Index] = &stored
+
+	for address, wallet := range working {
+		m.wallets[address] = wallet
+	}
+	for _, tx := range txs {
+		tx.BlockID = block.ID
+		m.insertTransaction(tx)
+	}
+	return nil
+}
+
+// 撤销链头区块：按相反顺序回滚其中交易的余额变动，删除交易记录和区块，返回被撤销的交易
+func (m *BlockchainMemory) RollbackBlock(block *models.Block) ([]*models.Transaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.blocks[block.Index]
+	if !ok || stored.ID != block.ID {
+		return nil, notFound(apperr.CodeBlockNotFound, "block %d not found", block.Index)
+	}
+
+	var txs []*models.Transaction
+	kept := m.transactions[:0]
+	for _, tx := range m.transactions {
+		if tx.BlockID == block.ID {
+			txs = append(txs, tx)
+			delete(m.txHashes, tx.Hash)
+			continue
+		}
+		kept = append(kept, tx)
+	}
+	for i := len(kept); i < len(m.transactions); i++ {
+		m.transactions[i] = nil
+	}
+	m.transactions = kept
+
+	for i := len(txs) - 1; i >= 0; i-- {
+		if wallet, ok := m.wallets[walletKey(txs[i].ToAddr)]; ok {
+			wallet.Balance, _ = wallet.Balance.Sub(txs[i].Amount)
+		}
+		if txs[i].IsCoinbase() {
+			continue
+		}
+		if wallet, ok := m.wallets[walletKey(txs[i].FromAddr)]; ok {
+			cost, _ := txs[i].Amount.Add(txs[i].Fee)
+			wallet.Balance, _ = wallet.Balance.Add(cost)
+			wallet.Nonce--
+		}
+	}
+
+	delete(m.blocks, block.Index)
+	return txs, nil
+}
+
+// 保存侧链区块，不影响钱包余额
+func (m *BlockchainMemory) SaveSideBlock(block *models.Block, txs []*models.Transaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.sideBlocks[block.Hash]; ok {
+		return nil
+	}
+
+	stored := &sideBlock{block: copyBlock(block)}
+	for _, tx := range txs {
+		t := *tx
+		stored.txs = append(stored.txs, &t)
+	}
+	m.sideBlocks[block.Hash] = stored
+	return nil
+}
+
+// 根据哈希获取侧链区块及其交易
+func (m *BlockchainMemory) GetSideBlock(hash string) (*models.Block, []*models.Transaction, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stored, ok := m.sideBlocks[hash]
+	if !ok {
+		return nil, nil, notFound(apperr.CodeBlockNotFound, "side block %s not found", hash)
+	}
+
+	txs := make([]*models.Transaction, 0, len(stored.txs))
+	for _, tx := range stored.txs {
+		t := *tx
+		txs = append(txs, &t)
+	}
+	return copyBlock(stored.block), txs, nil
+}
+
+// 删除侧链区块
+func (m *BlockchainMemory) DeleteSideBlock(hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sideBlocks, hash)
+	return nil
+}
+
+func copyBlock(block *models.Block) *models.Block {
+	b := *block
+	return &b
+}
+
+// 根据ID获取区块
+func (m *BlockchainMemory) GetBlockByID(id int64) (*models.Block, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, block := range m.blocks {
+		if block.ID == id {
+			result := *block
+			return &result, nil
+		}
+	}
+	return nil, notFound(apperr.CodeBlockNotFound, "block with id %d not found", id)
+}
+
+// 根据哈希获取主链区块
+func (m *BlockchainMemory) GetBlockByHash(hash string) (*models.Block, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, block := range m.blocks {
+		if block.Hash == hash {
+			return copyBlock(block), nil
+		}
+	}
+	return nil, notFound(apperr.CodeBlockNotFound, "block %s not found", hash)
+}
+
+// 根据索引获取区块
+func (m *BlockchainMemory) GetBlockByIndex(index int) (*models.Block, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	block, ok := m.blocks[index]
+	if !ok {
+		return nil, notFound(apperr.CodeBlockNotFound, "block %d not found", index)
+	}
+
+	result := *block
+	return &result, nil
+}
+
+// 获取最新区块
+func (m *BlockchainMemory) GetLatestBlock() (*models.Block, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var latest *models.Block
+	for _, block := range m.blocks {
+		if latest == nil || block.Index > latest.Index {
+			latest = block
+		}
+	}
+	if latest == nil {
+		return nil, notFound(apperr.CodeBlockNotFound, "chain has no blocks")
+	}
+
+	result := *latest
+	return &result, nil
+}
+
+// 获取所有区块
+func (m *BlockchainMemory) GetAllBlocks() ([]*models.Block, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	blocks := make([]*models.Block, 0, len(m.blocks))
+	for _, block := range m.blocks {
+		b := *block
+		blocks = append(blocks, &b)
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].Index < blocks[j].Index
+	})
+
+	return blocks, nil
+}
+
+// 按高度升序获取高度在 [from, to] 内的主链区块
+func (m *BlockchainMemory) GetBlocksByRange(from, to int) ([]*models.Block, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var blocks []*models.Block
+	for index := from; index <= to; index++ {
+		if block, ok := m.blocks[index]; ok {
+			b := *block
+			blocks = append(blocks, &b)
+		}
+	}
+	return blocks, nil
+}
+
+// 获取高度在 [from, to] 内的主链区块中的全部交易，同一区块内按打包顺序返回
+func (m *BlockchainMemory) GetTransactionsByBlockRange(from, to int) ([]*models.Transaction, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	blockIDs := make(map[int64]bool)
+	for index := from; index <= to; index++ {
+		if block, ok := m.blocks[index]; ok {
+			blockIDs[block.ID] = true
+		}
+	}
+
+	var transactions []*models.Transaction
+	for _, tx := range m.transactions {
+		if blockIDs[tx.BlockID] {
+			t := *tx
+			transactions = append(transactions, &t)
+		}
+	}
+	return transactions, nil
+}
+
+// 保存交易
+func (m *BlockchainMemory) SaveTransaction(tx *models.Transaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if tx.Hash != "" && m.txHashes[tx.Hash] {
+		return duplicateTransaction(tx.Hash)
+	}
+	m.insertTransaction(tx)
+	return nil
+}
+
+// insertTransaction 分配自增ID并保存交易副本，调用方需持有写锁
+func (m *BlockchainMemory) insertTransaction(tx *models.Transaction) {
+	tx.ID = m.nextTxID
+	m.nextTxID++
+
+	stored := *tx
+	m.transactions = append(m.transactions, &stored)
+	if tx.Hash != "" {
+		m.txHashes[tx.Hash] = true
+	}
+}
+
+// 根据ID获取交易
+func (m *BlockchainMemory) GetTransactionByID(id int64) (*models.Transaction, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, tx := range m.transactions {
+		if tx.ID == id {
+			result := *tx
+			return &result, nil
+		}
+	}
+	return nil, notFound(apperr.CodeTransactionNotFound, "transaction %d not found", id)
+}
+
+// 根据交易哈希获取已上链交易
+func (m *BlockchainMemory) GetTransactionByHash(hash string) (*models.Transaction, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	hash = strings.ToLower(hash)
+	if m.txHashes[hash] {
+		for _, tx := range m.transactions {
+			if tx.Hash == hash {
+				result := *tx
+				return &result, nil
+			}
+		}
+	}
+	return nil, notFound(apperr.CodeTransactionNotFound, "transaction %s not found", hash)
+}
+
+// 按ID升序获取ID大于 afterID 且尚未保存哈希的交易
+func (m *BlockchainMemory) GetTransactionsWithoutHash(afterID int64, limit int) ([]*models.Transaction, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var transactions []*models.Transaction
+	for _, tx := range m.transactions {
+		if len(transactions) == limit {
+			break
+		}
+		if tx.Hash == "" && tx.ID > afterID {
+			t := *tx
+			transactions = append(transactions, &t)
+		}
+	}
+	return transactions, nil
+}
+
+// 保存已有交易的哈希
+func (m *BlockchainMemory) SetTransactionHash(id int64, hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.txHashes[hash] {
+		return duplicateTransaction(hash)
+	}
+	for _, tx := range m.transactions {
+		if tx.ID == id {
+			delete(m.txHashes, tx.Hash)
+			tx.Hash = hash
+			m.txHashes[hash] = true
+			return nil
+		}
+	}
+	return notFound(apperr.CodeTransactionNotFound, "transaction %d not found", id)
+}
+
+// 获取区块的所有交易
+func (m *BlockchainMemory) GetTransactionsByBlockID(blockID int64) ([]*models.Transaction, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var transactions []*models.Transaction
+	for _, tx := range m.transactions {
+		if tx.BlockID == blockID {
+			t := *tx
+			transactions = append(transactions, &t)
+		}
+	}
+
+	return transactions, nil
+}
+
+// 获取地址转出和转入的交易，最新的在前
+func (m *BlockchainMemory) GetTransactionsByAddress(address string) ([]*models.Transaction, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var transactions []*models.Transaction
+	for _, tx := range m.newestTransactions() {
+		if strings.EqualFold(tx.FromAddr, address) || strings.EqualFold(tx.ToAddr, address) {
+			t := *tx
+			transactions = append(transactions, &t)
+		}
+	}
+	return transactions, nil
+}
+
+// 分页获取交易，最新的在前
+func (m *BlockchainMemory) GetTransactions(limit, offset int) ([]*models.Transaction, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var transactions []*models.Transaction
+	sorted := m.newestTransactions()
+	for i := offset; i < len(sorted) && i < offset+limit; i++ {
+		t := *sorted[i]
+		transactions = append(transactions, &t)
+	}
+	return transactions, nil
+}
+
+// 统计交易总数
+func (m *BlockchainMemory) CountTransactions() (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.transactions), nil
+}
+
+// newestTransactions 按时间倒序排列的交易，与 MySQL 的 ORDER BY timestamp DESC, id DESC 一致，调用方需持有读锁
+func (m *BlockchainMemory) newestTransactions() []*models.Transaction {
+	sorted := make([]*models.Transaction, len(m.transactions))
+	copy(sorted, m.transactions)
+	sort.Slice(sorted, func(i, j int) bool {
+		if !sorted[i].Timestamp.Equal(sorted[j].Timestamp) {
+			return sorted[i].Timestamp.After(sorted[j].Timestamp)
+		}
+		return sorted[i].ID > sorted[j].ID
+	})
+	return sorted
+}
+
+// 查询钱包的下一个交易序号
+func (m *BlockchainMemory) GetNonce(address string) (uint64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	wallet, ok := m.wallets[walletKey(address)]
+	if !ok {
+		return 0, notFound(apperr.CodeWalletNotFound, "wallet %s not found", address)
+	}
+	return wallet.Nonce, nil
+}
+
+func (m *BlockchainMemory) SaveWallet(wallet *models.Wallet) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := walletKey(wallet.Address)
+	if _, ok := m.wallets[key]; ok {
+		return apperr.New(apperr.Conflict, "", "duplicate wallet address %s", wallet.Address)
+	}
+
+	stored := *wallet
+	m.wallets[key] = &stored
+	return nil
+}
+
+// 转账：执行交易的余额变动并记录交易
+func (m *BlockchainMemory) Transfer(tx *models.Transaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	working := make(map[string]*models.Wallet)
+	if err := m.applyTransfer(tx, working); err != nil {
+		return err
+	}
+	for address, wallet := range working {
+		*m.wallets[address] = *wallet
+	}
+
+	// 记录交易
+	m.insertTransaction(tx)
+	return nil
+}
+
+// applyTransfer 检查双方钱包、交易序号和余额，在钱包副本上执行转账，调用方需持有锁
+// working 中记录了同一批次中已变动但尚未写入的钱包，由调用方在全部交易通过后写回
+func (m *BlockchainMemory) applyTransfer(tx *models.Transaction, working map[string]*models.Wallet) error {
+	walletOf := func(address string) (*models.Wallet, bool) {
+		address = walletKey(address)
+		if wallet, ok := working[address]; ok {
+			return wallet, true
+		}
+		stored, ok := m.wallets[address]
+		if !ok {
+			return nil, false
+		}
+		wallet := *stored
+		working[address] = &wallet
+		return &wallet, true
+	}
+
+	// coinbase 交易只增加矿工余额
+	if tx.IsCoinbase() {
+		toWallet, ok := walletOf(tx.ToAddr)
+		if !ok {
+			return recipientNotFound(tx.ToAddr)
+		}
+		toWallet.Balance, _ = toWallet.Balance.Add(tx.Amount)
+		return nil
+	}
+
+	fromWallet, ok := walletOf(tx.FromAddr)
+	if !ok {
+		return notFound(apperr.CodeWalletNotFound, "wallet %s not found", tx.FromAddr)
+	}
+	toWallet, ok := walletOf(tx.ToAddr)
+	if !ok {
+		return recipientNotFound(tx.ToAddr)
+	}
+	if tx.Nonce != fromWallet.Nonce {
+		return invalidNonce(tx.FromAddr, fromWallet.Nonce, tx.Nonce)
+	}
+	cost, ok := tx.Amount.Add(tx.Fee)
+	if !ok || fromWallet.Balance.Cmp(cost) < 0 {
+		return insufficientFunds()
+	}
+
+	// 转出钱包扣减金额和手续费、序号加一，收款钱包增加金额，手续费由 coinbase 交易发放给矿工
+	fromWallet.Balance, _ = fromWallet.Balance.Sub(cost)
+	fromWallet.Nonce++
+	toWallet.Balance, _ = toWallet.Balance.Add(tx.Amount)
+	return nil
+}
+
+// 统计全部钱包的余额之和
+func (m *BlockchainMemory) GetTotalSupply() (models.Amount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var total models.Amount
+	for _, wallet := range m.wallets {
+		total, _ = total.Add(wallet.Balance)
+	}
+	return total, nil
+}
+
+// 查询钱包余额
+func (m *BlockchainMemory) GetBalance(address string) (models.Amount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	wallet, ok := m.wallets[walletKey(address)]
+	if !ok {
+		return models.Amount{}, notFound(apperr.CodeWalletNotFound, "wallet %s not found", address)
+	}
+	return wallet.Balance, nil
+}
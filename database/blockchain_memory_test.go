@@ -0,0 +1,209 @@
+package database
+
+import (
+	"fmt"
+	"hello-go/apperr"
+	"hello-go/models"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestMemoryConcurrentTransfersConserveSupply 多个协程同时进行方向相反的转账和区块提交，
+// 无论哪些交易因序号冲突或余额不足失败，余额总和都保持不变
+func TestMemoryConcurrentTransfersConserveSupply(t *testing.T) {
+	const (
+		walletCount = 4
+		workers     = 16
+		rounds      = 200
+		initial     = 1000
+	)
+
+	m := NewBlockchainMemory()
+	addresses := make([]string, walletCount)
+	for i := range addresses {
+		addresses[i] = fmt.Sprintf("0x%040x", i+1)
+		if err := m.SaveWallet(&models.Wallet{Address: addresses[i], Balance: models.NewAmount(initial)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := models.NewAmount(initial * walletCount)
+
+	// newTx 按转出钱包当前的序号构造交易，并发时序号可能已经被其他协程使用
+	newTx := func(r *rand.Rand, from, to string) *models.Transaction {
+		nonce, err := m.GetNonce(from)
+		if err != nil {
+			t.Error(err)
+		}
+		return &models.Transaction{
+			FromAddr:  from,
+			ToAddr:    to,
+			Amount:    models.NewAmount(uint64(r.Intn(300) + 1)),
+			Nonce:     nonce,
+			Timestamp: time.Now(),
+		}
+	}
+	// expected 并发下允许出现的失败：序号已被使用或余额不足
+	expected := func(err error) bool {
+		if err == nil {
+			return true
+		}
+		switch apperr.CodeOf(err) {
+		case apperr.CodeNonceTooLow, apperr.CodeNonceTooHigh, apperr.CodeInsufficientFunds:
+			return true
+		}
+		return false
+	}
+
+	var nextIndex int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(int64(w)))
+			a := addresses[w%walletCount]
+			b := addresses[(w+1)%walletCount]
+			// 偶数协程 a -> b，奇数协程 b -> a
+			if w%2 == 1 {
+				a, b = b, a
+			}
+
+			for i := 0; i < rounds; i++ {
+				if i%4 == 0 {
+					// 一个区块中包含同一对钱包之间方向相反的两笔交易
+					block := &models.Block{Index: int(atomic.AddInt64(&nextIndex, 1)), Timestamp: time.Now()}
+					txs := []*models.Transaction{newTx(r, a, b), newTx(r, b, a)}
+					if err := m.CommitBlock(block, txs); !expected(err) {
+						t.Errorf("commit block: %v", err)
+					}
+					continue
+				}
+				if err := m.Transfer(newTx(r, a, b)); !expected(err) {
+					t.Errorf("transfer: %v", err)
+				}
+
+				if total, err := m.GetTotalSupply(); err != nil || total.Cmp(want) != 0 {
+					t.Errorf("total supply during transfers = %s (%v), want %s", total, err, want)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	var sum models.Amount
+	for _, address := range addresses {
+		balance, err := m.GetBalance(address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum, _ = sum.Add(balance)
+
+		// 序号等于已记录的转出交易数
+		nonce, err := m.GetNonce(address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		txs, err := m.GetTransactionsByAddress(address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sent uint64
+		for _, tx := range txs {
+			if tx.FromAddr == address {
+				sent++
+			}
+		}
+		if nonce != sent {
+			t.Errorf("nonce of %s = %d, want %d outgoing transactions", address, nonce, sent)
+		}
+	}
+	if sum.Cmp(want) != 0 {
+		t.Errorf("sum of balances = %s, want %s", sum, want)
+	}
+	total, err := m.GetTotalSupply()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total.Cmp(want) != 0 {
+		t.Errorf("total supply = %s, want %s", total, want)
+	}
+
+	count, _ := m.CountTransactions()
+	if count == 0 {
+		t.Error("no transfer succeeded")
+	}
+}
+
+// TestMemoryWalletAddressIsCaseInsensitive 与 MySQL 默认排序规则一致，地址大小写不同时指向同一个钱包
+func TestMemoryWalletAddressIsCaseInsensitive(t *testing.T) {
+	m := NewBlockchainMemory()
+	address := "0xAbCdEf0000000000000000000000000000000001"
+	if err := m.SaveWallet(&models.Wallet{Address: address, Balance: models.NewAmount(5)}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, a := range []string{address, "0xabcdef0000000000000000000000000000000001", "0xABCDEF0000000000000000000000000000000001"} {
+		balance, err := m.GetBalance(a)
+		if err != nil || balance.Cmp(models.NewAmount(5)) != 0 {
+			t.Errorf("GetBalance(%s) = %s, %v", a, balance, err)
+		}
+	}
+	if err := m.SaveWallet(&models.Wallet{Address: "0xabcdef0000000000000000000000000000000001"}); apperr.KindOf(err) != apperr.Conflict {
+		t.Errorf("saving the same address in lower case: got %v, want conflict", err)
+	}
+}
+
+// TestMemoryCommitBlockCreatesWallets 区块中涉及本地不存在的地址时，提交区块时创建钱包，
+// 区块被拒绝时不留下任何钱包，coinbase 地址不创建钱包
+func TestMemoryCommitBlockCreatesWallets(t *testing.T) {
+	const (
+		sender = "0x0000000000000000000000000000000000000001"
+		miner  = "0x00000000000000000000000000000000000000a1"
+		remote = "0x00000000000000000000000000000000000000b1"
+	)
+	tests := []struct {
+		name        string
+		amount      uint64
+		wantErr     bool
+		wantWallets []string
+	}{
+		{name: "valid block", amount: 10, wantWallets: []string{miner, remote}},
+		{name: "rejected block", amount: 101, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewBlockchainMemory()
+			if err := m.SaveWallet(&models.Wallet{Address: sender, Balance: models.NewAmount(100)}); err != nil {
+				t.Fatal(err)
+			}
+			txs := []*models.Transaction{
+				{FromAddr: models.CoinbaseAddress, ToAddr: miner, Amount: models.NewAmount(50)},
+				{FromAddr: sender, ToAddr: remote, Amount: models.NewAmount(tt.amount)},
+			}
+
+			err := m.CommitBlock(&models.Block{Index: 1, Timestamp: time.Now()}, txs)
+			if tt.wantErr {
+				if apperr.KindOf(err) != apperr.InsufficientFunds {
+					t.Fatalf("CommitBlock error = %v, want insufficient funds", err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			created := make(map[string]bool)
+			for _, address := range tt.wantWallets {
+				created[address] = true
+			}
+			for _, address := range []string{miner, remote, models.CoinbaseAddress} {
+				_, err := m.GetBalance(address)
+				if exists := err == nil; exists != created[address] {
+					t.Errorf("wallet %s exists = %v, want %v", address, exists, created[address])
+				}
+			}
+		})
+	}
+}
@@ -2,8 +2,10 @@ package database
 
 import (
 	"database/sql"
-	"fmt"
+	"encoding/json"
+	"hello-go/apperr"
 	"hello-go/models"
+	"strings"
 	"time"
 )
 
@@ -15,15 +17,63 @@ func NewBlockchainMySQL(db *sql.DB) *BlockchainMySQL {
 	return &BlockchainMySQL{db: db}
 }
 
+// 区块表查询列，与 scanBlock 的扫描顺序一致
+const blockColumns = `id, index_num, hash, prev_hash, data, merkle_root, timestamp, nonce, difficulty`
+
+// 交易表查询列，与 scanTransaction 的扫描顺序一致
+const transactionColumns = `id, block_id, hash, from_addr, to_addr, amount, fee, nonce, chain_id, signature, timestamp`
+
+// scanner 同时适用于 *sql.Row 和 *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// execer 同时适用于 *sql.DB 和 *sql.Tx 的执行接口
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func scanBlock(row scanner) (*models.Block, error) {
+	block := &models.Block{}
+	err := row.Scan(
+		&block.ID, &block.Index, &block.Hash, &block.PrevHash,
+		&block.Data, &block.MerkleRoot, &block.Timestamp, &block.Nonce, &block.Difficulty)
+	if err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+func scanTransaction(row scanner) (*models.Transaction, error) {
+	tx := &models.Transaction{}
+	var hash sql.NullString
+	err := row.Scan(&tx.ID, &tx.BlockID, &hash, &tx.FromAddr, &tx.ToAddr, &tx.Amount, &tx.Fee,
+		&tx.Nonce, &tx.ChainID, &tx.Signature, &tx.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	tx.Hash = hash.String
+	return tx, nil
+}
+
+// nullHash 未计算的交易哈希保存为 NULL，不参与唯一键检查
+func nullHash(hash string) sql.NullString {
+	return sql.NullString{String: hash, Valid: hash != ""}
+}
+
 // 保存区块
 func (b *BlockchainMySQL) SaveBlock(block *models.Block) error {
-	query := `INSERT INTO blocks (index_num, hash, prev_hash, data, timestamp, nonce, difficulty) 
-              VALUES (?, ?, ?, ?, ?, ?, ?)`
+	return insertBlock(b.db, block)
+}
+
+func insertBlock(exec execer, block *models.Block) error {
+	query := `INSERT INTO blocks (index_num, hash, prev_hash, data, merkle_root, timestamp, nonce, difficulty) 
+              VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
 
-	result, err := b.db.Exec(query, block.Index, block.Hash, block.PrevHash,
-		block.Data, block.Timestamp, block.Nonce, block.Difficulty)
+	result, err := exec.Exec(query, block.Index, block.Hash, block.PrevHash,
+		block.Data, block.MerkleRoot, block.Timestamp, block.Nonce, block.Difficulty)
 	if err != nil {
-		return err
+		return orConflict(err)
 	}
 
 	id, err := result.LastInsertId()
@@ -35,46 +85,171 @@ func (b *BlockchainMySQL) SaveBlock(block *models.Block) error {
 	return nil
 }
 
-// 根据索引获取区块
-func (b *BlockchainMySQL) GetBlockByIndex(index int) (*models.Block, error) {
-	query := `SELECT id, index_num, hash, prev_hash, data, timestamp, nonce, difficulty 
-              FROM blocks WHERE index_num = ?`
+// 保存区块、创建交易涉及的本地不存在的钱包并执行区块中的全部交易，任一步失败则整体回滚
+func (b *BlockchainMySQL) CommitBlock(block *models.Block, txs []*models.Transaction) (err error) {
+	dbTx, err := b.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			dbTx.Rollback()
+		}
+	}()
 
-	block := &models.Block{}
-	err := b.db.QueryRow(query, index).Scan(
-		&block.ID, &block.Index, &block.Hash, &block.PrevHash,
-		&block.Data, &block.Timestamp, &block.Nonce, &block.Difficulty)
+	if err = insertBlock(dbTx, block); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, address := range blockAddresses(txs) {
+		_, err = dbTx.Exec("INSERT IGNORE INTO wallets (address, balance, nonce, created_at) VALUES (?, 0, 0, ?)", address, now)
+		if err != nil {
+			return err
+		}
+	}
 
+	for _, tx := range txs {
+		tx.BlockID = block.ID
+		if err = applyTransfer(dbTx, tx); err != nil {
+			return err
+		}
+	}
+
+	return dbTx.Commit()
+}
+
+// 撤销链头区块：按相反顺序回滚其中交易的余额变动，删除交易记录和区块，返回被撤销的交易
+// 调用方需保证 block 是当前链头
+func (b *BlockchainMySQL) RollbackBlock(block *models.Block) (txs []*models.Transaction, err error) {
+	dbTx, err := b.db.Begin()
 	if err != nil {
 		return nil, err
 	}
+	defer func() {
+		if err != nil {
+			dbTx.Rollback()
+		}
+	}()
 
-	return block, nil
+	query := `SELECT ` + transactionColumns + ` FROM transactions WHERE block_id = ? ORDER BY id FOR UPDATE`
+	rows, err := dbTx.Query(query, block.ID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		tx, scanErr := scanTransaction(rows)
+		if scanErr != nil {
+			rows.Close()
+			return nil, scanErr
+		}
+		txs = append(txs, tx)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := len(txs) - 1; i >= 0; i-- {
+		tx := txs[i]
+		_, err = dbTx.Exec("UPDATE wallets SET balance = balance - CAST(? AS DECIMAL(65,0)) WHERE address = ?", tx.Amount, tx.ToAddr)
+		if err != nil {
+			return nil, err
+		}
+		// coinbase 交易没有转出钱包
+		if tx.IsCoinbase() {
+			continue
+		}
+		// 转账时已确认金额加手续费不溢出
+		cost, _ := tx.Amount.Add(tx.Fee)
+		_, err = dbTx.Exec("UPDATE wallets SET balance = balance + CAST(? AS DECIMAL(65,0)), nonce = nonce - 1 WHERE address = ?", cost, tx.FromAddr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err = dbTx.Exec("DELETE FROM transactions WHERE block_id = ?", block.ID); err != nil {
+		return nil, err
+	}
+	if _, err = dbTx.Exec("DELETE FROM blocks WHERE id = ?", block.ID); err != nil {
+		return nil, err
+	}
+
+	return txs, dbTx.Commit()
 }
 
-// 获取最新区块
-func (b *BlockchainMySQL) GetLatestBlock() (*models.Block, error) {
-	query := `SELECT id, index_num, hash, prev_hash, data, timestamp, nonce, difficulty 
-              FROM blocks ORDER BY index_num DESC LIMIT 1`
+// 保存侧链区块，区块中的交易以 JSON 形式随区块一起保存，不影响钱包余额
+func (b *BlockchainMySQL) SaveSideBlock(block *models.Block, txs []*models.Transaction) error {
+	encoded, err := json.Marshal(txs)
+	if err != nil {
+		return err
+	}
 
-	block := &models.Block{}
-	err := b.db.QueryRow(query).Scan(
-		&block.ID, &block.Index, &block.Hash, &block.PrevHash,
-		&block.Data, &block.Timestamp, &block.Nonce, &block.Difficulty)
+	query := `INSERT IGNORE INTO side_blocks (hash, index_num, prev_hash, data, merkle_root, timestamp, nonce, difficulty, transactions) 
+              VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = b.db.Exec(query, block.Hash, block.Index, block.PrevHash,
+		block.Data, block.MerkleRoot, block.Timestamp, block.Nonce, block.Difficulty, encoded)
+	return err
+}
+
+// 根据哈希获取侧链区块及其交易
+func (b *BlockchainMySQL) GetSideBlock(hash string) (*models.Block, []*models.Transaction, error) {
+	query := `SELECT hash, index_num, prev_hash, data, merkle_root, timestamp, nonce, difficulty, transactions 
+              FROM side_blocks WHERE hash = ?`
 
+	block := &models.Block{}
+	var encoded []byte
+	err := b.db.QueryRow(query, hash).Scan(&block.Hash, &block.Index, &block.PrevHash,
+		&block.Data, &block.MerkleRoot, &block.Timestamp, &block.Nonce, &block.Difficulty, &encoded)
 	if err != nil {
-		return nil, err
+		return nil, nil, orNotFound(err, apperr.CodeBlockNotFound, "side block %s not found", hash)
 	}
 
-	return block, nil
+	var txs []*models.Transaction
+	if err := json.Unmarshal(encoded, &txs); err != nil {
+		return nil, nil, err
+	}
+	return block, txs, nil
+}
+
+// 删除侧链区块
+func (b *BlockchainMySQL) DeleteSideBlock(hash string) error {
+	_, err := b.db.Exec("DELETE FROM side_blocks WHERE hash = ?", hash)
+	return err
+}
+
+// 根据索引获取区块
+func (b *BlockchainMySQL) GetBlockByIndex(index int) (*models.Block, error) {
+	query := `SELECT ` + blockColumns + ` FROM blocks WHERE index_num = ?`
+	block, err := scanBlock(b.db.QueryRow(query, index))
+	return block, orNotFound(err, apperr.CodeBlockNotFound, "block %d not found", index)
+}
+
+// 根据ID获取区块
+func (b *BlockchainMySQL) GetBlockByID(id int64) (*models.Block, error) {
+	query := `SELECT ` + blockColumns + ` FROM blocks WHERE id = ?`
+	block, err := scanBlock(b.db.QueryRow(query, id))
+	return block, orNotFound(err, apperr.CodeBlockNotFound, "block with id %d not found", id)
+}
+
+// 根据哈希获取主链区块
+func (b *BlockchainMySQL) GetBlockByHash(hash string) (*models.Block, error) {
+	query := `SELECT ` + blockColumns + ` FROM blocks WHERE hash = ?`
+	block, err := scanBlock(b.db.QueryRow(query, hash))
+	return block, orNotFound(err, apperr.CodeBlockNotFound, "block %s not found", hash)
+}
+
+// 获取最新区块
+func (b *BlockchainMySQL) GetLatestBlock() (*models.Block, error) {
+	query := `SELECT ` + blockColumns + ` FROM blocks ORDER BY index_num DESC LIMIT 1`
+	block, err := scanBlock(b.db.QueryRow(query))
+	return block, orNotFound(err, apperr.CodeBlockNotFound, "chain has no blocks")
 }
 
 // 获取所有区块
 func (b *BlockchainMySQL) GetAllBlocks() ([]*models.Block, error) {
-	query := `SELECT id, index_num, hash, prev_hash, data, timestamp, nonce, difficulty 
-              FROM blocks ORDER BY index_num`
+	query := `SELECT ` + blockColumns + ` FROM blocks ORDER BY index_num`
 	rows, err := b.db.Query(query)
-	fmt.Printf("执行SQL: %s [参数: %v]\n", query)
 	if err != nil {
 		return nil, err
 	}
@@ -82,25 +257,58 @@ func (b *BlockchainMySQL) GetAllBlocks() ([]*models.Block, error) {
 
 	var blocks []*models.Block
 	for rows.Next() {
-		block := &models.Block{}
-		err := rows.Scan(
-			&block.ID, &block.Index, &block.Hash, &block.PrevHash,
-			&block.Data, &block.Timestamp, &block.Nonce, &block.Difficulty)
+		block, err := scanBlock(rows)
 		if err != nil {
 			return nil, err
 		}
 		blocks = append(blocks, block)
 	}
 
-	return blocks, nil
+	return blocks, rows.Err()
+}
+
+// 按高度升序获取高度在 [from, to] 内的主链区块
+func (b *BlockchainMySQL) GetBlocksByRange(from, to int) ([]*models.Block, error) {
+	query := `SELECT ` + blockColumns + ` FROM blocks WHERE index_num BETWEEN ? AND ? ORDER BY index_num`
+	rows, err := b.db.Query(query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blocks []*models.Block
+	for rows.Next() {
+		block, err := scanBlock(rows)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+
+	return blocks, rows.Err()
+}
+
+// 获取高度在 [from, to] 内的主链区块中的全部交易，同一区块内按打包顺序返回
+func (b *BlockchainMySQL) GetTransactionsByBlockRange(from, to int) ([]*models.Transaction, error) {
+	query := `SELECT ` + transactionColumns + ` FROM transactions 
+              WHERE block_id IN (SELECT id FROM blocks WHERE index_num BETWEEN ? AND ?) ORDER BY block_id, id`
+	return b.queryTransactions(query, from, to)
 }
 
 // 保存交易
 func (b *BlockchainMySQL) SaveTransaction(tx *models.Transaction) error {
-	query := `INSERT INTO transactions (block_id, from_addr, to_addr, amount, timestamp) 
-              VALUES (?, ?, ?, ?, ?)`
+	return insertTransaction(b.db, tx)
+}
+
+func insertTransaction(exec execer, tx *models.Transaction) error {
+	query := `INSERT INTO transactions (block_id, hash, from_addr, to_addr, amount, fee, nonce, chain_id, signature, timestamp) 
+              VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	result, err := b.db.Exec(query, tx.BlockID, tx.FromAddr, tx.ToAddr, tx.Amount, tx.Timestamp)
+	result, err := exec.Exec(query, tx.BlockID, nullHash(tx.Hash), tx.FromAddr, tx.ToAddr, tx.Amount, tx.Fee,
+		tx.Nonce, tx.ChainID, tx.Signature, tx.Timestamp)
+	if isDuplicateEntry(err) {
+		return duplicateTransaction(tx.Hash)
+	}
 	if err != nil {
 		return err
 	}
@@ -114,12 +322,43 @@ func (b *BlockchainMySQL) SaveTransaction(tx *models.Transaction) error {
 	return nil
 }
 
-// 获取区块的所有交易
+// 根据ID获取交易
+func (b *BlockchainMySQL) GetTransactionByID(id int64) (*models.Transaction, error) {
+	query := `SELECT ` + transactionColumns + ` FROM transactions WHERE id = ?`
+	tx, err := scanTransaction(b.db.QueryRow(query, id))
+	return tx, orNotFound(err, apperr.CodeTransactionNotFound, "transaction %d not found", id)
+}
+
+// 根据交易哈希获取已上链交易
+func (b *BlockchainMySQL) GetTransactionByHash(hash string) (*models.Transaction, error) {
+	query := `SELECT ` + transactionColumns + ` FROM transactions WHERE hash = ?`
+	tx, err := scanTransaction(b.db.QueryRow(query, strings.ToLower(hash)))
+	return tx, orNotFound(err, apperr.CodeTransactionNotFound, "transaction %s not found", hash)
+}
+
+// 按ID升序获取ID大于 afterID 且尚未保存哈希的交易
+func (b *BlockchainMySQL) GetTransactionsWithoutHash(afterID int64, limit int) ([]*models.Transaction, error) {
+	query := `SELECT ` + transactionColumns + ` FROM transactions WHERE hash IS NULL AND id > ? ORDER BY id LIMIT ?`
+	return b.queryTransactions(query, afterID, limit)
+}
+
+// 保存已有交易的哈希
+func (b *BlockchainMySQL) SetTransactionHash(id int64, hash string) error {
+	_, err := b.db.Exec(`UPDATE transactions SET hash = ? WHERE id = ?`, hash, id)
+	if isDuplicateEntry(err) {
+		return duplicateTransaction(hash)
+	}
+	return err
+}
+
+// 获取区块的所有交易，按打包顺序返回
 func (b *BlockchainMySQL) GetTransactionsByBlockID(blockID int64) ([]*models.Transaction, error) {
-	query := `SELECT id, block_id, from_addr, to_addr, amount, timestamp 
-              FROM transactions WHERE block_id = ?`
+	query := `SELECT ` + transactionColumns + ` FROM transactions WHERE block_id = ? ORDER BY id`
+	return b.queryTransactions(query, blockID)
+}
 
-	rows, err := b.db.Query(query, blockID)
+func (b *BlockchainMySQL) queryTransactions(query string, args ...interface{}) ([]*models.Transaction, error) {
+	rows, err := b.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -127,74 +366,162 @@ func (b *BlockchainMySQL) GetTransactionsByBlockID(blockID int64) ([]*models.Tra
 
 	var transactions []*models.Transaction
 	for rows.Next() {
-		tx := &models.Transaction{}
-		err := rows.Scan(&tx.ID, &tx.BlockID, &tx.FromAddr, &tx.ToAddr, &tx.Amount, &tx.Timestamp)
+		tx, err := scanTransaction(rows)
 		if err != nil {
 			return nil, err
 		}
 		transactions = append(transactions, tx)
 	}
 
-	return transactions, nil
+	return transactions, rows.Err()
 }
 
-func (b *BlockchainMySQL) SaveWallet(wallet *models.Wallet) error {
-	query := `INSERT INTO wallets (address, balance,private_key,creat_time) VALUES (?,?,?,?)`
-	result, err := b.db.Exec(query,
-		wallet.Address, wallet.Balance, wallet.PrivateKey, time.Now())
-	fmt.Printf("执行SQL: %s [参数: %v]\n", query, wallet.Address, wallet.Balance, wallet.PrivateKey)
+// 获取地址转出和转入的交易，最新的在前
+func (b *BlockchainMySQL) GetTransactionsByAddress(address string) ([]*models.Transaction, error) {
+	query := `SELECT ` + transactionColumns + ` FROM transactions 
+              WHERE from_addr = ? OR to_addr = ? ORDER BY timestamp DESC, id DESC`
+	return b.queryTransactions(query, address, address)
+}
+
+// 分页获取交易，最新的在前
+func (b *BlockchainMySQL) GetTransactions(limit, offset int) ([]*models.Transaction, error) {
+	query := `SELECT ` + transactionColumns + ` FROM transactions ORDER BY timestamp DESC, id DESC LIMIT ? OFFSET ?`
+	return b.queryTransactions(query, limit, offset)
+}
+
+// 统计交易总数
+func (b *BlockchainMySQL) CountTransactions() (int, error) {
+	var count int
+	if err := b.db.QueryRow("SELECT COUNT(*) FROM transactions").Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// 查询钱包的下一个交易序号
+func (b *BlockchainMySQL) GetNonce(address string) (uint64, error) {
+	var nonce uint64
+	err := b.db.QueryRow("SELECT nonce FROM wallets WHERE address = ?", address).Scan(&nonce)
 	if err != nil {
-		fmt.Println("插入失败:", err)
+		return 0, orNotFound(err, apperr.CodeWalletNotFound, "wallet %s not found", address)
 	}
-	rows, _ := result.RowsAffected()
-	fmt.Println("插入行数:", rows)
-	return nil
+	return nonce, nil
 }
 
-// 给 wallet 充值
-func (b *BlockchainMySQL) TopUpWallet(address string) error {
-	query := `UPDATE wallets SET balance = 1000 WHERE address = ?`
-	result, err := b.db.Exec(query, address)
-	fmt.Printf("执行SQL: %s [参数: %v]\n", query, address)
+func (b *BlockchainMySQL) SaveWallet(wallet *models.Wallet) error {
+	// 私钥保存在加密 keystore 中，不写入数据库
+	query := `INSERT INTO wallets (address, balance, nonce, created_at) VALUES (?, ?, ?, ?)`
+	_, err := b.db.Exec(query, wallet.Address, wallet.Balance, wallet.Nonce, time.Now())
+	return orConflict(err)
+}
+
+// 转账：在同一个数据库事务中锁定双方钱包、变更余额并记录交易，任一步失败则整体回滚
+func (b *BlockchainMySQL) Transfer(tx *models.Transaction) (err error) {
+	dbTx, err := b.db.Begin()
 	if err != nil {
-		fmt.Println("update fi:", err)
+		return err
 	}
-	rows, _ := result.RowsAffected()
-	fmt.Println("upadte rows:", rows)
-	return nil
+	defer func() {
+		if err != nil {
+			dbTx.Rollback()
+		}
+	}()
+
+	if err = applyTransfer(dbTx, tx); err != nil {
+		return err
+	}
+
+	return dbTx.Commit()
 }
 
-// 转账
-func (b *BlockchainMySQL) Transfer(from, to string, amount float64) error {
-	// 检查余额
-	var fromBalance float64
-	err := b.db.QueryRow("SELECT balance FROM wallets WHERE address = ?", from).Scan(&fromBalance)
+// applyTransfer 在给定事务中执行一笔转账，由调用方负责提交或回滚
+func applyTransfer(dbTx *sql.Tx, tx *models.Transaction) error {
+	if tx.IsCoinbase() {
+		return applyCoinbase(dbTx, tx)
+	}
+
+	// 按地址顺序对双方钱包加行锁，避免并发的反向转账互相等待造成死锁
+	rows, err := dbTx.Query(`SELECT address, balance, nonce FROM wallets
+              WHERE address IN (?, ?) ORDER BY address FOR UPDATE`, tx.FromAddr, tx.ToAddr)
 	if err != nil {
 		return err
 	}
-	if fromBalance < amount {
-		return fmt.Errorf("余额不足")
+	wallets := make(map[string]models.Wallet, 2)
+	for rows.Next() {
+		var wallet models.Wallet
+		if err := rows.Scan(&wallet.Address, &wallet.Balance, &wallet.Nonce); err != nil {
+			rows.Close()
+			return err
+		}
+		wallets[strings.ToLower(wallet.Address)] = wallet
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// 检查双方钱包、交易序号和余额
+	from, ok := wallets[strings.ToLower(tx.FromAddr)]
+	if !ok {
+		return notFound(apperr.CodeWalletNotFound, "wallet %s not found", tx.FromAddr)
+	}
+	if _, ok := wallets[strings.ToLower(tx.ToAddr)]; !ok {
+		return recipientNotFound(tx.ToAddr)
+	}
+	if tx.Nonce != from.Nonce {
+		return invalidNonce(tx.FromAddr, from.Nonce, tx.Nonce)
+	}
+	cost, ok := tx.Amount.Add(tx.Fee)
+	if !ok || from.Balance.Cmp(cost) < 0 {
+		return insufficientFunds()
 	}
-	// 扣减和增加余额
-	_, err = b.db.Exec("UPDATE wallets SET balance = balance - ? WHERE address = ?", amount, from)
+
+	// 转出钱包扣减金额和手续费、序号加一，收款钱包增加金额，手续费由 coinbase 交易发放给矿工
+	// 金额以字符串传入，显式转换为 DECIMAL，避免 MySQL 按 DOUBLE 计算丢失精度
+	_, err = dbTx.Exec("UPDATE wallets SET balance = balance - CAST(? AS DECIMAL(65,0)), nonce = nonce + 1 WHERE address = ?", cost, tx.FromAddr)
 	if err != nil {
 		return err
 	}
-	_, err = b.db.Exec("UPDATE wallets SET balance = balance + ? WHERE address = ?", amount, to)
+	_, err = dbTx.Exec("UPDATE wallets SET balance = balance + CAST(? AS DECIMAL(65,0)) WHERE address = ?", tx.Amount, tx.ToAddr)
 	if err != nil {
 		return err
 	}
+
 	// 记录交易
-	_, err = b.db.Exec("INSERT INTO transactions (from_addr, to_addr, amount) VALUES (?, ?, ?)", from, to, amount)
-	return err
+	return insertTransaction(dbTx, tx)
+}
+
+// applyCoinbase 在给定事务中向矿工发放出块奖励和手续费
+func applyCoinbase(dbTx *sql.Tx, tx *models.Transaction) error {
+	result, err := dbTx.Exec("UPDATE wallets SET balance = balance + CAST(? AS DECIMAL(65,0)) WHERE address = ?", tx.Amount, tx.ToAddr)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return recipientNotFound(tx.ToAddr)
+	}
+	return insertTransaction(dbTx, tx)
+}
+
+// 统计全部钱包的余额之和
+func (b *BlockchainMySQL) GetTotalSupply() (models.Amount, error) {
+	var total models.Amount
+	if err := b.db.QueryRow("SELECT COALESCE(SUM(balance), 0) FROM wallets").Scan(&total); err != nil {
+		return models.Amount{}, err
+	}
+	return total, nil
 }
 
 // 查询钱包余额
-func (b *BlockchainMySQL) GetBalance(address string) (float64, error) {
-	var balance float64
+func (b *BlockchainMySQL) GetBalance(address string) (models.Amount, error) {
+	var balance models.Amount
 	err := b.db.QueryRow("SELECT balance FROM wallets WHERE address = ?", address).Scan(&balance)
 	if err != nil {
-		return 0, err
+		return models.Amount{}, orNotFound(err, apperr.CodeWalletNotFound, "wallet %s not found", address)
 	}
 	return balance, nil
 }
@@ -0,0 +1,328 @@
+package database
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"hello-go/apperr"
+	"hello-go/models"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testMySQLEnv 测试专用 MySQL 数据库的 DSN，例如
+// root:password@tcp(127.0.0.1:3306)/blockchain_test?parseTime=true
+// 未设置时跳过依赖 MySQL 的测试。测试先执行全部迁移，只写入自己创建的区块、钱包和交易，结束时全部删除
+const testMySQLEnv = "BLOCKCHAIN_TEST_MYSQL_DSN"
+
+// mysqlFixture 测试用的 MySQL 数据：一个区块和若干钱包，转账记录关联到该区块
+type mysqlFixture struct {
+	b         *BlockchainMySQL
+	block     *models.Block
+	addresses []string
+}
+
+// newMySQLFixture 连接并迁移测试数据库，创建区块和指定余额的钱包，测试结束时删除
+func newMySQLFixture(t *testing.T, balances ...uint64) *mysqlFixture {
+	t.Helper()
+
+	dsn := os.Getenv(testMySQLEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testMySQLEnv)
+	}
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MigrateUp(db, 0, 18); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &mysqlFixture{b: NewBlockchainMySQL(db)}
+	// 高度和哈希取随机值，避免与数据库中已有的区块冲突
+	f.block = &models.Block{
+		Index:     1<<30 + int(randomInt(t, 1<<30)),
+		Hash:      randomHex(t, 32),
+		PrevHash:  strings.Repeat("0", 64),
+		Timestamp: time.Now(),
+	}
+	if err := f.b.SaveBlock(f.block); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.cleanup(t) })
+
+	for _, balance := range balances {
+		address := "0x" + randomHex(t, 20)
+		if err := f.b.SaveWallet(&models.Wallet{Address: address, Balance: models.NewAmount(balance)}); err != nil {
+			t.Fatal(err)
+		}
+		f.addresses = append(f.addresses, address)
+	}
+	return f
+}
+
+func (f *mysqlFixture) cleanup(t *testing.T) {
+	if _, err := f.b.db.Exec("DELETE FROM transactions WHERE block_id = ?", f.block.ID); err != nil {
+		t.Error(err)
+	}
+	if _, err := f.b.db.Exec("DELETE FROM blocks WHERE id = ?", f.block.ID); err != nil {
+		t.Error(err)
+	}
+	for _, address := range f.addresses {
+		if _, err := f.b.db.Exec("DELETE FROM wallets WHERE address = ?", address); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// transfer 构造关联到测试区块的转账
+func (f *mysqlFixture) transfer(from, to string, amount, nonce uint64) *models.Transaction {
+	return &models.Transaction{
+		BlockID:   f.block.ID,
+		FromAddr:  from,
+		ToAddr:    to,
+		Amount:    models.NewAmount(amount),
+		Nonce:     nonce,
+		Timestamp: time.Now(),
+	}
+}
+
+// balances 查询测试钱包的余额
+func (f *mysqlFixture) balances(t *testing.T) map[string]models.Amount {
+	t.Helper()
+
+	balances := make(map[string]models.Amount, len(f.addresses))
+	for _, address := range f.addresses {
+		balance, err := f.b.GetBalance(address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		balances[address] = balance
+	}
+	return balances
+}
+
+// recorded 查询关联到测试区块的交易记录
+func (f *mysqlFixture) recorded(t *testing.T) []*models.Transaction {
+	t.Helper()
+
+	txs, err := f.b.GetTransactionsByBlockID(f.block.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return txs
+}
+
+func TestMySQLConcurrentTransfersConserveSupply(t *testing.T) {
+	const (
+		workers = 8
+		rounds  = 50
+		initial = 100
+	)
+	f := newMySQLFixture(t, initial, initial)
+	a, b := f.addresses[0], f.addresses[1]
+	supply, err := f.b.GetTotalSupply()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			// 偶数协程 a -> b，奇数协程 b -> a，双方钱包被反向加锁时不能死锁
+			from, to := a, b
+			if w%2 == 1 {
+				from, to = b, a
+			}
+			for i := 0; i < rounds; i++ {
+				// 序号可能在读取后被其他协程使用，此时转账因序号冲突被拒绝
+				nonce, err := f.b.GetNonce(from)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				err = f.b.Transfer(f.transfer(from, to, uint64(1+(w+i)%7), nonce))
+				switch apperr.KindOf(err) {
+				case apperr.InsufficientFunds, apperr.Conflict:
+				default:
+					if err != nil {
+						t.Errorf("transfer %s -> %s: %v", from, to, err)
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	balances := f.balances(t)
+	if sum, _ := balances[a].Add(balances[b]); sum.Cmp(models.NewAmount(2*initial)) != 0 {
+		t.Errorf("sum of balances = %s, want %d", sum, 2*initial)
+	}
+	if total, err := f.b.GetTotalSupply(); err != nil || total.Cmp(supply) != 0 {
+		t.Errorf("total supply = %s (%v), want %s", total, err, supply)
+	}
+
+	// 余额变动与交易记录一致：没有记录的转账不能改变余额，被回滚的转账不能留下记录
+	// 序号等于记录的转出交易数
+	want := map[string]*big.Int{a: big.NewInt(initial), b: big.NewInt(initial)}
+	sent := make(map[string]uint64)
+	txs := f.recorded(t)
+	for _, tx := range txs {
+		want[tx.FromAddr].Sub(want[tx.FromAddr], tx.Amount.ToBig())
+		want[tx.ToAddr].Add(want[tx.ToAddr], tx.Amount.ToBig())
+		sent[tx.FromAddr]++
+	}
+	for _, address := range f.addresses {
+		if balances[address].ToBig().Cmp(want[address]) != 0 {
+			t.Errorf("balance of %s = %s, want %s from %d recorded transfers", address, balances[address], want[address], len(txs))
+		}
+		nonce, err := f.b.GetNonce(address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nonce != sent[address] {
+			t.Errorf("nonce of %s = %d, want %d recorded outgoing transfers", address, nonce, sent[address])
+		}
+	}
+	if len(txs) == 0 {
+		t.Error("no transfer succeeded")
+	}
+}
+
+func TestMySQLTransferRejectsWithoutWriting(t *testing.T) {
+	tests := []struct {
+		name     string
+		tx       func(t *testing.T, f *mysqlFixture) *models.Transaction
+		wantKind apperr.Kind
+	}{
+		{
+			name: "unknown recipient",
+			tx: func(t *testing.T, f *mysqlFixture) *models.Transaction {
+				return f.transfer(f.addresses[0], "0x"+randomHex(t, 20), 10, 0)
+			},
+			wantKind: apperr.NotFound,
+		},
+		{
+			name: "unknown sender",
+			tx: func(t *testing.T, f *mysqlFixture) *models.Transaction {
+				return f.transfer("0x"+randomHex(t, 20), f.addresses[0], 10, 0)
+			},
+			wantKind: apperr.NotFound,
+		},
+		{
+			name: "insufficient funds",
+			tx: func(t *testing.T, f *mysqlFixture) *models.Transaction {
+				return f.transfer(f.addresses[0], f.addresses[1], 101, 0)
+			},
+			wantKind: apperr.InsufficientFunds,
+		},
+		{
+			name: "nonce too high",
+			tx: func(t *testing.T, f *mysqlFixture) *models.Transaction {
+				return f.transfer(f.addresses[0], f.addresses[1], 10, 1)
+			},
+			wantKind: apperr.Conflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newMySQLFixture(t, 100, 0)
+
+			if err := f.b.Transfer(tt.tx(t, f)); apperr.KindOf(err) != tt.wantKind {
+				t.Fatalf("Transfer error = %v, want kind %v", err, tt.wantKind)
+			}
+			balances := f.balances(t)
+			if balances[f.addresses[0]].Cmp(models.NewAmount(100)) != 0 || !balances[f.addresses[1]].IsZero() {
+				t.Errorf("balances = %v, want unchanged", balances)
+			}
+			if txs := f.recorded(t); len(txs) != 0 {
+				t.Errorf("%d transactions recorded, want 0", len(txs))
+			}
+		})
+	}
+}
+
+func TestMySQLCommitBlockCreatesWallets(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  uint64
+		wantErr bool
+	}{
+		{name: "valid block", amount: 10},
+		{name: "rejected block", amount: 101, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newMySQLFixture(t, 100)
+			miner, remote := "0x"+randomHex(t, 20), "0x"+randomHex(t, 20)
+			block := &models.Block{
+				Index:     f.block.Index + 1,
+				Hash:      randomHex(t, 32),
+				PrevHash:  f.block.Hash,
+				Timestamp: time.Now(),
+			}
+			t.Cleanup(func() {
+				if block.ID != 0 {
+					f.b.db.Exec("DELETE FROM transactions WHERE block_id = ?", block.ID)
+					f.b.db.Exec("DELETE FROM blocks WHERE id = ?", block.ID)
+				}
+				f.b.db.Exec("DELETE FROM wallets WHERE address IN (?, ?)", miner, remote)
+			})
+			txs := []*models.Transaction{
+				{FromAddr: models.CoinbaseAddress, ToAddr: miner, Amount: models.NewAmount(50), Timestamp: time.Now()},
+				{FromAddr: f.addresses[0], ToAddr: remote, Amount: models.NewAmount(tt.amount), Timestamp: time.Now()},
+			}
+
+			err := f.b.CommitBlock(block, txs)
+			if tt.wantErr {
+				if apperr.KindOf(err) != apperr.InsufficientFunds {
+					t.Fatalf("CommitBlock error = %v, want insufficient funds", err)
+				}
+				// 事务回滚后区块ID不再有效
+				block.ID = 0
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			want := map[string]bool{miner: !tt.wantErr, remote: !tt.wantErr}
+			for address, wantExists := range want {
+				_, err := f.b.GetBalance(address)
+				if exists := err == nil; exists != wantExists {
+					t.Errorf("wallet %s exists = %v, want %v", address, exists, wantExists)
+				}
+			}
+		})
+	}
+}
+
+func randomHex(t *testing.T, n int) string {
+	t.Helper()
+
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func randomInt(t *testing.T, max int64) int64 {
+	t.Helper()
+
+	n, err := rand.Int(rand.Reader, big.NewInt(max))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n.Int64()
+}
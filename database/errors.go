@@ -0,0 +1,63 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"hello-go/apperr"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL 唯一键冲突的错误号
+const mysqlDuplicateEntry = 1062
+
+// notFound 资源不存在的错误
+func notFound(code, format string, args ...interface{}) error {
+	return apperr.NewNotFound(code, format, args...)
+}
+
+// orNotFound 将查询返回的 sql.ErrNoRows 转换为 notFound 错误，其他错误原样返回
+func orNotFound(err error, code, format string, args ...interface{}) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound(code, format, args...)
+	}
+	return err
+}
+
+// orConflict 将 MySQL 唯一键冲突转换为 Conflict 错误，其他错误原样返回
+func orConflict(err error) error {
+	if isDuplicateEntry(err) {
+		return apperr.Wrap(apperr.Conflict, "", err)
+	}
+	return err
+}
+
+// isDuplicateEntry 是否为 MySQL 唯一键冲突
+func isDuplicateEntry(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry
+}
+
+// duplicateTransaction 相同哈希的交易已经上链
+func duplicateTransaction(hash string) error {
+	return apperr.New(apperr.Conflict, apperr.CodeDuplicateTransaction, "transaction %s already exists", hash)
+}
+
+// insufficientFunds 转出钱包余额不足
+func insufficientFunds() error {
+	return apperr.New(apperr.InsufficientFunds, "", "余额不足")
+}
+
+// invalidNonce 交易序号与转出钱包的下一个序号不一致
+func invalidNonce(address string, expected, got uint64) error {
+	code := apperr.CodeNonceTooLow
+	if got > expected {
+		code = apperr.CodeNonceTooHigh
+	}
+	return apperr.New(apperr.Conflict, code, "invalid nonce for %s: expected %d, got %d", address, expected, got)
+}
+
+// recipientNotFound 收款钱包不存在
+func recipientNotFound(address string) error {
+	return apperr.New(apperr.NotFound, apperr.CodeWalletNotFound, "收款钱包不存在: %s", address)
+}
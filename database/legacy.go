@@ -0,0 +1,300 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"time"
+)
+
+// 迁移机制引入前的数据库升级脚本，脚本中的 {{name}} 在执行前替换为参数值
+//
+//go:embed legacy/*.sql
+var legacyFiles embed.FS
+
+// legacyScript 读取 legacy 目录下的脚本并替换参数
+func legacyScript(name string, params map[string]string) (string, error) {
+	data, err := legacyFiles.ReadFile(path.Join("legacy", name))
+	if err != nil {
+		return "", err
+	}
+	script := string(data)
+	for key, value := range params {
+		script = strings.ReplaceAll(script, "{{"+key+"}}", value)
+	}
+	return script, nil
+}
+
+// amountColumn 保存金额的列，definition 为换算后的列定义，与迁移 1 的表结构一致
+type amountColumn struct {
+	table      string
+	column     string
+	definition string
+}
+
+var amountColumns = []amountColumn{
+	{table: "wallets", column: "balance", definition: "DECIMAL(65,0) NOT NULL DEFAULT 0 AFTER address"},
+	{table: "transactions", column: "amount", definition: "DECIMAL(65,0) NOT NULL AFTER to_addr"},
+}
+
+// columnType 查询列的数据类型和小数位数，列不存在时 ok 为 false
+func columnType(conn *sql.Conn, table, column string) (dataType string, scale int64, ok bool, err error) {
+	var s sql.NullInt64
+	err = conn.QueryRowContext(context.Background(),
+		`SELECT DATA_TYPE, NUMERIC_SCALE FROM information_schema.columns
+         WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?`, table, column).Scan(&dataType, &s)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", 0, false, nil
+	}
+	if err != nil {
+		return "", 0, false, err
+	}
+	return strings.ToLower(dataType), s.Int64, true, nil
+}
+
+// tableExists 当前数据库中是否存在表
+func tableExists(conn *sql.Conn, table string) (bool, error) {
+	var count int
+	err := conn.QueryRowContext(context.Background(),
+		"SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?", table).Scan(&count)
+	return count > 0, err
+}
+
+// convertLegacyAmounts 将以完整单位保存金额的列（带小数位的 DECIMAL 或 DOUBLE）换算为整数最小单位，decimals 为金额的小数位数
+// 换算前检查全部金额，任何一个金额需要舍入才能换算时不做修改并返回错误；没有需要换算的列时不做任何操作
+func convertLegacyAmounts(conn *sql.Conn, decimals int) error {
+	ctx := context.Background()
+	unit := "1" + strings.Repeat("0", decimals)
+
+	var pending []amountColumn
+	for _, c := range amountColumns {
+		dataType, scale, ok, err := columnType(conn, c.table, c.column)
+		if err != nil {
+			return err
+		}
+		if !ok || (dataType == "decimal" && scale == 0) {
+			continue
+		}
+		if dataType != "decimal" && dataType != "double" && dataType != "float" {
+			return fmt.Errorf("cannot convert %s.%s of type %s to integer units", c.table, c.column, dataType)
+		}
+		pending = append(pending, c)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	// 先检查全部列，避免只换算了一部分
+	for _, c := range pending {
+		query, err := legacyScript("integer_amounts_check.sql", map[string]string{"table": c.table, "column": c.column, "unit": unit})
+		if err != nil {
+			return err
+		}
+		var inexact int
+		if err := conn.QueryRowContext(ctx, splitStatements(query)[0]).Scan(&inexact); err != nil {
+			return fmt.Errorf("check %s.%s: %v", c.table, c.column, err)
+		}
+		if inexact > 0 {
+			return fmt.Errorf("%d rows of %s.%s are empty, negative or cannot be converted to integer units with %d decimals without rounding, fix them before migrating",
+				inexact, c.table, c.column, decimals)
+		}
+	}
+
+	// 侧链区块中的交易 JSON 仍是旧的金额格式，在换算前清除，需要时会从其他节点重新获取
+	exists, err := tableExists(conn, "side_blocks")
+	if err != nil {
+		return err
+	}
+	if exists {
+		if _, err := conn.ExecContext(ctx, "DELETE FROM side_blocks"); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range pending {
+		// 上次换算中途失败时可能留下临时列，原列仍是换算前的值
+		if _, _, ok, err := columnType(conn, c.table, c.column+"_units"); err != nil {
+			return err
+		} else if ok {
+			if _, err := conn.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s_units", c.table, c.column)); err != nil {
+				return err
+			}
+		}
+
+		script, err := legacyScript("integer_amounts.sql", map[string]string{
+			"table": c.table, "column": c.column, "unit": unit, "definition": c.definition,
+		})
+		if err != nil {
+			return err
+		}
+		for _, stmt := range splitStatements(script) {
+			if _, err := conn.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("convert %s.%s: %v", c.table, c.column, err)
+			}
+		}
+		log.Printf("Converted %s.%s to integer units with %d decimals", c.table, c.column, decimals)
+	}
+
+	return nil
+}
+
+// indexExists 表上是否存在指定名称的索引或外键约束
+func indexExists(conn *sql.Conn, table, name string) (bool, error) {
+	var count int
+	err := conn.QueryRowContext(context.Background(),
+		`SELECT (SELECT COUNT(*) FROM information_schema.statistics
+                 WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?)
+              + (SELECT COUNT(*) FROM information_schema.table_constraints
+                 WHERE table_schema = DATABASE() AND table_name = ? AND constraint_name = ?)`,
+		table, name, table, name).Scan(&count)
+	return count > 0, err
+}
+
+// legacyChange 旧表结构的一项调整，applies 返回 true 时执行 stmt
+type legacyChange struct {
+	applies func(conn *sql.Conn) (bool, error)
+	stmt    string
+}
+
+// columnMissing 列不存在时执行调整
+func columnMissing(table, column string) func(conn *sql.Conn) (bool, error) {
+	return func(conn *sql.Conn) (bool, error) {
+		_, _, ok, err := columnType(conn, table, column)
+		return !ok, err
+	}
+}
+
+// columnPresent 列存在时执行调整
+func columnPresent(table, column string) func(conn *sql.Conn) (bool, error) {
+	return func(conn *sql.Conn) (bool, error) {
+		_, _, ok, err := columnType(conn, table, column)
+		return ok, err
+	}
+}
+
+// indexMissing 索引或外键约束不存在时执行调整
+func indexMissing(table, name string) func(conn *sql.Conn) (bool, error) {
+	return func(conn *sql.Conn) (bool, error) {
+		ok, err := indexExists(conn, table, name)
+		return !ok, err
+	}
+}
+
+// always 每次都执行的调整，语句本身可以重复执行
+func always(*sql.Conn) (bool, error) {
+	return true, nil
+}
+
+// legacyChanges 将迁移机制引入前手工建立的 blocks、wallets、transactions 表调整为迁移 1 的结构
+// 每项调整只在需要时执行，中途失败后重新执行会跳过已完成的调整
+var legacyChanges = []legacyChange{
+	// 旧版本以明文保存私钥，私钥现在只保存在加密 keystore 中
+	{columnPresent("wallets", "private_key"), "ALTER TABLE wallets DROP COLUMN private_key"},
+	{columnPresent("wallets", "creat_time"), "UPDATE wallets SET creat_time = NOW() WHERE creat_time IS NULL"},
+	{columnPresent("wallets", "creat_time"), "ALTER TABLE wallets CHANGE creat_time created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP"},
+	{columnMissing("wallets", "created_at"), "ALTER TABLE wallets ADD COLUMN created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP"},
+
+	{columnMissing("blocks", "merkle_root"), "ALTER TABLE blocks ADD COLUMN merkle_root VARCHAR(64) NOT NULL DEFAULT '' AFTER data"},
+	{always, "ALTER TABLE blocks MODIFY nonce BIGINT NOT NULL"},
+	{indexMissing("blocks", "uk_blocks_hash"), "ALTER TABLE blocks ADD UNIQUE KEY uk_blocks_hash (hash)"},
+
+	// 旧版本转账时直接写入交易记录，这些记录没有对应的区块
+	{always, "DELETE FROM transactions WHERE block_id IS NULL"},
+	{columnMissing("transactions", "nonce"), "ALTER TABLE transactions ADD COLUMN nonce BIGINT UNSIGNED NOT NULL DEFAULT 0 AFTER amount"},
+	{columnMissing("transactions", "chain_id"), "ALTER TABLE transactions ADD COLUMN chain_id BIGINT UNSIGNED NOT NULL DEFAULT 0 AFTER nonce"},
+	{columnMissing("transactions", "signature"), "ALTER TABLE transactions ADD COLUMN signature VARCHAR(132) NOT NULL DEFAULT '' AFTER chain_id"},
+	{always, `ALTER TABLE transactions
+              MODIFY block_id BIGINT NOT NULL,
+              MODIFY from_addr VARCHAR(42) NOT NULL,
+              MODIFY to_addr VARCHAR(42) NOT NULL`},
+	{indexMissing("transactions", "idx_transactions_block_id"), "ALTER TABLE transactions ADD INDEX idx_transactions_block_id (block_id)"},
+	{indexMissing("transactions", "fk_transactions_block"), "ALTER TABLE transactions ADD CONSTRAINT fk_transactions_block FOREIGN KEY (block_id) REFERENCES blocks (id)"},
+	{indexMissing("transactions", "fk_transactions_from"), "ALTER TABLE transactions ADD CONSTRAINT fk_transactions_from FOREIGN KEY (from_addr) REFERENCES wallets (address)"},
+	{indexMissing("transactions", "fk_transactions_to"), "ALTER TABLE transactions ADD CONSTRAINT fk_transactions_to FOREIGN KEY (to_addr) REFERENCES wallets (address)"},
+}
+
+// orphanTransactionsQuery 统计没有对应区块或钱包的交易记录，这些记录会导致无法添加外键
+// 没有区块ID的记录会在调整时删除，不计入
+const orphanTransactionsQuery = `SELECT COUNT(*) FROM transactions t
+    WHERE t.block_id IS NOT NULL
+      AND (NOT EXISTS (SELECT 1 FROM blocks b WHERE b.id = t.block_id)
+        OR NOT EXISTS (SELECT 1 FROM wallets w WHERE w.address = t.from_addr)
+        OR NOT EXISTS (SELECT 1 FROM wallets w WHERE w.address = t.to_addr))`
+
+// bootstrapLegacySchema 数据库中已有 blocks 表却没有任何迁移记录时，说明是迁移机制引入前手工建表的数据库，
+// 直接执行迁移会因表已存在而失败。此时将已有表调整为对应迁移的结构并记录版本：
+// blocks、wallets、transactions 对应迁移 1，side_blocks 对应迁移 2，webhooks 和 webhook_deliveries 对应迁移 3，
+// 尚未建立的表由之后的迁移照常创建。applied 会加入记录的版本
+func bootstrapLegacySchema(conn *sql.Conn, migrations []*Migration, applied map[int64]migrationRecord) error {
+	if len(applied) > 0 {
+		return nil
+	}
+	legacy, err := tableExists(conn, "blocks")
+	if err != nil || !legacy {
+		return err
+	}
+	ctx := context.Background()
+	log.Println("Database has tables created before schema migrations were introduced, bootstrapping migration history")
+
+	// 迁移 3 的两张表需要同时存在，只有一张时无法判断结构，由管理员处理
+	hasWebhooks, err := tableExists(conn, "webhooks")
+	if err != nil {
+		return err
+	}
+	hasDeliveries, err := tableExists(conn, "webhook_deliveries")
+	if err != nil {
+		return err
+	}
+	if hasWebhooks != hasDeliveries {
+		return fmt.Errorf("database has only one of the webhooks and webhook_deliveries tables, drop it or create the other before migrating")
+	}
+	hasSideBlocks, err := tableExists(conn, "side_blocks")
+	if err != nil {
+		return err
+	}
+
+	var orphans int
+	if err := conn.QueryRowContext(ctx, orphanTransactionsQuery).Scan(&orphans); err != nil {
+		return err
+	}
+	if orphans > 0 {
+		return fmt.Errorf("%d transactions reference missing blocks or wallets, delete them before migrating so foreign keys can be added", orphans)
+	}
+
+	changes := legacyChanges
+	if hasSideBlocks {
+		changes = append(changes, legacyChange{always, "ALTER TABLE side_blocks MODIFY nonce BIGINT NOT NULL"})
+	}
+	for _, change := range changes {
+		ok, err := change.applies(conn)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if _, err := conn.ExecContext(ctx, change.stmt); err != nil {
+			return fmt.Errorf("bootstrap legacy schema: %v", err)
+		}
+	}
+
+	baseline := map[int64]bool{1: true, 2: hasSideBlocks, 3: hasWebhooks}
+	for _, m := range migrations {
+		if !baseline[m.Version] {
+			continue
+		}
+		now := time.Now()
+		if _, err := conn.ExecContext(ctx,
+			"INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)", m.Version, m.Name, now); err != nil {
+			return fmt.Errorf("migration %d_%s: record version: %v", m.Version, m.Name, err)
+		}
+		applied[m.Version] = migrationRecord{name: m.Name, appliedAt: now}
+		log.Printf("Recorded existing schema as migration %d_%s", m.Version, m.Name)
+	}
+	return nil
+}
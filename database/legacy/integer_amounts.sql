@@ -0,0 +1,8 @@
+-- 将以完整单位保存的金额 {{table}}.{{column}} 换算为最小单位
+-- 先换算到临时列，再在同一条 ALTER 中替换原列；中途失败时原列保持不变，可以重新执行
+-- 乘数必须是整数字面量，POW() 返回 DOUBLE 会丢失精度
+ALTER TABLE {{table}} ADD COLUMN {{column}}_units DECIMAL(65,0) NULL;
+UPDATE {{table}} SET {{column}}_units = CAST({{column}} AS DECIMAL(65,8)) * {{unit}};
+ALTER TABLE {{table}}
+    DROP COLUMN {{column}},
+    CHANGE {{column}}_units {{column}} {{definition}};
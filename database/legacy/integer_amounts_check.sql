@@ -0,0 +1,6 @@
+-- 统计 {{table}}.{{column}} 中无法精确换算为最小单位的金额：为空、为负、超过 8 位小数或乘以 {{unit}} 后仍有小数部分
+SELECT COUNT(*) FROM {{table}}
+WHERE {{column}} IS NULL
+   OR {{column}} < 0
+   OR CAST({{column}} AS DECIMAL(65,8)) <> {{column}}
+   OR CAST({{column}} AS DECIMAL(65,8)) * {{unit}} <> TRUNCATE(CAST({{column}} AS DECIMAL(65,8)) * {{unit}}, 0);
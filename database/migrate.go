@@ -0,0 +1,321 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 迁移文件命名为 <版本号>_<名称>.up.sql / <版本号>_<名称>.down.sql，版本号递增且不可修改已发布的迁移
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLock 执行迁移时持有的 MySQL 命名锁，避免多个节点同时启动时重复执行迁移
+const migrationLock = "hello_go_schema_migrations"
+
+// migrationLockTimeout 等待其他节点释放迁移锁的最长时间（秒）
+const migrationLockTimeout = 60
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus 迁移的执行状态，AppliedAt 为空表示尚未执行
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+	// Unknown 表示数据库中记录了该版本，但当前程序不包含对应的迁移文件（数据库由更新的版本迁移过）
+	Unknown bool
+}
+
+// Migrations 返回内置的全部迁移，按版本号升序排列
+func Migrations() ([]*Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		base, direction, ok := cutMigrationSuffix(name)
+		if !ok {
+			return nil, fmt.Errorf("migration %s: file name must end with .up.sql or .down.sql", name)
+		}
+		prefix, label, _ := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: file name must start with a positive version number", name)
+		}
+		data, err := migrationFiles.ReadFile(path.Join("migrations", name))
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		} else if m.Name != label {
+			return nil, fmt.Errorf("migration %d: conflicting names %q and %q", version, m.Name, label)
+		}
+		if direction == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s: both up and down files are required", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func cutMigrationSuffix(name string) (base, direction string, ok bool) {
+	if base, ok = strings.CutSuffix(name, ".up.sql"); ok {
+		return base, "up", true
+	}
+	if base, ok = strings.CutSuffix(name, ".down.sql"); ok {
+		return base, "down", true
+	}
+	return "", "", false
+}
+
+// MigrateUp 依次执行尚未执行的迁移，直到版本 target（为 0 时执行全部），返回本次执行的迁移
+// 执行迁移前先将仍以完整单位保存的金额换算为最小单位，decimals 为金额的小数位数
+func MigrateUp(db *sql.DB, target int64, decimals int) ([]*Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []*Migration
+	err = withMigrationLock(db, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+		latest := migrations[len(migrations)-1].Version
+		for version := range applied {
+			if version > latest {
+				return fmt.Errorf("database schema version %d is newer than the latest migration %d known to this binary", version, latest)
+			}
+		}
+		if err := bootstrapLegacySchema(conn, migrations, applied); err != nil {
+			return err
+		}
+		if err := convertLegacyAmounts(conn, decimals); err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			if target > 0 && m.Version > target {
+				break
+			}
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			if err := runMigration(conn, m, m.Up); err != nil {
+				return err
+			}
+			if _, err := conn.ExecContext(context.Background(),
+				"INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)", m.Version, m.Name, time.Now()); err != nil {
+				return fmt.Errorf("migration %d_%s: record version: %v", m.Version, m.Name, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// MigrateDown 按版本号从高到低回滚最近执行的 steps 个迁移，返回本次回滚的迁移
+func MigrateDown(db *sql.DB, steps int) ([]*Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*Migration, len(migrations))
+	for _, m := range migrations {
+		byVersion[m.Version] = m
+	}
+
+	var done []*Migration
+	err = withMigrationLock(db, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+		versions := make([]int64, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for _, version := range versions {
+			if len(done) == steps {
+				break
+			}
+			m, ok := byVersion[version]
+			if !ok {
+				return fmt.Errorf("migration %d is applied but not known to this binary, roll it back with the newer binary", version)
+			}
+			if err := runMigration(conn, m, m.Down); err != nil {
+				return err
+			}
+			if _, err := conn.ExecContext(context.Background(),
+				"DELETE FROM schema_migrations WHERE version = ?", m.Version); err != nil {
+				return fmt.Errorf("migration %d_%s: remove version: %v", m.Version, m.Name, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// GetMigrationStatus 返回内置迁移及数据库中已记录版本的执行状态，按版本号升序排列
+func GetMigrationStatus(db *sql.DB) ([]MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := ensureMigrationTable(conn); err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if record, ok := applied[m.Version]; ok {
+			status.AppliedAt = &record.appliedAt
+			delete(applied, m.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for version, record := range applied {
+		appliedAt := record.appliedAt
+		statuses = append(statuses, MigrationStatus{Version: version, Name: record.name, AppliedAt: &appliedAt, Unknown: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// withMigrationLock 在持有迁移锁的同一个连接上执行 fn
+// 命名锁与连接绑定，因此迁移的所有语句都必须通过该连接执行
+func withMigrationLock(db *sql.DB, fn func(conn *sql.Conn) error) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", migrationLock, migrationLockTimeout).Scan(&locked); err != nil {
+		return err
+	}
+	if !locked.Valid || locked.Int64 != 1 {
+		return fmt.Errorf("timed out waiting for another process to finish migrating the database")
+	}
+	defer conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", migrationLock)
+
+	if err := ensureMigrationTable(conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func ensureMigrationTable(conn *sql.Conn) error {
+	_, err := conn.ExecContext(context.Background(), `CREATE TABLE IF NOT EXISTS schema_migrations (
+              version BIGINT PRIMARY KEY,
+              name VARCHAR(255) NOT NULL,
+              applied_at DATETIME NOT NULL
+          ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`)
+	return err
+}
+
+type migrationRecord struct {
+	name      string
+	appliedAt time.Time
+}
+
+func appliedMigrations(conn *sql.Conn) (map[int64]migrationRecord, error) {
+	rows, err := conn.QueryContext(context.Background(), "SELECT version, name, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]migrationRecord)
+	for rows.Next() {
+		var version int64
+		var record migrationRecord
+		if err := rows.Scan(&version, &record.name, &record.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = record
+	}
+	return applied, rows.Err()
+}
+
+// runMigration 逐条执行迁移脚本中的语句
+// MySQL 的 DDL 会隐式提交，无法放在事务中回滚，迁移中途失败时需要根据错误手工处理
+func runMigration(conn *sql.Conn, m *Migration, script string) error {
+	for _, stmt := range splitStatements(script) {
+		if _, err := conn.ExecContext(context.Background(), stmt); err != nil {
+			return fmt.Errorf("migration %d_%s: %v", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// splitStatements 按行尾的分号拆分脚本并去掉整行注释，驱动默认不允许一次执行多条语句
+func splitStatements(script string) []string {
+	var stmts []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		stmts = append(stmts, rest)
+	}
+	return stmts
+}
@@ -0,0 +1,41 @@
+-- 区块表，index_num 为区块高度
+CREATE TABLE blocks (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    index_num INT NOT NULL,
+    hash VARCHAR(64) NOT NULL,
+    prev_hash VARCHAR(64) NOT NULL,
+    data TEXT,
+    merkle_root VARCHAR(64) NOT NULL,
+    timestamp DATETIME NOT NULL,
+    nonce BIGINT NOT NULL,
+    difficulty INT NOT NULL,
+    UNIQUE KEY uk_blocks_index_num (index_num),
+    UNIQUE KEY uk_blocks_hash (hash)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 钱包表，私钥保存在加密 keystore 中，不写入数据库
+CREATE TABLE wallets (
+    address VARCHAR(42) PRIMARY KEY,
+    balance DECIMAL(65,0) NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 交易记录表，只保存已上链的交易
+CREATE TABLE transactions (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    block_id BIGINT NOT NULL,
+    from_addr VARCHAR(42) NOT NULL,
+    to_addr VARCHAR(42) NOT NULL,
+    amount DECIMAL(65,0) NOT NULL,
+    nonce BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    chain_id BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    signature VARCHAR(132) NOT NULL DEFAULT '',
+    timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_transactions_block_id (block_id),
+    INDEX idx_transactions_from_addr (from_addr),
+    INDEX idx_transactions_to_addr (to_addr),
+    INDEX idx_transactions_timestamp (timestamp),
+    CONSTRAINT fk_transactions_block FOREIGN KEY (block_id) REFERENCES blocks (id),
+    CONSTRAINT fk_transactions_from FOREIGN KEY (from_addr) REFERENCES wallets (address),
+    CONSTRAINT fk_transactions_to FOREIGN KEY (to_addr) REFERENCES wallets (address)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
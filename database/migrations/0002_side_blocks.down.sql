@@ -0,0 +1 @@
+DROP TABLE side_blocks;
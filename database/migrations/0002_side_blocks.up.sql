@@ -0,0 +1,13 @@
+-- 侧链区块表，交易以 JSON 形式随区块保存
+CREATE TABLE side_blocks (
+    hash VARCHAR(64) PRIMARY KEY,
+    index_num INT NOT NULL,
+    prev_hash VARCHAR(64) NOT NULL,
+    data TEXT,
+    merkle_root VARCHAR(64) NOT NULL,
+    timestamp DATETIME NOT NULL,
+    nonce BIGINT NOT NULL,
+    difficulty INT NOT NULL,
+    transactions JSON NOT NULL,
+    INDEX idx_side_blocks_prev_hash (prev_hash)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
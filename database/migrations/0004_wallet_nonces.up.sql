@@ -0,0 +1,5 @@
+-- 钱包的下一个交易序号，等于已上链的转出交易数
+ALTER TABLE wallets ADD COLUMN nonce BIGINT UNSIGNED NOT NULL DEFAULT 0 AFTER balance;
+
+UPDATE wallets w
+SET nonce = (SELECT COUNT(*) FROM transactions t WHERE t.from_addr = w.address);
@@ -0,0 +1,4 @@
+-- 已有 coinbase 交易时外键会阻止删除，需要先处理这些交易
+DELETE FROM wallets WHERE address = '0x0000000000000000000000000000000000000000';
+
+ALTER TABLE transactions DROP COLUMN fee;
@@ -0,0 +1,6 @@
+-- 交易手续费，由转出钱包在转账金额之外支付，归打包区块的矿工所有
+ALTER TABLE transactions ADD COLUMN fee DECIMAL(65,0) NOT NULL DEFAULT 0 AFTER amount;
+
+-- coinbase 交易的转出地址，交易通过外键关联双方钱包
+INSERT IGNORE INTO wallets (address, balance, nonce, created_at)
+VALUES ('0x0000000000000000000000000000000000000000', 0, 0, NOW());
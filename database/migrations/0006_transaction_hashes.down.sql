@@ -0,0 +1,2 @@
+ALTER TABLE transactions DROP INDEX uk_transactions_hash;
+ALTER TABLE transactions DROP COLUMN hash;
@@ -0,0 +1,4 @@
+-- 交易哈希 keccak256(规范 RLP 编码)，以 0x 开头的小写十六进制
+-- MySQL 无法计算 keccak256，已有交易的哈希由节点启动时补全；重放产生的重复交易哈希相同，保留为 NULL
+ALTER TABLE transactions ADD COLUMN hash VARCHAR(66) NULL AFTER block_id;
+ALTER TABLE transactions ADD UNIQUE KEY uk_transactions_hash (hash);
@@ -2,7 +2,6 @@ package database
 
 import (
 	"database/sql"
-	"fmt"
 	"hello-go/config"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -13,14 +12,15 @@ type MySQLDB struct {
 }
 
 func NewMySQLDB(config *config.DatabaseConfig) (*sql.DB, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		config.User, config.Password, config.Host, config.Port, config.DBName)
-
-	db, err := sql.Open("mysql", dsn)
+	db, err := sql.Open("mysql", config.DataSourceName())
 	if err != nil {
 		return nil, err
 	}
 
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	db.SetMaxIdleConns(config.MaxIdleConns)
+	db.SetConnMaxLifetime(config.ConnMaxLifetime)
+
 	if err := db.Ping(); err != nil {
 		return nil, err
 	}
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"database/sql"
+	"hello-go/apperr"
+	"hello-go/models"
+	"sort"
+	"time"
+)
+
+func copyWebhook(hook *models.Webhook) *models.Webhook {
+	h := *hook
+	h.Events = append([]string(nil), hook.Events...)
+	return &h
+}
+
+func copyDelivery(delivery *models.WebhookDelivery) *models.WebhookDelivery {
+	d := *delivery
+	d.Payload = append([]byte(nil), delivery.Payload...)
+	return &d
+}
+
+// 保存回调注册
+func (m *BlockchainMemory) SaveWebhook(hook *models.Webhook) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hook.ID = m.nextWebhookID
+	m.nextWebhookID++
+	m.webhooks[hook.ID] = copyWebhook(hook)
+	return nil
+}
+
+// 根据ID获取回调注册
+func (m *BlockchainMemory) GetWebhook(id int64) (*models.Webhook, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	hook, ok := m.webhooks[id]
+	if !ok {
+		return nil, notFound(apperr.CodeWebhookNotFound, "webhook %d not found", id)
+	}
+	return copyWebhook(hook), nil
+}
+
+// 获取全部回调注册
+func (m *BlockchainMemory) ListWebhooks() ([]*models.Webhook, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	hooks := make([]*models.Webhook, 0, len(m.webhooks))
+	for _, hook := range m.webhooks {
+		hooks = append(hooks, copyWebhook(hook))
+	}
+	sort.Slice(hooks, func(i, j int) bool {
+		return hooks[i].ID < hooks[j].ID
+	})
+	return hooks, nil
+}
+
+// 删除回调注册及其投递记录
+func (m *BlockchainMemory) DeleteWebhook(id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.webhooks[id]; !ok {
+		return notFound(apperr.CodeWebhookNotFound, "webhook %d not found", id)
+	}
+	delete(m.webhooks, id)
+
+	kept := m.deliveries[:0]
+	for _, delivery := range m.deliveries {
+		if delivery.WebhookID != id {
+			kept = append(kept, delivery)
+		}
+	}
+	for i := len(kept); i < len(m.deliveries); i++ {
+		m.deliveries[i] = nil
+	}
+	m.deliveries = kept
+	return nil
+}
+
+// 保存投递记录
+func (m *BlockchainMemory) SaveWebhookDelivery(delivery *models.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delivery.ID = m.nextDeliveryID
+	m.nextDeliveryID++
+	m.deliveries = append(m.deliveries, copyDelivery(delivery))
+	return nil
+}
+
+// 更新投递记录的状态
+func (m *BlockchainMemory) UpdateWebhookDelivery(delivery *models.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, stored := range m.deliveries {
+		if stored.ID == delivery.ID {
+			m.deliveries[i] = copyDelivery(delivery)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+// 获取到达重试时间的待投递记录
+func (m *BlockchainMemory) GetDueWebhookDeliveries(now time.Time, limit int) ([]*models.WebhookDelivery, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var due []*models.WebhookDelivery
+	for _, delivery := range m.deliveries {
+		if delivery.Status == models.DeliveryPending && !delivery.NextAttemptAt.After(now) {
+			due = append(due, copyDelivery(delivery))
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// 按时间倒序分页获取回调的投递记录
+func (m *BlockchainMemory) GetWebhookDeliveries(webhookID int64, limit, offset int) ([]*models.WebhookDelivery, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	deliveries := []*models.WebhookDelivery{}
+	skipped := 0
+	for i := len(m.deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		if m.deliveries[i].WebhookID != webhookID {
+			continue
+		}
+		if skipped < offset {
+			skipped++
+			continue
+		}
+		deliveries = append(deliveries, copyDelivery(m.deliveries[i]))
+	}
+	return deliveries, nil
+}
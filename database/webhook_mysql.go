@@ -0,0 +1,163 @@
+package database
+
+import (
+	"hello-go/apperr"
+	"hello-go/models"
+	"strings"
+	"time"
+)
+
+// 投递记录查询列，与 scanDelivery 的扫描顺序一致
+const deliveryColumns = `id, webhook_id, event, payload, status, attempts, response_code, error, next_attempt_at, created_at, updated_at`
+
+func scanWebhook(row scanner) (*models.Webhook, error) {
+	hook := &models.Webhook{}
+	var events string
+	err := row.Scan(&hook.ID, &hook.URL, &hook.Address, &events, &hook.Secret, &hook.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if events != "" {
+		hook.Events = strings.Split(events, ",")
+	}
+	return hook, nil
+}
+
+func scanDelivery(row scanner) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{}
+	var payload []byte
+	err := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.Event, &payload, &delivery.Status,
+		&delivery.Attempts, &delivery.ResponseCode, &delivery.Error,
+		&delivery.NextAttemptAt, &delivery.CreatedAt, &delivery.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	delivery.Payload = payload
+	return delivery, nil
+}
+
+// 保存回调注册，事件类型以逗号分隔保存
+func (b *BlockchainMySQL) SaveWebhook(hook *models.Webhook) error {
+	query := `INSERT INTO webhooks (url, address, events, secret, created_at) VALUES (?, ?, ?, ?, ?)`
+
+	result, err := b.db.Exec(query, hook.URL, hook.Address, strings.Join(hook.Events, ","), hook.Secret, hook.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	hook.ID = id
+	return nil
+}
+
+// 根据ID获取回调注册
+func (b *BlockchainMySQL) GetWebhook(id int64) (*models.Webhook, error) {
+	query := `SELECT id, url, address, events, secret, created_at FROM webhooks WHERE id = ?`
+	hook, err := scanWebhook(b.db.QueryRow(query, id))
+	return hook, orNotFound(err, apperr.CodeWebhookNotFound, "webhook %d not found", id)
+}
+
+// 获取全部回调注册
+func (b *BlockchainMySQL) ListWebhooks() ([]*models.Webhook, error) {
+	rows, err := b.db.Query(`SELECT id, url, address, events, secret, created_at FROM webhooks ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hooks []*models.Webhook
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+
+	return hooks, rows.Err()
+}
+
+// 删除回调注册，投递记录通过外键级联删除
+func (b *BlockchainMySQL) DeleteWebhook(id int64) error {
+	result, err := b.db.Exec("DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return notFound(apperr.CodeWebhookNotFound, "webhook %d not found", id)
+	}
+	return nil
+}
+
+// 保存投递记录
+func (b *BlockchainMySQL) SaveWebhookDelivery(delivery *models.WebhookDelivery) error {
+	query := `INSERT INTO webhook_deliveries (webhook_id, event, payload, status, attempts, response_code, error, next_attempt_at, created_at, updated_at) 
+              VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := b.db.Exec(query, delivery.WebhookID, delivery.Event, []byte(delivery.Payload), delivery.Status,
+		delivery.Attempts, delivery.ResponseCode, delivery.Error,
+		delivery.NextAttemptAt, delivery.CreatedAt, delivery.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	delivery.ID = id
+	return nil
+}
+
+// 更新投递记录的状态
+func (b *BlockchainMySQL) UpdateWebhookDelivery(delivery *models.WebhookDelivery) error {
+	query := `UPDATE webhook_deliveries SET status = ?, attempts = ?, response_code = ?, error = ?, next_attempt_at = ?, updated_at = ? 
+              WHERE id = ?`
+
+	_, err := b.db.Exec(query, delivery.Status, delivery.Attempts, delivery.ResponseCode, delivery.Error,
+		delivery.NextAttemptAt, delivery.UpdatedAt, delivery.ID)
+	return err
+}
+
+// 获取到达重试时间的待投递记录
+func (b *BlockchainMySQL) GetDueWebhookDeliveries(now time.Time, limit int) ([]*models.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries 
+              WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?`
+	return b.queryDeliveries(query, models.DeliveryPending, now, limit)
+}
+
+// 按时间倒序分页获取回调的投递记录
+func (b *BlockchainMySQL) GetWebhookDeliveries(webhookID int64, limit, offset int) ([]*models.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries 
+              WHERE webhook_id = ? ORDER BY id DESC LIMIT ? OFFSET ?`
+	return b.queryDeliveries(query, webhookID, limit, offset)
+}
+
+func (b *BlockchainMySQL) queryDeliveries(query string, args ...interface{}) ([]*models.WebhookDelivery, error) {
+	rows, err := b.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
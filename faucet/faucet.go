@@ -0,0 +1,360 @@
+package faucet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hello-go/apperr"
+	"hello-go/blockchain"
+	"hello-go/config"
+	"hello-go/models"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// 工作量证明挑战的有效期
+	challengeTTL = 5 * time.Minute
+	// 同时有效的挑战数上限，防止无限申请挑战占用内存
+	maxChallenges = 10000
+	// 清理过期挑战和冷却记录的间隔
+	pruneInterval = time.Minute
+	// 挑战的随机字节数
+	challengeBytes = 16
+)
+
+// Challenge 领取前需要完成的工作量证明挑战
+// 找到 solution 使 sha256(challenge + address + solution) 的十六进制表示以 Difficulty 个 0 开头
+type Challenge struct {
+	Challenge  string    `json:"challenge"`
+	Address    string    `json:"address"`
+	Difficulty int       `json:"difficulty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Faucet 水龙头：使用自行持有的水龙头钱包私钥签名，向申请地址发起链上转账，
+// 按地址和 IP 限制领取间隔，按自然日（UTC）限制发放总额
+type Faucet struct {
+	bc      *blockchain.Blockchain
+	cfg     *config.FaucetConfig
+	address string
+	// 水龙头钱包的私钥只保存在这里，keystore 账户保持锁定，其他人无法通过转账接口代签水龙头的交易
+	key    *ecdsa.PrivateKey
+	amount models.Amount
+	fee    models.Amount
+	// dailyCap 为 0 时不限制每日发放总额
+	dailyCap models.Amount
+
+	mu sync.Mutex
+	// 各地址、各 IP 上次领取的时间，地址使用校验和格式
+	lastByAddress map[string]time.Time
+	lastByIP      map[string]time.Time
+	// day 为当前统计的日期，paidToday 为当天已发放的金额（含手续费）
+	day        string
+	paidToday  models.Amount
+	challenges map[string]*Challenge
+}
+
+// NewFaucet 创建水龙头并解密水龙头钱包的私钥，钱包必须在本节点的 keystore 中
+func NewFaucet(bc *blockchain.Blockchain, cfg *config.FaucetConfig) (*Faucet, error) {
+	// 加载配置时已校验金额格式
+	amount, _ := models.ParseUnits(cfg.Amount, bc.Decimals())
+	fee, _ := models.ParseUnits(cfg.Fee, bc.Decimals())
+	dailyCap, _ := models.ParseUnits(cfg.DailyCap, bc.Decimals())
+
+	address := common.HexToAddress(cfg.Address).Hex()
+	key, err := bc.WalletKey(address, cfg.Passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt faucet wallet %s: %w", address, err)
+	}
+	// 水龙头钱包的交易只能由水龙头签名，经过领取间隔和额度检查
+	bc.ReserveWallet(address)
+
+	return &Faucet{
+		bc:            bc,
+		cfg:           cfg,
+		address:       address,
+		key:           key,
+		amount:        amount,
+		fee:           fee,
+		dailyCap:      dailyCap,
+		lastByAddress: make(map[string]time.Time),
+		lastByIP:      make(map[string]time.Time),
+		challenges:    make(map[string]*Challenge),
+	}, nil
+}
+
+// Info 水龙头的发放规则和当天的剩余额度
+type Info struct {
+	Address         string        `json:"address"`
+	Amount          models.Amount `json:"amount"`
+	Fee             models.Amount `json:"fee"`
+	AddressCooldown string        `json:"address_cooldown"`
+	IPCooldown      string        `json:"ip_cooldown"`
+	// DailyCap 为 0 时不限制每日发放总额，此时不返回 Remaining
+	DailyCap      models.Amount  `json:"daily_cap"`
+	Remaining     *models.Amount `json:"remaining_today,omitempty"`
+	PowDifficulty int            `json:"pow_difficulty"`
+	Balance       models.Amount  `json:"balance"`
+}
+
+// Info 返回水龙头的发放规则、当天剩余额度和钱包余额
+func (f *Faucet) Info() (*Info, error) {
+	balance, err := f.bc.GetBalance(f.address)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &Info{
+		Address:         f.address,
+		Amount:          f.amount,
+		Fee:             f.fee,
+		AddressCooldown: f.cfg.AddressCooldown.String(),
+		IPCooldown:      f.cfg.IPCooldown.String(),
+		DailyCap:        f.dailyCap,
+		PowDifficulty:   f.cfg.PowDifficulty,
+		Balance:         balance,
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rollDay(time.Now())
+	if !f.dailyCap.IsZero() {
+		remaining, ok := f.dailyCap.Sub(f.paidToday)
+		if !ok {
+			remaining = models.Amount{}
+		}
+		info.Remaining = &remaining
+	}
+	return info, nil
+}
+
+// NewChallenge 为地址生成一次性的工作量证明挑战，未启用工作量证明时返回错误
+func (f *Faucet) NewChallenge(address string) (*Challenge, error) {
+	if f.cfg.PowDifficulty == 0 {
+		return nil, apperr.New(apperr.Validation, "", "faucet does not require a proof-of-work challenge")
+	}
+	address, err := f.recipient(address)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, challengeBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	challenge := &Challenge{
+		Challenge:  hex.EncodeToString(buf),
+		Address:    address,
+		Difficulty: f.cfg.PowDifficulty,
+		ExpiresAt:  time.Now().Add(challengeTTL),
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.challenges) >= maxChallenges {
+		f.pruneChallenges(time.Now())
+		if len(f.challenges) >= maxChallenges {
+			return nil, apperr.New(apperr.RateLimited, "", "too many outstanding faucet challenges, try again later")
+		}
+	}
+	f.challenges[challenge.Challenge] = challenge
+	return challenge, nil
+}
+
+// Request 向地址发放一次水龙头金额，ip 为申请者的 IP
+// 启用工作量证明时需提供通过 NewChallenge 获得的挑战及其解，挑战无论成功与否只能使用一次
+func (f *Faucet) Request(address, ip, challenge, solution string) (*blockchain.TransferReceipt, error) {
+	address, err := f.recipient(address)
+	if err != nil {
+		return nil, err
+	}
+
+	// 串行处理领取请求，保证冷却检查、额度统计和水龙头交易序号一致
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	if err := f.checkLimits(address, ip, now); err != nil {
+		return nil, err
+	}
+	if f.cfg.PowDifficulty > 0 {
+		if err := f.verifyChallenge(address, challenge, solution, now); err != nil {
+			return nil, err
+		}
+	}
+
+	receipt, err := f.send(address)
+	if err != nil {
+		return nil, err
+	}
+
+	f.lastByAddress[address] = now
+	f.lastByIP[ip] = now
+	// checkLimits 已确认不会超出每日额度
+	f.paidToday, _ = f.paidToday.Add(f.cost())
+	log.Printf("Faucet sent %s to %s (%s)", f.amount, address, receipt.Hash)
+	return receipt, nil
+}
+
+// Start 启动定期清理过期挑战和冷却记录，返回的函数用于停止
+func (f *Faucet) Start() (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		ticker := time.NewTicker(pruneInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				f.mu.Lock()
+				f.pruneChallenges(now)
+				prune(f.lastByAddress, now.Add(-f.cfg.AddressCooldown))
+				prune(f.lastByIP, now.Add(-f.cfg.IPCooldown))
+				f.mu.Unlock()
+			}
+		}
+	}()
+
+	log.Printf("Faucet started, paying %s per request from %s", f.amount, f.address)
+	return cancel
+}
+
+// recipient 校验并规范化领取地址
+func (f *Faucet) recipient(address string) (string, error) {
+	if !common.IsHexAddress(address) {
+		return "", apperr.New(apperr.Validation, "", "invalid address: %s", address)
+	}
+	address = common.HexToAddress(address).Hex()
+	if address == f.address || strings.EqualFold(address, models.CoinbaseAddress) {
+		return "", apperr.New(apperr.Validation, "", "address %s cannot receive faucet payments", address)
+	}
+	return address, nil
+}
+
+// cost 每次发放从水龙头钱包扣除的金额
+func (f *Faucet) cost() models.Amount {
+	// 溢出时 Transfer 会拒绝水龙头交易，不会按此金额记账
+	cost, _ := f.amount.Add(f.fee)
+	return cost
+}
+
+// checkLimits 检查地址和 IP 的冷却时间以及当天的剩余额度
+func (f *Faucet) checkLimits(address, ip string, now time.Time) error {
+	if last, ok := f.lastByAddress[address]; ok {
+		if wait := last.Add(f.cfg.AddressCooldown).Sub(now); wait > 0 {
+			return apperr.New(apperr.RateLimited, "", "address %s already received faucet funds, try again in %s", address, wait.Round(time.Second))
+		}
+	}
+	if last, ok := f.lastByIP[ip]; ok {
+		if wait := last.Add(f.cfg.IPCooldown).Sub(now); wait > 0 {
+			return apperr.New(apperr.RateLimited, "", "too many faucet requests from %s, try again in %s", ip, wait.Round(time.Second))
+		}
+	}
+
+	f.rollDay(now)
+	if f.dailyCap.IsZero() {
+		return nil
+	}
+	total, ok := f.paidToday.Add(f.cost())
+	if !ok || total.Cmp(f.dailyCap) > 0 {
+		return apperr.New(apperr.RateLimited, apperr.CodeFaucetCapReached, "faucet daily cap of %s reached, try again tomorrow (UTC)", f.cfg.DailyCap)
+	}
+	return nil
+}
+
+// rollDay 进入新的一天（UTC）时重置当天的发放统计
+func (f *Faucet) rollDay(now time.Time) {
+	day := now.UTC().Format("2006-01-02")
+	if day != f.day {
+		f.day = day
+		f.paidToday = models.Amount{}
+	}
+}
+
+// verifyChallenge 校验并消耗工作量证明挑战
+func (f *Faucet) verifyChallenge(address, challenge, solution string, now time.Time) error {
+	if challenge == "" || solution == "" {
+		return invalidChallenge("proof-of-work challenge and solution are required, request one from /api/v1/faucet/challenge")
+	}
+
+	issued, ok := f.challenges[challenge]
+	if !ok {
+		return invalidChallenge("unknown or already used challenge %s", challenge)
+	}
+	delete(f.challenges, challenge)
+
+	if now.After(issued.ExpiresAt) {
+		return invalidChallenge("challenge %s has expired", challenge)
+	}
+	if issued.Address != address {
+		return invalidChallenge("challenge %s was issued for %s", challenge, issued.Address)
+	}
+	if !Solves(issued, solution) {
+		return invalidChallenge("solution does not meet difficulty %d", issued.Difficulty)
+	}
+	return nil
+}
+
+// Solves 判断 solution 是否满足挑战的难度
+func Solves(c *Challenge, solution string) bool {
+	sum := sha256.Sum256([]byte(c.Challenge + c.Address + solution))
+	return strings.HasPrefix(hex.EncodeToString(sum[:]), strings.Repeat("0", c.Difficulty))
+}
+
+func invalidChallenge(format string, args ...interface{}) error {
+	return apperr.New(apperr.Validation, apperr.CodeInvalidChallenge, format, args...)
+}
+
+// send 使用水龙头私钥签名并提交转账
+func (f *Faucet) send(address string) (*blockchain.TransferReceipt, error) {
+	nonce, err := f.bc.GetNonce(f.address, true)
+	if err != nil {
+		return nil, err
+	}
+	tx := &models.Transaction{
+		FromAddr: f.address,
+		ToAddr:   address,
+		Amount:   f.amount,
+		Fee:      f.fee,
+		Nonce:    nonce,
+		ChainID:  f.bc.ChainID(),
+	}
+
+	if err := blockchain.SignTransaction(tx, f.key); err != nil {
+		return nil, fmt.Errorf("sign faucet transaction: %w", err)
+	}
+
+	receipt, err := f.bc.Transfer(tx)
+	if apperr.KindOf(err) == apperr.InsufficientFunds {
+		return nil, apperr.New(apperr.Unavailable, "", "faucet wallet %s has insufficient funds", f.address)
+	}
+	return receipt, err
+}
+
+// pruneChallenges 删除过期的挑战
+func (f *Faucet) pruneChallenges(now time.Time) {
+	for key, challenge := range f.challenges {
+		if now.After(challenge.ExpiresAt) {
+			delete(f.challenges, key)
+		}
+	}
+}
+
+// prune 删除早于 before 的记录，这些地址或 IP 已经可以再次领取
+func prune(last map[string]time.Time, before time.Time) {
+	for key, t := range last {
+		if t.Before(before) {
+			delete(last, key)
+		}
+	}
+}
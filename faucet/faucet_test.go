@@ -0,0 +1,390 @@
+package faucet
+
+import (
+	"fmt"
+	"hello-go/apperr"
+	"hello-go/blockchain"
+	"hello-go/config"
+	"hello-go/database"
+	"hello-go/models"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const testPassphrase = "faucet-passphrase"
+
+// newTestFaucet 创建使用内存数据库的水龙头，金额没有小数位，水龙头钱包余额为 balance
+// cfg 中的地址和口令由这里填写
+func newTestFaucet(t *testing.T, cfg config.FaucetConfig, balance uint64) (*Faucet, *database.BlockchainMemory) {
+	t.Helper()
+
+	store := database.NewBlockchainMemory()
+	ks := keystore.NewKeyStore(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP)
+	chain := &config.ChainConfig{ChainID: 1337, GenesisTime: time.Unix(0, 0), Decimals: 0, BlockReward: "0"}
+	mining := &config.MiningConfig{InitialDifficulty: 1, MinDifficulty: 1, MaxDifficulty: 1, Workers: 1, Timeout: 10 * time.Second}
+	bc := blockchain.NewBlockchain(store, ks, chain, mining)
+	if _, err := bc.CreateGenesisBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	account, err := ks.NewAccount(testPassphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveWallet(&models.Wallet{Address: account.Address.Hex(), Balance: models.NewAmount(balance)}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Address, cfg.Passphrase = account.Address.Hex(), testPassphrase
+	if cfg.Fee == "" {
+		cfg.Fee = "0"
+	}
+	if cfg.DailyCap == "" {
+		cfg.DailyCap = "0"
+	}
+	f, err := NewFaucet(bc, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, store
+}
+
+// recipients 创建 n 个空钱包作为领取地址，地址使用校验和格式
+func recipients(t *testing.T, store *database.BlockchainMemory, n int) []string {
+	t.Helper()
+
+	addresses := make([]string, n)
+	for i := range addresses {
+		addresses[i] = common.HexToAddress(fmt.Sprintf("0x%040x", 0xb0+i)).Hex()
+		if err := store.SaveWallet(&models.Wallet{Address: addresses[i]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return addresses
+}
+
+// elapse 将领取记录提前 d，模拟时间经过
+func (f *Faucet) elapse(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for key, last := range f.lastByAddress {
+		f.lastByAddress[key] = last.Add(-d)
+	}
+	for key, last := range f.lastByIP {
+		f.lastByIP[key] = last.Add(-d)
+	}
+}
+
+func TestRequestCooldowns(t *testing.T) {
+	type step struct {
+		// recipient 领取地址的序号
+		recipient int
+		ip        string
+		// elapsed 本次领取前经过的时间
+		elapsed time.Duration
+		wantErr bool
+	}
+
+	tests := []struct {
+		name            string
+		addressCooldown time.Duration
+		ipCooldown      time.Duration
+		steps           []step
+	}{
+		{
+			name:            "same address from another IP",
+			addressCooldown: time.Hour, ipCooldown: time.Minute,
+			steps: []step{{0, "10.0.0.1", 0, false}, {0, "10.0.0.2", 0, true}},
+		},
+		{
+			name:            "same IP for another address",
+			addressCooldown: time.Minute, ipCooldown: time.Hour,
+			steps: []step{{0, "10.0.0.1", 0, false}, {1, "10.0.0.1", 0, true}},
+		},
+		{
+			name:            "different address and IP",
+			addressCooldown: time.Hour, ipCooldown: time.Hour,
+			steps: []step{{0, "10.0.0.1", 0, false}, {1, "10.0.0.2", 0, false}},
+		},
+		{
+			name:            "address cooldown expires",
+			addressCooldown: time.Hour, ipCooldown: time.Minute,
+			steps: []step{
+				{0, "10.0.0.1", 0, false},
+				{0, "10.0.0.2", 59 * time.Minute, true},
+				{0, "10.0.0.3", 2 * time.Minute, false},
+			},
+		},
+		{
+			name:            "IP cooldown expires",
+			addressCooldown: time.Minute, ipCooldown: time.Hour,
+			steps: []step{
+				{0, "10.0.0.1", 0, false},
+				{1, "10.0.0.1", 30 * time.Minute, true},
+				{1, "10.0.0.1", 31 * time.Minute, false},
+			},
+		},
+		{
+			name: "no cooldown",
+			steps: []step{
+				{0, "10.0.0.1", 0, false},
+				{0, "10.0.0.1", 0, false},
+			},
+		},
+		{
+			// 被拒绝的领取不记录领取时间，不会延长冷却
+			name:            "rejected request does not restart cooldown",
+			addressCooldown: time.Hour, ipCooldown: time.Hour,
+			steps: []step{
+				{0, "10.0.0.1", 0, false},
+				{1, "10.0.0.1", 0, true},
+				{1, "10.0.0.2", 0, false},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, store := newTestFaucet(t, config.FaucetConfig{
+				Amount:          "10",
+				AddressCooldown: tt.addressCooldown,
+				IPCooldown:      tt.ipCooldown,
+			}, 1000)
+			addresses := recipients(t, store, 2)
+
+			for i, s := range tt.steps {
+				f.elapse(s.elapsed)
+				_, err := f.Request(addresses[s.recipient], s.ip, "", "")
+				if (err != nil) != s.wantErr {
+					t.Fatalf("step %d: Request error = %v, wantErr %v", i, err, s.wantErr)
+				}
+				if err != nil && apperr.KindOf(err) != apperr.RateLimited {
+					t.Errorf("step %d: error kind = %v, want rate limited", i, apperr.KindOf(err))
+				}
+			}
+		})
+	}
+}
+
+func TestRequestDailyCap(t *testing.T) {
+	tests := []struct {
+		name     string
+		dailyCap string
+		// wantPaid 当天能成功领取的次数，每次发放 10 加手续费 1
+		wantPaid      int
+		wantRemaining string
+	}{
+		{name: "unlimited", dailyCap: "0", wantPaid: 5},
+		{name: "cap fits two payments exactly", dailyCap: "22", wantPaid: 2, wantRemaining: "0"},
+		{name: "fee counts toward cap", dailyCap: "21", wantPaid: 1, wantRemaining: "10"},
+		{name: "cap below one payment", dailyCap: "10", wantPaid: 0, wantRemaining: "10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, store := newTestFaucet(t, config.FaucetConfig{Amount: "10", Fee: "1", DailyCap: tt.dailyCap}, 1000)
+			addresses := recipients(t, store, 5)
+
+			paid := 0
+			for i, address := range addresses {
+				_, err := f.Request(address, "10.0.0."+strconv.Itoa(i), "", "")
+				if err == nil {
+					paid++
+					continue
+				}
+				if apperr.CodeOf(err) != apperr.CodeFaucetCapReached {
+					t.Fatalf("request %d: error = %v, want %s", i, err, apperr.CodeFaucetCapReached)
+				}
+			}
+			if paid != tt.wantPaid {
+				t.Errorf("%d requests paid, want %d", paid, tt.wantPaid)
+			}
+
+			info, err := f.Info()
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch {
+			case tt.wantRemaining == "" && info.Remaining != nil:
+				t.Errorf("remaining = %s, want none for unlimited faucet", info.Remaining)
+			case tt.wantRemaining != "" && (info.Remaining == nil || info.Remaining.String() != tt.wantRemaining):
+				t.Errorf("remaining = %v, want %s", info.Remaining, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestRequestDailyCapResetsNextDay(t *testing.T) {
+	f, store := newTestFaucet(t, config.FaucetConfig{Amount: "10", DailyCap: "10"}, 1000)
+	addresses := recipients(t, store, 2)
+
+	if _, err := f.Request(addresses[0], "10.0.0.1", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Request(addresses[1], "10.0.0.2", "", ""); apperr.CodeOf(err) != apperr.CodeFaucetCapReached {
+		t.Fatalf("second request error = %v, want %s", err, apperr.CodeFaucetCapReached)
+	}
+
+	// 模拟进入新的一天（UTC）
+	f.mu.Lock()
+	f.day = time.Now().UTC().AddDate(0, 0, -1).Format("2006-01-02")
+	f.mu.Unlock()
+	if _, err := f.Request(addresses[1], "10.0.0.2", "", ""); err != nil {
+		t.Fatalf("request on the next day: %v", err)
+	}
+}
+
+// TestRequestFailedTransferNotCounted 水龙头余额不足时转账失败，不计入当天额度，也不开始冷却
+func TestRequestFailedTransferNotCounted(t *testing.T) {
+	f, store := newTestFaucet(t, config.FaucetConfig{Amount: "10", AddressCooldown: time.Hour, IPCooldown: time.Hour, DailyCap: "100"}, 5)
+	address := recipients(t, store, 1)[0]
+
+	if _, err := f.Request(address, "10.0.0.1", "", ""); apperr.KindOf(err) != apperr.Unavailable {
+		t.Fatalf("Request error = %v, want unavailable", err)
+	}
+	if !f.paidToday.IsZero() || len(f.lastByAddress) != 0 || len(f.lastByIP) != 0 {
+		t.Errorf("failed request recorded: paid today %s, %d addresses, %d IPs", f.paidToday, len(f.lastByAddress), len(f.lastByIP))
+	}
+}
+
+// solve 找到满足挑战难度的解，want 为 false 时找一个不满足的解
+func solve(c *Challenge, want bool) string {
+	for i := 0; ; i++ {
+		solution := strconv.Itoa(i)
+		if Solves(c, solution) == want {
+			return solution
+		}
+	}
+}
+
+func TestRequestChallenge(t *testing.T) {
+	tests := []struct {
+		name string
+		// answer 根据为 recipient 签发的挑战返回提交的挑战和解
+		answer   func(f *Faucet, c *Challenge) (challenge, solution string)
+		wantCode string
+		// wantKept 请求被拒绝后挑战仍然有效，只有缺少挑战或解时不消耗挑战
+		wantKept bool
+	}{
+		{
+			name:   "valid solution",
+			answer: func(_ *Faucet, c *Challenge) (string, string) { return c.Challenge, solve(c, true) },
+		},
+		{
+			name:     "missing solution",
+			answer:   func(_ *Faucet, c *Challenge) (string, string) { return c.Challenge, "" },
+			wantCode: apperr.CodeInvalidChallenge,
+			wantKept: true,
+		},
+		{
+			name: "unknown challenge",
+			answer: func(_ *Faucet, c *Challenge) (string, string) {
+				unknown := strings.Map(func(r rune) rune {
+					if r == '0' {
+						return '1'
+					}
+					return '0'
+				}, c.Challenge)
+				return unknown, solve(c, true)
+			},
+			wantCode: apperr.CodeInvalidChallenge,
+		},
+		{
+			name:     "wrong solution",
+			answer:   func(_ *Faucet, c *Challenge) (string, string) { return c.Challenge, solve(c, false) },
+			wantCode: apperr.CodeInvalidChallenge,
+		},
+		{
+			name: "expired challenge",
+			answer: func(f *Faucet, c *Challenge) (string, string) {
+				f.challenges[c.Challenge].ExpiresAt = time.Now().Add(-time.Second)
+				return c.Challenge, solve(c, true)
+			},
+			wantCode: apperr.CodeInvalidChallenge,
+		},
+		{
+			name: "challenge issued for another address",
+			answer: func(f *Faucet, _ *Challenge) (string, string) {
+				other, err := f.NewChallenge(fmt.Sprintf("0x%040x", 0xc0))
+				if err != nil {
+					panic(err)
+				}
+				return other.Challenge, solve(other, true)
+			},
+			wantCode: apperr.CodeInvalidChallenge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, store := newTestFaucet(t, config.FaucetConfig{Amount: "10", PowDifficulty: 1}, 1000)
+			recipient := recipients(t, store, 1)[0]
+
+			c, err := f.NewChallenge(recipient)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Address != recipient || c.Difficulty != 1 || len(c.Challenge) != 2*challengeBytes {
+				t.Fatalf("challenge = %+v", c)
+			}
+
+			challenge, solution := tt.answer(f, c)
+			_, err = f.Request(recipient, "10.0.0.1", challenge, solution)
+			switch {
+			case tt.wantCode == "" && err != nil:
+				t.Fatalf("Request: %v", err)
+			case tt.wantCode != "" && apperr.CodeOf(err) != tt.wantCode:
+				t.Fatalf("Request error = %v, want code %q", err, tt.wantCode)
+			}
+
+			// 校验过的挑战无论成功与否都已被消耗
+			if _, ok := f.challenges[challenge]; ok != tt.wantKept {
+				t.Errorf("challenge %s still valid = %v, want %v", challenge, ok, tt.wantKept)
+			}
+		})
+	}
+}
+
+func TestChallengeSurvivesRateLimitedRequest(t *testing.T) {
+	f, store := newTestFaucet(t, config.FaucetConfig{Amount: "10", IPCooldown: time.Hour, PowDifficulty: 1}, 1000)
+	addresses := recipients(t, store, 2)
+
+	first, err := f.NewChallenge(addresses[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Request(addresses[0], "10.0.0.1", first.Challenge, solve(first, true)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 冷却检查在校验挑战之前，因冷却被拒绝的请求不消耗挑战
+	second, err := f.NewChallenge(addresses[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	solution := solve(second, true)
+	if _, err := f.Request(addresses[1], "10.0.0.1", second.Challenge, solution); apperr.KindOf(err) != apperr.RateLimited {
+		t.Fatalf("Request error = %v, want rate limited", err)
+	}
+	if _, err := f.Request(addresses[1], "10.0.0.2", second.Challenge, solution); err != nil {
+		t.Fatalf("retry from another IP: %v", err)
+	}
+}
+
+func TestNewChallengeRequiresPowDifficulty(t *testing.T) {
+	f, store := newTestFaucet(t, config.FaucetConfig{Amount: "10"}, 1000)
+	recipient := recipients(t, store, 1)[0]
+
+	if _, err := f.NewChallenge(recipient); apperr.KindOf(err) != apperr.Validation {
+		t.Errorf("NewChallenge error = %v, want validation error", err)
+	}
+	if _, err := f.Request(recipient, "10.0.0.1", "", ""); err != nil {
+		t.Errorf("Request without challenge: %v", err)
+	}
+}
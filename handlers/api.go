@@ -1,211 +1,454 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"hello-go/apperr"
 	"hello-go/blockchain"
 	"hello-go/config"
-	"hello-go/database"
-	"log"
+	"hello-go/faucet"
+	"hello-go/models"
+	"hello-go/webhook"
+	"mime"
 	"net/http"
 	"strconv"
-	"sync"
+	"strings"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/gin-gonic/gin"
 )
 
-var (
-	bcInstance *blockchain.Blockchain
-	once       sync.Once
-)
+// Handler REST 接口处理器，依赖由调用方创建后注入，整个进程共用同一个区块链实例和数据访问层
+type Handler struct {
+	bc       *blockchain.Blockchain
+	webhooks webhook.Store
+	keyStore *config.KeyStoreConfig
+	mining   *config.MiningConfig
+	// faucet 为 nil 时未启用水龙头
+	faucet *faucet.Faucet
+}
+
+func NewHandler(bc *blockchain.Blockchain, webhooks webhook.Store, keyStore *config.KeyStoreConfig, mining *config.MiningConfig, faucet *faucet.Faucet) *Handler {
+	return &Handler{
+		bc:       bc,
+		webhooks: webhooks,
+		keyStore: keyStore,
+		mining:   mining,
+		faucet:   faucet,
+	}
+}
 
 // Response 统一响应结构
 type Response struct {
-	Success   bool        `json:"success"`
-	Message   string      `json:"message,omitempty"`
-	Data      interface{} `json:"data,omitempty"`
-	Error     string      `json:"error,omitempty"`
-	Timestamp time.Time   `json:"timestamp"`
+	Success bool        `json:"success"`
+	Message string      `json:"message,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+	Error   string      `json:"error,omitempty"`
+	// ErrorCode 机器可读的错误码，失败时返回，取值保持稳定
+	ErrorCode string    `json:"error_code,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
 }
 
-// getBlockchainInstance 获取区块链实例（单例模式）
-func getBlockchainInstance() *blockchain.Blockchain {
-	once.Do(func() {
-		// 初始化数据库连接
-		dbConfig := config.GetDBConfig()
-		db, err := database.NewMySQLDB(dbConfig)
-		if err != nil {
-			log.Fatal("Failed to connect to database:", err)
-		}
-
-		// 初始化区块链数据访问层
-		blockchainDB := database.NewBlockchainMySQL(db)
-
-		// 创建区块链实例
-		bcInstance = blockchain.NewBlockchain(blockchainDB)
-
-		// 检查是否有创世区块，如果没有则创建
-		latestBlock, err := blockchainDB.GetLatestBlock()
-		if err != nil {
-			// 创建创世区块
-			genesis, err := bcInstance.CreateGenesisBlock()
-			if err != nil {
-				log.Fatal("Failed to create genesis block:", err)
-			}
-			log.Printf("Created genesis block: %s", genesis.Hash)
-		} else {
-			log.Printf("Latest block: Index=%d, Hash=%s", latestBlock.Index, latestBlock.Hash)
-		}
+// RegisterRoutes 注册 /api/v1 下的 REST 接口
+func (h *Handler) RegisterRoutes(r *gin.Engine) {
+	api := r.Group("/api/v1", requireJSON)
+
+	// 钱包相关接口
+	api.POST("/wallet", h.CreateWallet)
+	api.GET("/wallet/:address", h.GetBalance)
+	api.GET("/wallet/:address/nonce", h.GetNonce)
+	api.POST("/wallet/:address/unlock", h.UnlockWallet)
+	api.POST("/wallet/:address/lock", h.LockWallet)
+	api.POST("/wallet/:address/export", h.ExportWallet)
+	api.POST("/transfer", h.Transfer)
+
+	// 水龙头接口
+	api.GET("/faucet", h.GetFaucetInfo)
+	api.GET("/faucet/challenge", h.GetFaucetChallenge)
+	api.POST("/faucet", h.RequestFaucet)
+
+	// 交易池与挖矿接口
+	api.GET("/mempool", h.GetMempool)
+	api.POST("/mine", h.MineBlock)
+	api.GET("/mining/stats", h.GetMinerStats)
+
+	// 交易记录相关接口
+	api.GET("/transactions", h.GetAllTransactions)
+	api.GET("/transactions/history/:address", h.GetTransactionHistory)
+	api.GET("/transactions/block/:block_id", h.GetTransactionsByBlock)
+	api.GET("/transactions/proof/:id", h.GetTransactionProof)
+	api.GET("/transactions/:hash", h.GetTransactionByHash)
+
+	// 回调通知接口
+	api.POST("/webhooks", h.CreateWebhook)
+	api.GET("/webhooks", h.ListWebhooks)
+	api.GET("/webhooks/:id", h.GetWebhook)
+	api.DELETE("/webhooks/:id", h.DeleteWebhook)
+	api.GET("/webhooks/:id/deliveries", h.GetWebhookDeliveries)
+
+	// 区块浏览接口
+	api.GET("/blocks", h.ListBlocks)
+	api.GET("/blocks/latest", h.GetLatestBlock)
+	api.GET("/blocks/:index", h.GetBlockByIndex)
+	api.GET("/blocks/hash/:hash", h.GetBlockByHash)
+
+	// 区块链信息接口
+	api.GET("/blockchain", h.GetBlockchainInfo)
+	api.GET("/blockchain/validate", h.ValidateBlockchain)
+
+	// 健康检查接口
+	api.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "ok",
+			"message": "Blockchain API is running",
+		})
 	})
-	return bcInstance
 }
 
-// sendResponse 发送统一格式的响应
-func sendResponse(c *gin.Context, success bool, message string, data interface{}, errMsg string) {
-	response := Response{
-		Success:   success,
+// requireJSON 写操作的请求体必须是 JSON
+// 浏览器跨站提交表单或以 text/plain 发送的请求不需要 CORS 预检，如果接受这类请求，
+// 任何网页都可以借用已解锁钱包的服务端签名发起转账；要求 application/json 后跨站请求必须先通过预检
+func requireJSON(c *gin.Context) {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return
+	}
+	contentType := c.GetHeader("Content-Type")
+	if contentType == "" && c.Request.ContentLength == 0 {
+		return
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || mediaType != "application/json" {
+		sendError(c, "", apperr.New(apperr.Validation, apperr.CodeUnsupportedContentType,
+			"Content-Type must be application/json, got %q", contentType))
+		c.Abort()
+	}
+}
+
+// sendResponse 发送成功响应，错误响应使用 sendError
+func sendResponse(c *gin.Context, message string, data interface{}) {
+	c.JSON(http.StatusOK, Response{
+		Success:   true,
 		Message:   message,
 		Data:      data,
-		Error:     errMsg,
 		Timestamp: time.Now(),
-	}
+	})
+}
 
-	if success {
-		c.JSON(http.StatusOK, response)
-	} else {
-		c.JSON(http.StatusInternalServerError, response)
+// sendError 按错误类别返回对应的 HTTP 状态码和 error_code，message 不为空时作为错误信息的前缀
+func sendError(c *gin.Context, message string, err error) {
+	errMsg := err.Error()
+	if message != "" {
+		errMsg = message + ": " + errMsg
 	}
+
+	c.JSON(apperr.HTTPStatus(err), Response{
+		Success:   false,
+		Error:     errMsg,
+		ErrorCode: apperr.CodeOf(err),
+		Timestamp: time.Now(),
+	})
+}
+
+// badRequest 请求参数无效
+func badRequest(format string, args ...interface{}) error {
+	return apperr.New(apperr.Validation, apperr.CodeInvalidRequest, format, args...)
 }
 
 // CreateWallet 创建钱包
-func CreateWallet(c *gin.Context) {
-	bc := getBlockchainInstance()
+func (h *Handler) CreateWallet(c *gin.Context) {
+	var walletRequest struct {
+		Passphrase string `json:"passphrase" binding:"required,min=8"`
+	}
+
+	if err := c.ShouldBindJSON(&walletRequest); err != nil {
+		sendError(c, "", badRequest("Invalid request data: %v", err))
+		return
+	}
 
-	wallet, err := bc.CreateNewWallet()
+	wallet, err := h.bc.CreateNewWallet(walletRequest.Passphrase)
 	if err != nil {
-		sendResponse(c, false, "", nil, "Failed to create wallet: "+err.Error())
+		sendError(c, "Failed to create wallet", err)
 		return
 	}
 
 	walletData := gin.H{
-		"address":     wallet.Address,
-		"private_key": wallet.PrivateKey,
-		"balance":     wallet.Balance,
+		"address": wallet.Address,
+		"balance": wallet.Balance,
+	}
+
+	sendResponse(c, "Wallet created successfully", walletData)
+}
+
+// UnlockWallet 解锁钱包，解锁期间服务端可代为签名转账
+func (h *Handler) UnlockWallet(c *gin.Context) {
+	var unlockRequest struct {
+		Passphrase string `json:"passphrase" binding:"required"`
+		Timeout    int    `json:"timeout" binding:"gte=0"`
+	}
+
+	if err := c.ShouldBindJSON(&unlockRequest); err != nil {
+		sendError(c, "", badRequest("Invalid request data: %v", err))
+		return
+	}
+
+	timeout := time.Duration(unlockRequest.Timeout) * time.Second
+	if timeout == 0 {
+		timeout = h.keyStore.DefaultUnlockTimeout
+	}
+	if timeout > h.keyStore.MaxUnlockTimeout {
+		timeout = h.keyStore.MaxUnlockTimeout
 	}
 
-	sendResponse(c, true, "Wallet created successfully", walletData, "")
+	address := c.Param("address")
+
+	if err := h.bc.UnlockWallet(address, unlockRequest.Passphrase, timeout); err != nil {
+		sendError(c, "Failed to unlock wallet", err)
+		return
+	}
+
+	unlockData := gin.H{
+		"address":      address,
+		"unlocked":     true,
+		"expires_at":   time.Now().Add(timeout),
+		"timeout_secs": int(timeout.Seconds()),
+	}
+
+	sendResponse(c, "Wallet unlocked successfully", unlockData)
+}
+
+// LockWallet 锁定钱包
+func (h *Handler) LockWallet(c *gin.Context) {
+	address := c.Param("address")
+
+	if err := h.bc.LockWallet(address); err != nil {
+		sendError(c, "Failed to lock wallet", err)
+		return
+	}
+
+	lockData := gin.H{
+		"address":  address,
+		"unlocked": false,
+	}
+
+	sendResponse(c, "Wallet locked successfully", lockData)
+}
+
+// ExportWallet 导出加密的 keystore 文件
+func (h *Handler) ExportWallet(c *gin.Context) {
+	var exportRequest struct {
+		Passphrase    string `json:"passphrase" binding:"required"`
+		NewPassphrase string `json:"new_passphrase"`
+	}
+
+	if err := c.ShouldBindJSON(&exportRequest); err != nil {
+		sendError(c, "", badRequest("Invalid request data: %v", err))
+		return
+	}
+
+	// 未指定新口令时沿用原口令加密导出文件
+	newPassphrase := exportRequest.NewPassphrase
+	if newPassphrase == "" {
+		newPassphrase = exportRequest.Passphrase
+	}
+
+	address := c.Param("address")
+
+	keyJSON, err := h.bc.ExportWallet(address, exportRequest.Passphrase, newPassphrase)
+	if err != nil {
+		sendError(c, "Failed to export wallet", err)
+		return
+	}
+
+	exportData := gin.H{
+		"address":  address,
+		"keystore": json.RawMessage(keyJSON),
+	}
+
+	sendResponse(c, "Wallet exported successfully", exportData)
 }
 
 // GetBalance 查询余额
-func GetBalance(c *gin.Context) {
-	bc := getBlockchainInstance()
+func (h *Handler) GetBalance(c *gin.Context) {
 	address := c.Param("address")
 
 	if address == "" {
-		sendResponse(c, false, "", nil, "Address parameter is required")
+		sendError(c, "", badRequest("Address parameter is required"))
 		return
 	}
 
-	balance, err := bc.GetBalance(address)
+	balance, err := h.bc.GetBalance(address)
 	if err != nil {
-		sendResponse(c, false, "", nil, "Failed to get balance: "+err.Error())
+		sendError(c, "Failed to get balance", err)
 		return
 	}
 
 	balanceData := gin.H{
-		"address": address,
-		"balance": balance,
+		"address":   address,
+		"balance":   balance,
+		"decimals":  h.bc.Decimals(),
+		"formatted": balance.FormatUnits(h.bc.Decimals()),
+	}
+
+	sendResponse(c, "Balance retrieved successfully", balanceData)
+}
+
+// GetNonce 查询钱包的交易序号
+func (h *Handler) GetNonce(c *gin.Context) {
+	address := c.Param("address")
+
+	nonce, err := h.bc.GetNonce(address, false)
+	if err != nil {
+		sendError(c, "Failed to get nonce", err)
+		return
+	}
+	pendingNonce, err := h.bc.GetNonce(address, true)
+	if err != nil {
+		sendError(c, "Failed to get nonce", err)
+		return
 	}
 
-	sendResponse(c, true, "Balance retrieved successfully", balanceData, "")
+	nonceData := gin.H{
+		"address":       address,
+		"nonce":         nonce,
+		"pending_nonce": pendingNonce,
+	}
+
+	sendResponse(c, "Nonce retrieved successfully", nonceData)
 }
 
 // Transfer 转账
-func Transfer(c *gin.Context) {
+func (h *Handler) Transfer(c *gin.Context) {
 	var transferRequest struct {
-		FromAddress string  `json:"from_address" binding:"required"`
-		ToAddress   string  `json:"to_address" binding:"required"`
-		Amount      float64 `json:"amount" binding:"required,gt=0"`
+		FromAddress string `json:"from_address" binding:"required"`
+		ToAddress   string `json:"to_address" binding:"required"`
+		// Amount 以最小单位表示的金额，十进制字符串
+		Amount models.Amount `json:"amount"`
+		// Fee 以最小单位表示的手续费，可以省略
+		Fee       models.Amount `json:"fee"`
+		Nonce     uint64        `json:"nonce"`
+		ChainID   uint64        `json:"chain_id"`
+		Signature string        `json:"signature"`
 	}
 
 	if err := c.ShouldBindJSON(&transferRequest); err != nil {
-		sendResponse(c, false, "", nil, "Invalid request data: "+err.Error())
+		sendError(c, "", badRequest("Invalid request data: %v", err))
 		return
 	}
+	if transferRequest.Amount.IsZero() {
+		sendError(c, "", badRequest("Invalid request data: amount must be greater than 0"))
+		return
+	}
+
+	tx := &models.Transaction{
+		FromAddr:  transferRequest.FromAddress,
+		ToAddr:    transferRequest.ToAddress,
+		Amount:    transferRequest.Amount,
+		Fee:       transferRequest.Fee,
+		Nonce:     transferRequest.Nonce,
+		ChainID:   transferRequest.ChainID,
+		Signature: transferRequest.Signature,
+	}
 
-	bc := getBlockchainInstance()
+	// 未携带签名时使用已解锁的 keystore 账户代为签名
+	if tx.Signature == "" {
+		if tx.ChainID == 0 {
+			tx.ChainID = h.bc.ChainID()
+		}
+		if err := h.bc.SignWithWallet(tx); err != nil {
+			sendError(c, "Transfer failed", err)
+			return
+		}
+	}
 
-	// 执行转账
-	err := bc.Transfer(transferRequest.FromAddress, transferRequest.ToAddress, transferRequest.Amount)
+	// 校验签名后提交到交易池
+	receipt, err := h.bc.Transfer(tx)
 	if err != nil {
-		sendResponse(c, false, "", nil, "Transfer failed: "+err.Error())
+		sendError(c, "Transfer failed", err)
 		return
 	}
 
-	transferData := gin.H{
-		"from_address": transferRequest.FromAddress,
-		"to_address":   transferRequest.ToAddress,
-		"amount":       transferRequest.Amount,
-		"timestamp":    time.Now(),
+	sendResponse(c, "Transfer submitted to mempool", receipt)
+}
+
+// GetMempool 获取交易池中等待打包的交易
+func (h *Handler) GetMempool(c *gin.Context) {
+	pending := h.bc.PendingTransactions()
+
+	mempoolData := gin.H{
+		"transactions": pending,
+		"count":        len(pending),
 	}
 
-	sendResponse(c, true, "Transfer completed successfully", transferData, "")
+	sendResponse(c, "Mempool retrieved successfully", mempoolData)
 }
 
-// GetTransactionHistory 获取交易历史
-func GetTransactionHistory(c *gin.Context) {
-	address := c.Param("address")
-	if address == "" {
-		sendResponse(c, false, "", nil, "Address parameter is required")
-		return
+// MineBlock 立即将交易池中的交易打包出块
+// 可以通过 timeout 查询参数（秒）指定挖矿超时时间，客户端断开连接时挖矿也会停止
+func (h *Handler) MineBlock(c *gin.Context) {
+	timeout := h.mining.Timeout
+	if v := c.Query("timeout"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds <= 0 {
+			sendError(c, "", badRequest("Invalid timeout"))
+			return
+		}
+		timeout = time.Duration(seconds) * time.Second
 	}
 
-	// 获取数据库连接
-	dbConfig := config.GetDBConfig()
-	db, err := database.NewMySQLDB(dbConfig)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	block, transactions, err := h.bc.MinePendingTransactions(ctx)
 	if err != nil {
-		sendResponse(c, false, "", nil, "Failed to connect to database: "+err.Error())
+		sendError(c, "Failed to mine block", err)
+		return
+	}
+
+	mineData := gin.H{
+		"block":        block,
+		"transactions": transactions,
+		"count":        len(transactions),
+		"hash_rate":    h.bc.MinerStats().LastHashRate,
+	}
+
+	sendResponse(c, "Block mined successfully", mineData)
+}
+
+// GetMinerStats 获取挖矿统计信息
+func (h *Handler) GetMinerStats(c *gin.Context) {
+	sendResponse(c, "Miner stats retrieved successfully", h.bc.MinerStats())
+}
+
+// GetTransactionHistory 获取交易历史
+func (h *Handler) GetTransactionHistory(c *gin.Context) {
+	address := c.Param("address")
+	if address == "" {
+		sendError(c, "", badRequest("Address parameter is required"))
 		return
 	}
-	defer db.Close()
 
 	// 查询该地址的所有交易记录
-	query := `
-		SELECT id, from_addr, to_addr, amount, timestamp 
-		FROM transactions 
-		WHERE from_addr = ? OR to_addr = ? 
-		ORDER BY timestamp DESC
-	`
-
-	rows, err := db.Query(query, address, address)
+	txs, err := h.bc.GetTransactionsByAddress(address)
 	if err != nil {
-		sendResponse(c, false, "", nil, "Failed to get transaction history: "+err.Error())
+		sendError(c, "Failed to get transaction history", err)
 		return
 	}
-	defer rows.Close()
 
 	var transactions []gin.H
-	for rows.Next() {
-		var id int64
-		var fromAddr, toAddr string
-		var amount float64
-		var timestamp time.Time
-
-		err := rows.Scan(&id, &fromAddr, &toAddr, &amount, &timestamp)
-		if err != nil {
-			continue
-		}
-
+	for _, tx := range txs {
 		transactionType := "sent"
-		if toAddr == address {
+		if strings.EqualFold(tx.ToAddr, address) {
 			transactionType = "received"
 		}
 
 		transactions = append(transactions, gin.H{
-			"id":               id,
-			"from_address":     fromAddr,
-			"to_address":       toAddr,
-			"amount":           amount,
-			"timestamp":        timestamp,
+			"id":               tx.ID,
+			"from_address":     tx.FromAddr,
+			"to_address":       tx.ToAddr,
+			"amount":           tx.Amount,
+			"timestamp":        tx.Timestamp,
 			"transaction_type": transactionType,
 		})
 	}
@@ -216,46 +459,69 @@ func GetTransactionHistory(c *gin.Context) {
 		"total_count":  len(transactions),
 	}
 
-	sendResponse(c, true, "Transaction history retrieved successfully", historyData, "")
+	sendResponse(c, "Transaction history retrieved successfully", historyData)
 }
 
 // GetTransactionsByBlock 获取指定区块的交易
-func GetTransactionsByBlock(c *gin.Context) {
+func (h *Handler) GetTransactionsByBlock(c *gin.Context) {
 	blockIDStr := c.Param("block_id")
 	blockID, err := strconv.ParseInt(blockIDStr, 10, 64)
 	if err != nil {
-		sendResponse(c, false, "", nil, "Invalid block ID")
+		sendError(c, "", badRequest("Invalid block ID"))
+		return
+	}
+
+	transactions, err := h.bc.GetTransactionsByBlockID(blockID)
+	if err != nil {
+		sendError(c, "Failed to get transactions", err)
+		return
+	}
+
+	blockData := gin.H{
+		"block_id":     blockID,
+		"transactions": transactions,
+		"count":        len(transactions),
+	}
+
+	sendResponse(c, "Block transactions retrieved successfully", blockData)
+}
+
+// GetTransactionByHash 根据交易哈希查询交易、所在区块、确认数和状态
+func (h *Handler) GetTransactionByHash(c *gin.Context) {
+	raw, err := hexutil.Decode(c.Param("hash"))
+	if err != nil || len(raw) != common.HashLength {
+		sendError(c, "", badRequest("Invalid transaction hash: must be 0x followed by 64 hex characters"))
 		return
 	}
 
-	// 获取数据库连接
-	dbConfig := config.GetDBConfig()
-	db, err := database.NewMySQLDB(dbConfig)
+	info, err := h.bc.GetTransactionInfo(common.BytesToHash(raw))
 	if err != nil {
-		sendResponse(c, false, "", nil, "Failed to connect to database: "+err.Error())
+		sendError(c, "Failed to get transaction", err)
 		return
 	}
-	defer db.Close()
 
-	blockchainDB := database.NewBlockchainMySQL(db)
+	sendResponse(c, "Transaction retrieved successfully", info)
+}
 
-	transactions, err := blockchainDB.GetTransactionsByBlockID(blockID)
+// GetTransactionProof 获取交易的 Merkle 包含证明
+func (h *Handler) GetTransactionProof(c *gin.Context) {
+	txID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		sendResponse(c, false, "", nil, "Failed to get transactions: "+err.Error())
+		sendError(c, "", badRequest("Invalid transaction ID"))
 		return
 	}
 
-	blockData := gin.H{
-		"block_id":     blockID,
-		"transactions": transactions,
-		"count":        len(transactions),
+	proof, err := h.bc.GetTransactionProof(txID)
+	if err != nil {
+		sendError(c, "Failed to get transaction proof", err)
+		return
 	}
 
-	sendResponse(c, true, "Block transactions retrieved successfully", blockData, "")
+	sendResponse(c, "Transaction proof retrieved successfully", proof)
 }
 
 // GetAllTransactions 获取所有交易记录
-func GetAllTransactions(c *gin.Context) {
+func (h *Handler) GetAllTransactions(c *gin.Context) {
 	// 获取分页参数
 	pageStr := c.DefaultQuery("page", "1")
 	limitStr := c.DefaultQuery("limit", "20")
@@ -272,56 +538,20 @@ func GetAllTransactions(c *gin.Context) {
 
 	offset := (page - 1) * limit
 
-	// 获取数据库连接
-	dbConfig := config.GetDBConfig()
-	db, err := database.NewMySQLDB(dbConfig)
-	if err != nil {
-		sendResponse(c, false, "", nil, "Failed to connect to database: "+err.Error())
-		return
-	}
-	defer db.Close()
-
-	// 查询总数量
-	var total int
-	err = db.QueryRow("SELECT COUNT(*) FROM transactions").Scan(&total)
-	if err != nil {
-		sendResponse(c, false, "", nil, "Failed to get transaction count: "+err.Error())
-		return
-	}
-
-	// 查询交易记录
-	query := `
-		SELECT id, from_addr, to_addr, amount, timestamp 
-		FROM transactions 
-		ORDER BY timestamp DESC 
-		LIMIT ? OFFSET ?
-	`
-
-	rows, err := db.Query(query, limit, offset)
+	txs, total, err := h.bc.GetTransactions(limit, offset)
 	if err != nil {
-		sendResponse(c, false, "", nil, "Failed to get transactions: "+err.Error())
+		sendError(c, "Failed to get transactions", err)
 		return
 	}
-	defer rows.Close()
 
 	var transactions []gin.H
-	for rows.Next() {
-		var id int64
-		var fromAddr, toAddr string
-		var amount float64
-		var timestamp time.Time
-
-		err := rows.Scan(&id, &fromAddr, &toAddr, &amount, &timestamp)
-		if err != nil {
-			continue
-		}
-
+	for _, tx := range txs {
 		transactions = append(transactions, gin.H{
-			"id":           id,
-			"from_address": fromAddr,
-			"to_address":   toAddr,
-			"amount":       amount,
-			"timestamp":    timestamp,
+			"id":           tx.ID,
+			"from_address": tx.FromAddr,
+			"to_address":   tx.ToAddr,
+			"amount":       tx.Amount,
+			"timestamp":    tx.Timestamp,
 		})
 	}
 
@@ -335,44 +565,53 @@ func GetAllTransactions(c *gin.Context) {
 		},
 	}
 
-	sendResponse(c, true, "All transactions retrieved successfully", paginationData, "")
+	sendResponse(c, "All transactions retrieved successfully", paginationData)
 }
 
 // GetBlockchainInfo 获取区块链信息
-func GetBlockchainInfo(c *gin.Context) {
-	bc := getBlockchainInstance()
-
+func (h *Handler) GetBlockchainInfo(c *gin.Context) {
 	// 验证区块链
-	isValid, err := bc.ValidateChain()
+	report, err := h.bc.ValidateChain()
 	if err != nil {
-		sendResponse(c, false, "", nil, "Failed to validate chain: "+err.Error())
+		sendError(c, "Failed to validate chain", err)
 		return
 	}
 
-	// 获取所有区块 - 通过数据库接口获取
-	dbConfig := config.GetDBConfig()
-	db, err := database.NewMySQLDB(dbConfig)
+	blocks, err := h.bc.GetAllBlocks()
 	if err != nil {
-		sendResponse(c, false, "", nil, "Failed to connect to database: "+err.Error())
+		sendError(c, "Failed to get blocks", err)
 		return
 	}
-	defer db.Close()
 
-	blockchainDB := database.NewBlockchainMySQL(db)
-	blocks, err := blockchainDB.GetAllBlocks()
+	supply, err := h.bc.Supply()
 	if err != nil {
-		sendResponse(c, false, "", nil, "Failed to get blocks: "+err.Error())
+		sendError(c, "Failed to get supply", err)
 		return
 	}
 
 	blockchainData := gin.H{
-		"is_valid":     isValid,
+		"is_valid":     report.Valid,
 		"blocks":       blocks,
 		"block_count":  len(blocks),
+		"supply":       supply,
 		"last_updated": time.Now(),
 	}
 
-	sendResponse(c, true, "Blockchain information retrieved successfully", blockchainData, "")
+	sendResponse(c, "Blockchain information retrieved successfully", blockchainData)
 }
 
-// 交易记录
+// ValidateBlockchain 完整校验区块链并返回每条规则的校验结果
+func (h *Handler) ValidateBlockchain(c *gin.Context) {
+	report, err := h.bc.ValidateChain()
+	if err != nil {
+		sendError(c, "Failed to validate chain", err)
+		return
+	}
+
+	message := "Blockchain is valid"
+	if !report.Valid {
+		message = "Blockchain is invalid"
+	}
+
+	sendResponse(c, message, report)
+}
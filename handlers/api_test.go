@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"hello-go/apperr"
+	"hello-go/blockchain"
+	"hello-go/config"
+	"hello-go/database"
+	"hello-go/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/gin-gonic/gin"
+)
+
+// newTestRouter 创建使用内存数据库的区块链，sender 有 100 个最小单位的余额并且已解锁
+func newTestRouter(t *testing.T) (r *gin.Engine, bc *blockchain.Blockchain, sender, recipient string) {
+	t.Helper()
+
+	store := database.NewBlockchainMemory()
+	ks := keystore.NewKeyStore(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP)
+	chain := &config.ChainConfig{ChainID: 1337, GenesisTime: time.Unix(0, 0), Decimals: 18, BlockReward: "0"}
+	mining := &config.MiningConfig{InitialDifficulty: 1, MinDifficulty: 1, MaxDifficulty: 1, Workers: 1, Timeout: 10 * time.Second}
+	bc = blockchain.NewBlockchain(store, ks, chain, mining)
+	if _, err := bc.CreateGenesisBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	account, err := ks.NewAccount(testPassphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender = account.Address.Hex()
+	if err := store.SaveWallet(&models.Wallet{Address: sender, Balance: models.NewAmount(100)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.UnlockWallet(sender, testPassphrase, 0); err != nil {
+		t.Fatal(err)
+	}
+	recipient = "0x00000000000000000000000000000000000000b0"
+	if err := store.SaveWallet(&models.Wallet{Address: recipient}); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r = gin.New()
+	NewHandler(bc, store, &config.KeyStoreConfig{}, mining, nil).RegisterRoutes(r)
+	return r, bc, sender, recipient
+}
+
+func TestWriteRoutesRequireJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		wantStatus  int
+	}{
+		{name: "json", contentType: "application/json", wantStatus: http.StatusOK},
+		{name: "json with charset", contentType: "application/json; charset=utf-8", wantStatus: http.StatusOK},
+		{name: "text/plain form", contentType: "text/plain", wantStatus: http.StatusBadRequest},
+		{name: "urlencoded form", contentType: "application/x-www-form-urlencoded", wantStatus: http.StatusBadRequest},
+		{name: "multipart form", contentType: "multipart/form-data; boundary=x", wantStatus: http.StatusBadRequest},
+		{name: "missing", contentType: "", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, bc, sender, recipient := newTestRouter(t)
+
+			// 跨站表单可以发送任意合法的 JSON 文本，由服务端为已解锁的钱包代签
+			body := `{"from_address":"` + sender + `","to_address":"` + recipient + `","amount":"10"}`
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/transfer", strings.NewReader(body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			wantPending := 1
+			if tt.wantStatus != http.StatusOK {
+				wantPending = 0
+				if !strings.Contains(w.Body.String(), apperr.CodeUnsupportedContentType) {
+					t.Errorf("body = %s, want error code %s", w.Body, apperr.CodeUnsupportedContentType)
+				}
+			}
+			if pending := bc.PendingTransactions(); len(pending) != wantPending {
+				t.Errorf("mempool has %d transactions, want %d", len(pending), wantPending)
+			}
+		})
+	}
+}
+
+func TestWriteRoutesWithoutBodyNeedNoContentType(t *testing.T) {
+	r, _, sender, _ := newTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet/"+sender+"/lock", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("lock without body: status = %d: %s", w.Code, w.Body)
+	}
+}
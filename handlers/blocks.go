@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 区块列表每页默认和最多返回的区块数
+const (
+	defaultBlockLimit = 20
+	maxBlockLimit     = 100
+)
+
+// ListBlocks 从高度 from（默认为链头）开始按高度递减分页获取区块
+func (h *Handler) ListBlocks(c *gin.Context) {
+	from := -1
+	if s := c.Query("from"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			sendError(c, "", badRequest("Invalid from: must be a non-negative block index"))
+			return
+		}
+		from = n
+	}
+
+	limit := defaultBlockLimit
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxBlockLimit {
+			sendError(c, "", badRequest("Invalid limit: must be between 1 and %d", maxBlockLimit))
+			return
+		}
+		limit = n
+	}
+
+	page, err := h.bc.ListBlocks(from, limit)
+	if err != nil {
+		sendError(c, "Failed to get blocks", err)
+		return
+	}
+
+	sendResponse(c, "Blocks retrieved successfully", page)
+}
+
+// GetLatestBlock 获取链头区块
+func (h *Handler) GetLatestBlock(c *gin.Context) {
+	block, err := h.bc.GetLatestBlockSummary()
+	if err != nil {
+		sendError(c, "Failed to get block", err)
+		return
+	}
+
+	sendResponse(c, "Block retrieved successfully", block)
+}
+
+// GetBlockByIndex 获取指定高度的区块
+func (h *Handler) GetBlockByIndex(c *gin.Context) {
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil || index < 0 {
+		sendError(c, "", badRequest("Invalid block index"))
+		return
+	}
+
+	block, err := h.bc.GetBlockSummary(index)
+	if err != nil {
+		sendError(c, "Failed to get block", err)
+		return
+	}
+
+	sendResponse(c, "Block retrieved successfully", block)
+}
+
+// GetBlockByHash 根据区块哈希获取区块，哈希可以带 0x 前缀
+func (h *Handler) GetBlockByHash(c *gin.Context) {
+	hash := strings.ToLower(strings.TrimPrefix(c.Param("hash"), "0x"))
+	if raw, err := hex.DecodeString(hash); err != nil || len(raw) != 32 {
+		sendError(c, "", badRequest("Invalid block hash: must be 64 hex characters"))
+		return
+	}
+
+	block, err := h.bc.GetBlockSummaryByHash(hash)
+	if err != nil {
+		sendError(c, "Failed to get block", err)
+		return
+	}
+
+	sendResponse(c, "Block retrieved successfully", block)
+}
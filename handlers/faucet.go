@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"hello-go/apperr"
+
+	"github.com/gin-gonic/gin"
+)
+
+// faucetDisabled 未配置水龙头钱包时返回的错误
+func faucetDisabled() error {
+	return apperr.New(apperr.Unavailable, apperr.CodeFaucetDisabled, "faucet is not enabled on this node")
+}
+
+// GetFaucetInfo 获取水龙头的发放规则和剩余额度
+func (h *Handler) GetFaucetInfo(c *gin.Context) {
+	if h.faucet == nil {
+		sendError(c, "", faucetDisabled())
+		return
+	}
+
+	info, err := h.faucet.Info()
+	if err != nil {
+		sendError(c, "Failed to get faucet info", err)
+		return
+	}
+
+	sendResponse(c, "Faucet info retrieved successfully", info)
+}
+
+// GetFaucetChallenge 获取领取前需要完成的工作量证明挑战
+func (h *Handler) GetFaucetChallenge(c *gin.Context) {
+	if h.faucet == nil {
+		sendError(c, "", faucetDisabled())
+		return
+	}
+
+	challenge, err := h.faucet.NewChallenge(c.Query("address"))
+	if err != nil {
+		sendError(c, "Failed to create faucet challenge", err)
+		return
+	}
+
+	sendResponse(c, "Faucet challenge created successfully", challenge)
+}
+
+// RequestFaucet 从水龙头领取代币，水龙头发起一笔链上转账
+func (h *Handler) RequestFaucet(c *gin.Context) {
+	if h.faucet == nil {
+		sendError(c, "", faucetDisabled())
+		return
+	}
+
+	var faucetRequest struct {
+		Address string `json:"address" binding:"required"`
+		// 启用工作量证明时必填
+		Challenge string `json:"challenge"`
+		Solution  string `json:"solution"`
+	}
+
+	if err := c.ShouldBindJSON(&faucetRequest); err != nil {
+		sendError(c, "", badRequest("Invalid request data: %v", err))
+		return
+	}
+
+	receipt, err := h.faucet.Request(faucetRequest.Address, c.ClientIP(), faucetRequest.Challenge, faucetRequest.Solution)
+	if err != nil {
+		sendError(c, "Faucet request failed", err)
+		return
+	}
+
+	sendResponse(c, "Faucet transaction submitted to mempool", receipt)
+}
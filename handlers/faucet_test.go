@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"hello-go/apperr"
+	"hello-go/blockchain"
+	"hello-go/config"
+	"hello-go/database"
+	"hello-go/faucet"
+	"hello-go/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/gin-gonic/gin"
+)
+
+const testPassphrase = "faucet-passphrase"
+
+// newFaucetRouter 创建使用内存数据库的区块链和水龙头，水龙头钱包有 100 个完整单位的余额，并且已被手动解锁
+func newFaucetRouter(t *testing.T) (r *gin.Engine, bc *blockchain.Blockchain, faucetAddress, recipient string) {
+	t.Helper()
+
+	store := database.NewBlockchainMemory()
+	ks := keystore.NewKeyStore(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP)
+	chain := &config.ChainConfig{ChainID: 1337, GenesisTime: time.Unix(0, 0), Decimals: 18, BlockReward: "0"}
+	mining := &config.MiningConfig{InitialDifficulty: 1, MinDifficulty: 1, MaxDifficulty: 1, Workers: 1, Timeout: 10 * time.Second}
+	bc = blockchain.NewBlockchain(store, ks, chain, mining)
+	if _, err := bc.CreateGenesisBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	account, err := ks.NewAccount(testPassphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	faucetAddress = account.Address.Hex()
+	balance, _ := models.ParseUnits("100", chain.Decimals)
+	if err := store.SaveWallet(&models.Wallet{Address: faucetAddress, Balance: balance}); err != nil {
+		t.Fatal(err)
+	}
+	wallet, err := bc.CreateNewWallet("recipient")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipient = wallet.Address
+
+	tap, err := faucet.NewFaucet(bc, &config.FaucetConfig{
+		Address:         faucetAddress,
+		Passphrase:      testPassphrase,
+		Amount:          "10",
+		Fee:             "0",
+		AddressCooldown: time.Hour,
+		IPCooldown:      time.Hour,
+		DailyCap:        "0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 最坏情况：管理员通过接口解锁了水龙头钱包
+	if err := bc.UnlockWallet(faucetAddress, testPassphrase, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r = gin.New()
+	NewHandler(bc, store, &config.KeyStoreConfig{}, mining, tap).RegisterRoutes(r)
+	return r, bc, faucetAddress, recipient
+}
+
+func postJSON(r *gin.Engine, path string, body interface{}) (*httptest.ResponseRecorder, Response) {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var resp Response
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestTransferRejectsUnsignedFaucetTransfer(t *testing.T) {
+	r, bc, faucetAddress, recipient := newFaucetRouter(t)
+
+	w, resp := postJSON(r, "/api/v1/transfer", gin.H{
+		"from_address": faucetAddress,
+		"to_address":   recipient,
+		"amount":       "99000000000000000000",
+	})
+	if w.Code != http.StatusBadRequest || resp.ErrorCode != apperr.CodeInvalidSignature {
+		t.Fatalf("unsigned transfer from faucet: got %d %q, want 400 %q", w.Code, resp.ErrorCode, apperr.CodeInvalidSignature)
+	}
+	if pending := bc.PendingTransactions(); len(pending) != 0 {
+		t.Fatalf("mempool has %d transactions, want 0", len(pending))
+	}
+
+	// 水龙头自身仍然可以发放
+	w, resp = postJSON(r, "/api/v1/faucet", gin.H{"address": recipient})
+	if w.Code != http.StatusOK {
+		t.Fatalf("faucet request: got %d %s", w.Code, resp.Error)
+	}
+	pending := bc.PendingTransactions()
+	if len(pending) != 1 || pending[0].FromAddr != faucetAddress {
+		t.Fatalf("mempool = %+v, want one faucet transaction", pending)
+	}
+}
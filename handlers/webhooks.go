@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"hello-go/models"
+	"hello-go/webhook"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+// webhookResponse 回调注册的响应，secret 只在创建时返回
+type webhookResponse struct {
+	*models.Webhook
+	Secret string `json:"secret,omitempty"`
+}
+
+// CreateWebhook 注册回调
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	var webhookRequest struct {
+		URL     string   `json:"url" binding:"required"`
+		Address string   `json:"address"`
+		Events  []string `json:"events"`
+		Secret  string   `json:"secret"`
+	}
+
+	if err := c.ShouldBindJSON(&webhookRequest); err != nil {
+		sendError(c, "", badRequest("Invalid request data: %v", err))
+		return
+	}
+
+	target, err := url.Parse(webhookRequest.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		sendError(c, "", badRequest("Invalid webhook URL: must be an absolute http or https URL"))
+		return
+	}
+
+	address := strings.TrimSpace(webhookRequest.Address)
+	if address != "" {
+		if !common.IsHexAddress(address) {
+			sendError(c, "", badRequest("Invalid address: %s", address))
+			return
+		}
+		address = common.HexToAddress(address).Hex()
+	}
+
+	for _, event := range webhookRequest.Events {
+		if !webhook.ValidEvent(event) {
+			sendError(c, "", badRequest("Invalid event: %s, supported events: %s", event, strings.Join(webhook.Events, ", ")))
+			return
+		}
+	}
+
+	// 未指定 secret 时生成随机密钥
+	secret := webhookRequest.Secret
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			sendError(c, "Failed to generate secret", err)
+			return
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
+	hook := &models.Webhook{
+		URL:       target.String(),
+		Address:   address,
+		Events:    webhookRequest.Events,
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	}
+
+	if err := h.webhooks.SaveWebhook(hook); err != nil {
+		sendError(c, "Failed to create webhook", err)
+		return
+	}
+
+	sendResponse(c, "Webhook created successfully", &webhookResponse{Webhook: hook, Secret: hook.Secret})
+}
+
+// ListWebhooks 获取全部回调注册
+func (h *Handler) ListWebhooks(c *gin.Context) {
+	hooks, err := h.webhooks.ListWebhooks()
+	if err != nil {
+		sendError(c, "Failed to get webhooks", err)
+		return
+	}
+	if hooks == nil {
+		hooks = []*models.Webhook{}
+	}
+
+	webhookData := gin.H{
+		"webhooks": hooks,
+		"count":    len(hooks),
+	}
+
+	sendResponse(c, "Webhooks retrieved successfully", webhookData)
+}
+
+// GetWebhook 获取回调注册
+func (h *Handler) GetWebhook(c *gin.Context) {
+	hook, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+
+	sendResponse(c, "Webhook retrieved successfully", hook)
+}
+
+// DeleteWebhook 删除回调注册及其投递记录
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	hook, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webhooks.DeleteWebhook(hook.ID); err != nil {
+		sendError(c, "Failed to delete webhook", err)
+		return
+	}
+
+	sendResponse(c, "Webhook deleted successfully", gin.H{"id": hook.ID})
+}
+
+// GetWebhookDeliveries 分页获取回调的投递记录，最新的在前
+func (h *Handler) GetWebhookDeliveries(c *gin.Context) {
+	hook, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		sendError(c, "", badRequest("Invalid limit: must be between 1 and 200"))
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		sendError(c, "", badRequest("Invalid offset"))
+		return
+	}
+
+	deliveries, err := h.webhooks.GetWebhookDeliveries(hook.ID, limit, offset)
+	if err != nil {
+		sendError(c, "Failed to get webhook deliveries", err)
+		return
+	}
+
+	deliveryData := gin.H{
+		"webhook_id": hook.ID,
+		"deliveries": deliveries,
+		"count":      len(deliveries),
+		"limit":      limit,
+		"offset":     offset,
+	}
+
+	sendResponse(c, "Webhook deliveries retrieved successfully", deliveryData)
+}
+
+// findWebhook 根据路径参数查找回调注册，查找失败时直接写入错误响应
+func (h *Handler) findWebhook(c *gin.Context) (*models.Webhook, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		sendError(c, "", badRequest("Invalid webhook ID"))
+		return nil, false
+	}
+
+	hook, err := h.webhooks.GetWebhook(id)
+	if err != nil {
+		sendError(c, "Failed to get webhook", err)
+		return nil, false
+	}
+	return hook, true
+}
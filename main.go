@@ -1,10 +1,21 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"hello-go/config"
+	"hello-go/faucet"
 	"hello-go/handlers"
+	"hello-go/p2p"
+	"hello-go/rpc"
+	"hello-go/webhook"
+	"hello-go/ws"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,21 +24,54 @@ func main() {
 	// 设置日志格式
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 
-	// 根据环境设置Gin模式
-	if os.Getenv("GIN_MODE") == "release" {
+	// 加载配置文件、环境变量和命令行参数
+	args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// 执行命令行子命令
+	if len(args) > 0 {
+		os.Exit(runCommand(args[0], args[1:]))
+	}
+
+	// 连接数据库并加载区块链，所有模块共用
+	a, err := newApp()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer a.Close()
+
+	serverConfig := config.GetServerConfig()
+
+	// 根据日志级别设置Gin模式
+	if serverConfig.LogLevel == config.LogLevelDebug {
+		gin.SetMode(gin.DebugMode)
+	} else {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
 	// 创建Gin路由器
-	r := gin.Default()
+	r := gin.New()
 
 	// 添加中间件
-	r.Use(gin.Logger())
+	if serverConfig.LogLevel == config.LogLevelDebug || serverConfig.LogLevel == config.LogLevelInfo {
+		r.Use(gin.Logger())
+	}
 	r.Use(gin.Recovery())
 
+	// 只信任配置的反向代理转发的客户端 IP
+	if err := r.SetTrustedProxies(serverConfig.TrustedProxies); err != nil {
+		log.Fatal(err)
+	}
+
 	// 添加CORS中间件
+	r.Use(cors(serverConfig.CORSOrigins))
 	r.Use(func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
 
@@ -39,31 +83,30 @@ func main() {
 		c.Next()
 	})
 
-	// API路由组
-	api := r.Group("/api/v1")
-	{
-		// 钱包相关接口
-		api.POST("/wallet", handlers.CreateWallet)
-		api.GET("/wallet/:address", handlers.GetBalance)
-		api.POST("/transfer", handlers.Transfer)
-
-		// 交易记录相关接口
-		api.GET("/transactions", handlers.GetAllTransactions)
-		api.GET("/transactions/history/:address", handlers.GetTransactionHistory)
-		api.GET("/transactions/block/:block_id", handlers.GetTransactionsByBlock)
-
-		// 区块链信息接口
-		api.GET("/blockchain", handlers.GetBlockchainInfo)
-
-		// 健康检查接口
-		api.GET("/health", func(c *gin.Context) {
-			c.JSON(http.StatusOK, gin.H{
-				"status":  "ok",
-				"message": "Blockchain API is running",
-			})
-		})
+	// 水龙头，未配置水龙头钱包时不启用
+	var tap *faucet.Faucet
+	if faucetConfig := config.GetFaucetConfig(); faucetConfig.Address != "" {
+		if tap, err = faucet.NewFaucet(a.bc, faucetConfig); err != nil {
+			log.Fatal(err)
+		}
+		stopFaucet := tap.Start()
+		defer stopFaucet()
 	}
 
+	// REST 接口
+	handlers.NewHandler(a.bc, a.store, config.GetKeyStoreConfig(), config.GetMiningConfig(), tap).RegisterRoutes(r)
+
+	// 节点间通信接口
+	node := p2p.NewNode(a.bc, config.GetP2PConfig())
+	node.RegisterRoutes(r)
+
+	// 以太坊兼容的 JSON-RPC 接口
+	rpc.NewServer(a.bc).RegisterRoutes(r)
+
+	// WebSocket 事件订阅接口
+	hub := ws.NewHub(a.bc, serverConfig.CORSOrigins)
+	hub.RegisterRoutes(r)
+
 	// 根路径
 	r.GET("/", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -72,21 +115,60 @@ func main() {
 			"endpoints": gin.H{
 				"create_wallet":           "POST /api/v1/wallet",
 				"get_balance":             "GET /api/v1/wallet/:address",
+				"get_nonce":               "GET /api/v1/wallet/:address/nonce",
+				"unlock_wallet":           "POST /api/v1/wallet/:address/unlock",
+				"lock_wallet":             "POST /api/v1/wallet/:address/lock",
+				"export_wallet":           "POST /api/v1/wallet/:address/export",
 				"transfer":                "POST /api/v1/transfer",
+				"faucet_info":             "GET /api/v1/faucet",
+				"faucet_challenge":        "GET /api/v1/faucet/challenge?address=",
+				"faucet_request":          "POST /api/v1/faucet",
+				"get_mempool":             "GET /api/v1/mempool",
+				"mine_block":              "POST /api/v1/mine",
+				"miner_stats":             "GET /api/v1/mining/stats",
 				"get_all_transactions":    "GET /api/v1/transactions",
 				"get_transaction_history": "GET /api/v1/transactions/history/:address",
 				"get_block_transactions":  "GET /api/v1/transactions/block/:block_id",
+				"get_transaction_proof":   "GET /api/v1/transactions/proof/:id",
+				"get_transaction":         "GET /api/v1/transactions/:hash",
+				"create_webhook":          "POST /api/v1/webhooks",
+				"list_webhooks":           "GET /api/v1/webhooks",
+				"get_webhook":             "GET /api/v1/webhooks/:id",
+				"delete_webhook":          "DELETE /api/v1/webhooks/:id",
+				"webhook_deliveries":      "GET /api/v1/webhooks/:id/deliveries",
+				"list_blocks":             "GET /api/v1/blocks?from=&limit=",
+				"latest_block":            "GET /api/v1/blocks/latest",
+				"get_block":               "GET /api/v1/blocks/:index",
+				"get_block_by_hash":       "GET /api/v1/blocks/hash/:hash",
 				"blockchain_info":         "GET /api/v1/blockchain",
+				"validate_blockchain":     "GET /api/v1/blockchain/validate",
 				"health_check":            "GET /api/v1/health",
+				"p2p_status":              "GET /p2p/status",
+				"p2p_peers":               "GET /p2p/peers",
+				"json_rpc":                "POST /rpc",
+				"websocket":               "GET /ws",
 			},
 		})
 	})
 
+	// 启动后台矿工
+	stopMiner := a.bc.StartMiner()
+	defer stopMiner()
+
+	// 开始推送 WebSocket 事件
+	stopHub := hub.Start()
+	defer stopHub()
+
+	// 启动回调投递
+	stopWebhooks := webhook.NewDispatcher(a.store, config.GetWebhookConfig()).Start(a.bc)
+	defer stopWebhooks()
+
+	// 启动节点发现与区块同步
+	stopNode := node.Start()
+	defer stopNode()
+
 	// 启动服务器
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
+	port := strconv.Itoa(serverConfig.Port)
 
 	log.Printf("Starting blockchain server on port %s", port)
 	log.Printf("API documentation available at http://localhost:%s", port)
@@ -95,3 +177,22 @@ func main() {
 		log.Fatal("Failed to start server:", err)
 	}
 }
+
+// cors 设置允许跨域访问的来源，origins 包含 "*" 时允许所有来源
+func cors(origins []string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		allowed[strings.TrimRight(origin, "/")] = true
+	}
+
+	return func(c *gin.Context) {
+		if allowed["*"] {
+			c.Header("Access-Control-Allow-Origin", "*")
+			return
+		}
+		c.Header("Vary", "Origin")
+		if origin := c.GetHeader("Origin"); allowed[origin] {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+	}
+}
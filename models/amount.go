@@ -0,0 +1,159 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/holiman/uint256"
+)
+
+// Amount 以最小单位（整数）表示的金额，避免浮点数运算的舍入误差
+// Amount 是值类型，可以直接复制；JSON 中编码为十进制字符串
+type Amount struct {
+	v uint256.Int
+}
+
+// NewAmount 以最小单位创建金额
+func NewAmount(units uint64) Amount {
+	var a Amount
+	a.v.SetUint64(units)
+	return a
+}
+
+// ParseAmount 解析以最小单位表示的十进制整数字符串
+func ParseAmount(s string) (Amount, error) {
+	var a Amount
+	if err := a.v.SetFromDecimal(s); err != nil {
+		return Amount{}, fmt.Errorf("invalid amount %q: %v", s, err)
+	}
+	return a, nil
+}
+
+// ParseUnits 解析带小数的金额，例如 decimals 为 18 时 "1.5" 为 1500000000000000000 个最小单位
+func ParseUnits(s string, decimals int) (Amount, error) {
+	whole, frac, _ := strings.Cut(s, ".")
+	if len(frac) > decimals {
+		return Amount{}, fmt.Errorf("invalid amount %q: more than %d decimal places", s, decimals)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	return ParseAmount(whole + frac + strings.Repeat("0", decimals-len(frac)))
+}
+
+// Units 返回 n 个完整单位对应的金额，即 n * 10^decimals
+func Units(n uint64, decimals int) Amount {
+	var a Amount
+	a.v.Exp(uint256.NewInt(10), uint256.NewInt(uint64(decimals)))
+	a.v.Mul(&a.v, uint256.NewInt(n))
+	return a
+}
+
+// Add 返回 a + b，结果溢出时 ok 为 false
+func (a Amount) Add(b Amount) (sum Amount, ok bool) {
+	_, overflow := sum.v.AddOverflow(&a.v, &b.v)
+	return sum, !overflow
+}
+
+// Sub 返回 a - b，b 大于 a 时 ok 为 false
+func (a Amount) Sub(b Amount) (diff Amount, ok bool) {
+	_, underflow := diff.v.SubOverflow(&a.v, &b.v)
+	return diff, !underflow
+}
+
+// Rsh 返回 a 除以 2^n 向下取整的结果
+func (a Amount) Rsh(n uint) Amount {
+	var r Amount
+	r.v.Rsh(&a.v, n)
+	return r
+}
+
+// Cmp 比较两个金额，a < b 返回 -1，相等返回 0，a > b 返回 1
+func (a Amount) Cmp(b Amount) int {
+	return a.v.Cmp(&b.v)
+}
+
+func (a Amount) IsZero() bool {
+	return a.v.IsZero()
+}
+
+// ToBig 转换为 big.Int
+func (a Amount) ToBig() *big.Int {
+	return a.v.ToBig()
+}
+
+// String 返回以最小单位表示的十进制整数
+func (a Amount) String() string {
+	return a.v.Dec()
+}
+
+// FormatUnits 按小数位数格式化，省略末尾的零，例如 decimals 为 18 时 1500000000000000000 为 "1.5"
+func (a Amount) FormatUnits(decimals int) string {
+	s := a.v.Dec()
+	if decimals == 0 {
+		return s
+	}
+	if len(s) <= decimals {
+		s = strings.Repeat("0", decimals-len(s)+1) + s
+	}
+	whole, frac := s[:len(s)-decimals], strings.TrimRight(s[len(s)-decimals:], "0")
+	if frac == "" {
+		return whole
+	}
+	return whole + "." + frac
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + a.v.Dec() + `"`), nil
+}
+
+// UnmarshalJSON 接受十进制字符串或 JSON 整数
+func (a *Amount) UnmarshalJSON(input []byte) error {
+	s := strings.Trim(string(input), `"`)
+	parsed, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// Value 实现 driver.Valuer，以十进制字符串写入 DECIMAL 列
+func (a Amount) Value() (driver.Value, error) {
+	return a.v.Dec(), nil
+}
+
+// Scan 实现 sql.Scanner，读取 DECIMAL 列
+func (a *Amount) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		if v < 0 {
+			return fmt.Errorf("negative amount: %d", v)
+		}
+		*a = NewAmount(uint64(v))
+		return nil
+	case nil:
+		*a = Amount{}
+		return nil
+	default:
+		return fmt.Errorf("unsupported amount type %T", src)
+	}
+
+	// DECIMAL(65,0) 列不会有小数部分，兼容迁移前带 .00000000 的取值
+	if whole, frac, ok := strings.Cut(s, "."); ok && strings.Trim(frac, "0") == "" {
+		s = whole
+	}
+	parsed, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
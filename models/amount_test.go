@@ -0,0 +1,232 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// maxAmount 2^256-1，Amount 能表示的最大值
+const maxAmount = "115792089237316195423570985008687907853269984665640564039457584007913129639935"
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "0", want: "0"},
+		{in: "1500000000000000000", want: "1500000000000000000"},
+		{in: maxAmount, want: maxAmount},
+		{in: "115792089237316195423570985008687907853269984665640564039457584007913129639936", wantErr: true},
+		{in: "-1", wantErr: true},
+		{in: "1.5", wantErr: true},
+		{in: "1e18", wantErr: true},
+		{in: "abc", wantErr: true},
+		{in: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAmount(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAmount(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got.String() != tt.want {
+			t.Errorf("ParseAmount(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseUnits(t *testing.T) {
+	tests := []struct {
+		in       string
+		decimals int
+		want     string
+		wantErr  bool
+	}{
+		{in: "1", decimals: 18, want: "1000000000000000000"},
+		{in: "1.5", decimals: 18, want: "1500000000000000000"},
+		{in: "0.000000000000000001", decimals: 18, want: "1"},
+		{in: ".25", decimals: 2, want: "25"},
+		{in: "1.", decimals: 2, want: "100"},
+		{in: "42", decimals: 0, want: "42"},
+		{in: "0.0000000000000000001", decimals: 18, wantErr: true},
+		{in: "1.5", decimals: 0, wantErr: true},
+		{in: "1.2.3", decimals: 18, wantErr: true},
+		{in: "-1.5", decimals: 18, wantErr: true},
+		// 放大 10^18 后超过 2^256-1
+		{in: maxAmount[:len(maxAmount)-17], decimals: 18, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseUnits(tt.in, tt.decimals)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseUnits(%q, %d) error = %v, wantErr %v", tt.in, tt.decimals, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got.String() != tt.want {
+			t.Errorf("ParseUnits(%q, %d) = %s, want %s", tt.in, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestFormatUnits(t *testing.T) {
+	tests := []struct {
+		units    string
+		decimals int
+		want     string
+	}{
+		{units: "0", decimals: 18, want: "0"},
+		{units: "1", decimals: 18, want: "0.000000000000000001"},
+		{units: "1500000000000000000", decimals: 18, want: "1.5"},
+		{units: "2000000000000000000", decimals: 18, want: "2"},
+		{units: "123", decimals: 2, want: "1.23"},
+		{units: "123", decimals: 0, want: "123"},
+	}
+
+	for _, tt := range tests {
+		a, err := ParseAmount(tt.units)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := a.FormatUnits(tt.decimals)
+		if got != tt.want {
+			t.Errorf("FormatUnits(%s, %d) = %s, want %s", tt.units, tt.decimals, got, tt.want)
+		}
+		// 格式化结果可以解析回原值
+		back, err := ParseUnits(got, tt.decimals)
+		if err != nil || back.Cmp(a) != 0 {
+			t.Errorf("ParseUnits(%q, %d) = %s (%v), want %s", got, tt.decimals, back, err, tt.units)
+		}
+	}
+}
+
+func TestAmountArithmetic(t *testing.T) {
+	largest, err := ParseAmount(maxAmount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		op     func() (Amount, bool)
+		want   string
+		wantOK bool
+	}{
+		{name: "add", op: func() (Amount, bool) { return NewAmount(2).Add(NewAmount(3)) }, want: "5", wantOK: true},
+		{name: "add to max", op: func() (Amount, bool) { return largest.Add(NewAmount(0)) }, want: maxAmount, wantOK: true},
+		{name: "add overflows", op: func() (Amount, bool) { return largest.Add(NewAmount(1)) }},
+		{name: "sub", op: func() (Amount, bool) { return NewAmount(5).Sub(NewAmount(3)) }, want: "2", wantOK: true},
+		{name: "sub to zero", op: func() (Amount, bool) { return NewAmount(5).Sub(NewAmount(5)) }, want: "0", wantOK: true},
+		{name: "sub underflows", op: func() (Amount, bool) { return NewAmount(3).Sub(NewAmount(5)) }},
+	}
+
+	for _, tt := range tests {
+		got, ok := tt.op()
+		if ok != tt.wantOK {
+			t.Errorf("%s: ok = %v, want %v", tt.name, ok, tt.wantOK)
+			continue
+		}
+		if ok && got.String() != tt.want {
+			t.Errorf("%s = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	if got := Units(3, 18).String(); got != "3000000000000000000" {
+		t.Errorf("Units(3, 18) = %s", got)
+	}
+	if got := NewAmount(5).Rsh(1).String(); got != "2" {
+		t.Errorf("5 >> 1 = %s, want 2", got)
+	}
+}
+
+func TestAmountScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     interface{}
+		want    string
+		wantErr bool
+	}{
+		{name: "bytes", src: []byte("1500000000000000000"), want: "1500000000000000000"},
+		{name: "string", src: "42", want: "42"},
+		{name: "int64", src: int64(7), want: "7"},
+		{name: "null", src: nil, want: "0"},
+		{name: "max uint256", src: []byte(maxAmount), want: maxAmount},
+		{name: "zero fraction before migration", src: []byte("100.00000000"), want: "100"},
+		{name: "non-zero fraction", src: []byte("100.5"), wantErr: true},
+		{name: "negative int64", src: int64(-1), wantErr: true},
+		{name: "negative decimal", src: []byte("-1"), wantErr: true},
+		{name: "overflow", src: []byte(maxAmount + "0"), wantErr: true},
+		{name: "float", src: 1.5, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 从非零值开始，确认 NULL 会将金额清零
+			a := NewAmount(99)
+			err := a.Scan(tt.src)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Scan(%v) error = %v, wantErr %v", tt.src, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if a.String() != tt.want {
+				t.Errorf("Scan(%v) = %s, want %s", tt.src, a, tt.want)
+			}
+
+			// Value 写回的值可以被 Scan 读出相同的金额
+			v, err := a.Value()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var back Amount
+			if err := back.Scan(v); err != nil || back.Cmp(a) != 0 {
+				t.Errorf("Scan(Value()) = %s (%v), want %s", back, err, a)
+			}
+		})
+	}
+}
+
+func TestAmountJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: `"1500000000000000000"`, want: "1500000000000000000"},
+		{in: `42`, want: "42"},
+		{in: `"` + maxAmount + `"`, want: maxAmount},
+		{in: `"-1"`, wantErr: true},
+		{in: `"1.5"`, wantErr: true},
+		{in: `1e3`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		var a Amount
+		err := json.Unmarshal([]byte(tt.in), &a)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Unmarshal(%s) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if a.String() != tt.want {
+			t.Errorf("Unmarshal(%s) = %s, want %s", tt.in, a, tt.want)
+		}
+		out, err := json.Marshal(a)
+		if err != nil || string(out) != `"`+tt.want+`"` {
+			t.Errorf("Marshal = %s (%v), want %q", out, err, tt.want)
+		}
+	}
+
+	// 嵌入结构体时同样编码为字符串，避免 JavaScript 客户端丢失精度
+	out, err := json.Marshal(struct {
+		Balance Amount `json:"balance"`
+	}{Units(1, 18)})
+	if err != nil || !strings.Contains(string(out), `"balance":"1000000000000000000"`) {
+		t.Errorf("Marshal struct = %s (%v)", out, err)
+	}
+}
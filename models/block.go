@@ -10,22 +10,39 @@ type Block struct {
 	Hash       string    `json:"hash"`
 	PrevHash   string    `json:"prev_hash"`
 	Data       string    `json:"data"`
+	MerkleRoot string    `json:"merkle_root"`
 	Timestamp  time.Time `json:"timestamp"`
 	Nonce      int       `json:"nonce"`
 	Difficulty int       `json:"difficulty"`
 }
 
 type Transaction struct {
-	ID        int64     `json:"id"`
-	BlockID   int64     `json:"block_id"`
-	FromAddr  string    `json:"from_addr"`
-	ToAddr    string    `json:"to_addr"`
-	Amount    float64   `json:"amount"`
+	ID      int64 `json:"id"`
+	BlockID int64 `json:"block_id"`
+	// Hash 交易哈希，keccak256(规范 RLP 编码)，以 0x 开头的小写十六进制，全链唯一
+	Hash     string `json:"hash"`
+	FromAddr string `json:"from_addr"`
+	ToAddr   string `json:"to_addr"`
+	Amount   Amount `json:"amount"`
+	// Fee 手续费，由转出钱包在 Amount 之外支付，归打包区块的矿工所有
+	Fee       Amount    `json:"fee"`
+	Nonce     uint64    `json:"nonce"`
+	ChainID   uint64    `json:"chain_id"`
+	Signature string    `json:"signature"`
 	Timestamp time.Time `json:"timestamp"`
 }
 
+// CoinbaseAddress coinbase 交易的转出地址，没有对应的私钥，普通交易无法以该地址签名
+const CoinbaseAddress = "0x0000000000000000000000000000000000000000"
+
+// IsCoinbase 是否为向矿工发放出块奖励和手续费的 coinbase 交易
+func (tx *Transaction) IsCoinbase() bool {
+	return tx.FromAddr == CoinbaseAddress
+}
+
 type Wallet struct {
-	Address    string
-	PrivateKey string
-	Balance    float64
+	Address string
+	Balance Amount
+	// Nonce 下一笔转出交易必须使用的序号，等于已上链的转出交易数
+	Nonce uint64
 }
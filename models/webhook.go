@@ -0,0 +1,42 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 回调投递状态
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook 地址活动回调注册
+type Webhook struct {
+	ID  int64  `json:"id"`
+	URL string `json:"url"`
+	// Address 只推送涉及该地址的事件，为空时推送所有地址的事件
+	Address string `json:"address"`
+	// Events 订阅的事件类型，为空时订阅全部事件
+	Events []string `json:"events"`
+	// Secret 用于对回调内容进行 HMAC-SHA256 签名，只在创建时返回
+	Secret    string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDelivery 一次回调投递及其重试状态
+type WebhookDelivery struct {
+	ID        int64           `json:"id"`
+	WebhookID int64           `json:"webhook_id"`
+	Event     string          `json:"event"`
+	Payload   json.RawMessage `json:"payload"`
+	Status    string          `json:"status"`
+	Attempts  int             `json:"attempts"`
+	// ResponseCode 最近一次投递的 HTTP 状态码，请求失败时为 0
+	ResponseCode  int       `json:"response_code"`
+	Error         string    `json:"error,omitempty"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
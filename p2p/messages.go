@@ -0,0 +1,142 @@
+package p2p
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hello-go/blockchain"
+	"hello-go/models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// blockMessage 广播新区块
+type blockMessage struct {
+	From         string                `json:"from"`
+	Block        *models.Block         `json:"block" binding:"required"`
+	Transactions []*models.Transaction `json:"transactions"`
+}
+
+// transactionMessage 广播新交易
+type transactionMessage struct {
+	From        string              `json:"from"`
+	Transaction *models.Transaction `json:"transaction" binding:"required"`
+}
+
+// peersMessage 节点登记与节点列表交换
+type peersMessage struct {
+	URL   string   `json:"url"`
+	Peers []string `json:"peers"`
+}
+
+// blocksResponse 区块范围查询结果
+type blocksResponse struct {
+	Blocks []*blockchain.BlockWithTransactions `json:"blocks"`
+}
+
+func (n *Node) get(rawURL string, out interface{}) error {
+	resp, err := n.client.Get(rawURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: unexpected status %d", rawURL, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (n *Node) post(rawURL string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.client.Post(rawURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("POST %s: unexpected status %d", rawURL, resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// announce 向节点登记自身地址，返回对方已知的节点列表
+func (n *Node) announce(peerURL string) ([]string, error) {
+	var resp peersMessage
+	err := n.post(peerURL+"/p2p/peers", &peersMessage{URL: n.cfg.AdvertiseURL}, &resp)
+	return resp.Peers, err
+}
+
+func (n *Node) fetchStatus(peerURL string) (*blockchain.ChainStatus, error) {
+	status := &blockchain.ChainStatus{}
+	if err := n.get(peerURL+"/p2p/status", status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func (n *Node) fetchBlocks(peerURL string, from, limit int) ([]*blockchain.BlockWithTransactions, error) {
+	query := url.Values{}
+	query.Set("from", strconv.Itoa(from))
+	query.Set("limit", strconv.Itoa(limit))
+
+	var resp blocksResponse
+	if err := n.get(peerURL+"/p2p/blocks?"+query.Encode(), &resp); err != nil {
+		return nil, err
+	}
+	return resp.Blocks, nil
+}
+
+// seenCache 记录最近处理过的消息标识，超过有效期后自动遗忘
+type seenCache struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	items map[string]time.Time
+}
+
+func newSeenCache(ttl time.Duration) *seenCache {
+	return &seenCache{
+		ttl:   ttl,
+		items: make(map[string]time.Time),
+	}
+}
+
+// has 标识是否在有效期内出现过，不记录标识
+func (c *seenCache) has(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires, ok := c.items[key]
+	return ok && time.Now().Before(expires)
+}
+
+// add 记录标识，返回该标识此前是否已经出现过
+func (c *seenCache) add(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if expires, ok := c.items[key]; ok && now.Before(expires) {
+		return true
+	}
+
+	// 顺带清理过期的标识
+	for k, expires := range c.items {
+		if now.After(expires) {
+			delete(c.items, k)
+		}
+	}
+	c.items[key] = now.Add(c.ttl)
+	return false
+}
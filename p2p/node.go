@@ -0,0 +1,332 @@
+package p2p
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hello-go/blockchain"
+	"hello-go/config"
+	"hello-go/models"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 连续失败多少次后移除通过发现机制加入的节点，种子节点不会被移除
+const maxPeerFailures = 3
+
+// Peer 已知的其他节点
+type Peer struct {
+	URL      string    `json:"url"`
+	Height   int       `json:"height"`
+	TipHash  string    `json:"tip_hash"`
+	LastSeen time.Time `json:"last_seen"`
+	Seed     bool      `json:"seed"`
+	failures int
+}
+
+// Node 基于 HTTP 的节点间网络：发现节点、广播新区块和交易、同步缺失区块
+type Node struct {
+	bc     *blockchain.Blockchain
+	cfg    *config.P2PConfig
+	client *http.Client
+
+	mu    sync.RWMutex
+	peers map[string]*Peer
+
+	// 已处理过的区块哈希和交易签名，避免广播消息在节点间循环
+	seen *seenCache
+	// 同一时间只进行一次同步，已有同步在进行时新的同步请求直接返回
+	syncing atomic.Bool
+}
+
+func NewNode(bc *blockchain.Blockchain, cfg *config.P2PConfig) *Node {
+	n := &Node{
+		bc:     bc,
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.RequestTimeout},
+		peers:  make(map[string]*Peer),
+		seen:   newSeenCache(10 * time.Minute),
+	}
+	for _, peer := range cfg.Peers {
+		n.addPeer(peer, true)
+	}
+	return n
+}
+
+// Start 开始广播本地新区块和交易，并定期发现节点、同步区块，返回的函数用于停止
+func (n *Node) Start() (stop func()) {
+	n.bc.OnBlock(func(block *models.Block, txs []*models.Transaction) {
+		n.seen.add(block.Hash)
+		go n.broadcastBlock(block, txs)
+	})
+	n.bc.OnTransaction(func(tx *models.Transaction) {
+		n.seen.add(tx.Signature)
+		go n.broadcastTransaction(tx)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		ticker := time.NewTicker(n.cfg.SyncInterval)
+		defer ticker.Stop()
+
+		for {
+			n.discover()
+			n.Sync()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	log.Printf("P2P node started at %s with %d seed peers", n.cfg.AdvertiseURL, len(n.cfg.Peers))
+	return cancel
+}
+
+// Peers 获取已知节点列表
+func (n *Node) Peers() []*Peer {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	peers := make([]*Peer, 0, len(n.peers))
+	for _, peer := range n.peers {
+		p := *peer
+		peers = append(peers, &p)
+	}
+	return peers
+}
+
+func (n *Node) peerURLs() []string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	urls := make([]string, 0, len(n.peers))
+	for url := range n.peers {
+		urls = append(urls, url)
+	}
+	return urls
+}
+
+// addPeer 加入新节点，忽略自身、地址无效和超出上限的节点
+func (n *Node) addPeer(rawURL string, seed bool) bool {
+	peerURL, err := normalizePeerURL(rawURL)
+	if err != nil || peerURL == n.cfg.AdvertiseURL {
+		return false
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, ok := n.peers[peerURL]; ok {
+		return false
+	}
+	if len(n.peers) >= n.cfg.MaxPeers {
+		return false
+	}
+	n.peers[peerURL] = &Peer{URL: peerURL, Seed: seed}
+	log.Printf("P2P peer added: %s", peerURL)
+	return true
+}
+
+// normalizePeerURL 校验节点地址并去掉末尾的 "/"
+// 节点地址由其他节点提供，本节点会向其发起请求，只接受不带路径、查询参数和用户信息的 http(s) 地址，
+// 并拒绝未指定地址、组播地址和链路本地地址（例如云服务器的元数据服务 169.254.169.254）
+func normalizePeerURL(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimRight(rawURL, "/"))
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("peer URL must use http or https: %q", rawURL)
+	}
+	if u.Hostname() == "" || u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("peer URL must be scheme://host[:port]: %q", rawURL)
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil &&
+		(ip.IsUnspecified() || ip.IsMulticast() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast()) {
+		return "", fmt.Errorf("peer URL host is not a unicast address: %q", rawURL)
+	}
+	return u.String(), nil
+}
+
+// markPeer 记录节点的请求结果，非种子节点连续失败过多时移除
+func (n *Node) markPeer(url string, status *blockchain.ChainStatus, err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	peer, ok := n.peers[url]
+	if !ok {
+		return
+	}
+	if err != nil {
+		peer.failures++
+		if !peer.Seed && peer.failures >= maxPeerFailures {
+			delete(n.peers, url)
+			log.Printf("P2P peer removed after %d failures: %s", peer.failures, url)
+		}
+		return
+	}
+
+	peer.failures = 0
+	peer.LastSeen = time.Now()
+	if status != nil {
+		peer.Height = status.Height
+		peer.TipHash = status.TipHash
+	}
+}
+
+func (n *Node) removePeer(url string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	delete(n.peers, url)
+}
+
+// discover 向已知节点登记自身，并从它们的节点列表中发现新节点
+func (n *Node) discover() {
+	for _, url := range n.peerURLs() {
+		peers, err := n.announce(url)
+		if err != nil {
+			n.markPeer(url, nil, err)
+			continue
+		}
+		for _, peer := range peers {
+			n.addPeer(peer, false)
+		}
+	}
+}
+
+// Sync 与所有已知节点比较累计工作量，从工作量更大的节点拉取缺失的区块
+// 对方与本地链分叉时，从共同祖先之后开始拉取，由 AddBlock 按工作量决定是否重组
+// 已有同步在进行时直接返回
+func (n *Node) Sync() {
+	if !n.syncing.CompareAndSwap(false, true) {
+		return
+	}
+	defer n.syncing.Store(false)
+
+	local, err := n.bc.Status()
+	if err != nil {
+		log.Println("P2P sync: failed to get local status:", err)
+		return
+	}
+
+	for _, url := range n.peerURLs() {
+		status, err := n.fetchStatus(url)
+		n.markPeer(url, status, err)
+		if err != nil {
+			continue
+		}
+
+		if status.ChainID != local.ChainID || status.GenesisHash != local.GenesisHash {
+			log.Printf("P2P peer %s is on a different chain, removing", url)
+			n.removePeer(url)
+			continue
+		}
+		if status.TotalWork == nil || status.TotalWork.Cmp(local.TotalWork) <= 0 {
+			continue
+		}
+
+		ancestor, err := n.findCommonAncestor(url, local.Height, status.Height)
+		if err != nil {
+			log.Printf("P2P sync from %s: failed to find common ancestor: %v", url, err)
+			continue
+		}
+		if err := n.syncFrom(url, ancestor+1, status.Height); err != nil {
+			log.Printf("P2P sync from %s stopped: %v", url, err)
+		}
+		if local, err = n.bc.Status(); err != nil {
+			return
+		}
+	}
+}
+
+// findCommonAncestor 从两条链中较低的高度开始向下按批次比较区块哈希，返回最高的共同区块索引
+// 两条链的创世区块相同，因此最坏情况下返回 0
+func (n *Node) findCommonAncestor(url string, localHeight, peerHeight int) (int, error) {
+	top := localHeight
+	if peerHeight < top {
+		top = peerHeight
+	}
+
+	for top > 0 {
+		from := top - n.cfg.SyncBatchSize + 1
+		if from < 0 {
+			from = 0
+		}
+		blocks, err := n.fetchBlocks(url, from, top-from+1)
+		if err != nil {
+			return 0, err
+		}
+
+		for i := len(blocks) - 1; i >= 0; i-- {
+			remote := blocks[i].Block
+			if remote.Index > top {
+				continue
+			}
+			local, err := n.bc.GetBlockByIndex(remote.Index)
+			if err != nil {
+				return 0, err
+			}
+			if local.Hash == remote.Hash {
+				return remote.Index, nil
+			}
+		}
+		top = from - 1
+	}
+	return 0, nil
+}
+
+// syncFrom 从指定节点按批次拉取 [from, to] 范围内的区块并依次导入
+func (n *Node) syncFrom(url string, from, to int) error {
+	for from <= to {
+		blocks, err := n.fetchBlocks(url, from, n.cfg.SyncBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(blocks) == 0 {
+			return nil
+		}
+
+		for _, item := range blocks {
+			err := n.bc.AddBlock(item.Block, item.Transactions)
+			if err != nil && !errors.Is(err, blockchain.ErrKnownBlock) {
+				return err
+			}
+			n.seen.add(item.Block.Hash)
+			from = item.Block.Index + 1
+		}
+	}
+	return nil
+}
+
+// broadcastBlock 向所有已知节点广播区块
+func (n *Node) broadcastBlock(block *models.Block, txs []*models.Transaction) {
+	msg := &blockMessage{
+		From:         n.cfg.AdvertiseURL,
+		Block:        block,
+		Transactions: txs,
+	}
+	for _, url := range n.peerURLs() {
+		n.markPeer(url, nil, n.post(url+"/p2p/blocks", msg, nil))
+	}
+}
+
+// broadcastTransaction 向所有已知节点广播交易
+func (n *Node) broadcastTransaction(tx *models.Transaction) {
+	msg := &transactionMessage{
+		From:        n.cfg.AdvertiseURL,
+		Transaction: tx,
+	}
+	for _, url := range n.peerURLs() {
+		n.markPeer(url, nil, n.post(url+"/p2p/transactions", msg, nil))
+	}
+}
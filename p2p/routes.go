@@ -0,0 +1,139 @@
+package p2p
+
+import (
+	"errors"
+	"hello-go/blockchain"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes 注册节点间通信接口
+func (n *Node) RegisterRoutes(r *gin.Engine) {
+	group := r.Group("/p2p")
+	{
+		group.GET("/status", n.handleStatus)
+		group.GET("/peers", n.handleGetPeers)
+		group.POST("/peers", n.handleAnnounce)
+		group.GET("/blocks", n.handleGetBlocks)
+		group.POST("/blocks", n.handleBlock)
+		group.POST("/transactions", n.handleTransaction)
+	}
+}
+
+// handleStatus 返回本地链状态
+func (n *Node) handleStatus(c *gin.Context) {
+	status, err := n.bc.Status()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// handleGetPeers 返回已知节点详情
+func (n *Node) handleGetPeers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"peers": n.Peers()})
+}
+
+// handleAnnounce 登记发起方节点，并返回本节点已知的节点列表
+// 已知节点达到上限时不再登记，但仍返回节点列表
+func (n *Node) handleAnnounce(c *gin.Context) {
+	var msg peersMessage
+	if err := c.ShouldBindJSON(&msg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := normalizePeerURL(msg.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	n.addPeer(msg.URL, false)
+	c.JSON(http.StatusOK, peersMessage{URL: n.cfg.AdvertiseURL, Peers: n.peerURLs()})
+}
+
+// handleGetBlocks 按索引范围返回区块及其交易
+func (n *Node) handleGetBlocks(c *gin.Context) {
+	from, err := strconv.Atoi(c.DefaultQuery("from", "0"))
+	if err != nil || from < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(n.cfg.SyncBatchSize)))
+	if err != nil || limit < 1 || limit > n.cfg.SyncBatchSize {
+		limit = n.cfg.SyncBatchSize
+	}
+
+	blocks, err := n.bc.GetBlocksFrom(from, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, blocksResponse{Blocks: blocks})
+}
+
+// handleBlock 接收其他节点广播的区块，父区块缺失时从发送方同步
+func (n *Node) handleBlock(c *gin.Context) {
+	var msg blockMessage
+	if err := c.ShouldBindJSON(&msg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	n.addPeer(msg.From, false)
+
+	// 区块通过校验后才记录哈希，避免伪造的区块冒用真实区块的哈希，使真实区块的广播被忽略
+	if n.seen.has(msg.Block.Hash) {
+		c.JSON(http.StatusOK, gin.H{"status": "known"})
+		return
+	}
+
+	err := n.bc.AddBlock(msg.Block, msg.Transactions)
+	switch {
+	case err == nil:
+		n.seen.add(msg.Block.Hash)
+		c.JSON(http.StatusOK, gin.H{"status": "accepted"})
+	case errors.Is(err, blockchain.ErrKnownBlock):
+		n.seen.add(msg.Block.Hash)
+		c.JSON(http.StatusOK, gin.H{"status": "known"})
+	case errors.Is(err, blockchain.ErrUnknownParent):
+		// 同步进行中时不再启动新的同步，缺失的区块由进行中或下一次定期同步拉取
+		if !n.syncing.Load() {
+			go n.Sync()
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "syncing"})
+	default:
+		log.Printf("P2P rejected block %d from %s: %v", msg.Block.Index, msg.From, err)
+		c.JSON(http.StatusOK, gin.H{"status": "rejected", "error": err.Error()})
+	}
+}
+
+// handleTransaction 接收其他节点广播的交易，校验通过后加入交易池并继续广播
+func (n *Node) handleTransaction(c *gin.Context) {
+	var msg transactionMessage
+	if err := c.ShouldBindJSON(&msg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	n.addPeer(msg.From, false)
+
+	// 与区块相同，交易通过校验后才记录签名
+	if n.seen.has(msg.Transaction.Signature) {
+		c.JSON(http.StatusOK, gin.H{"status": "known"})
+		return
+	}
+
+	_, err := n.bc.ImportTransaction(msg.Transaction)
+	switch {
+	case err == nil:
+		n.seen.add(msg.Transaction.Signature)
+		c.JSON(http.StatusOK, gin.H{"status": "accepted"})
+	case errors.Is(err, blockchain.ErrDuplicateTransaction):
+		n.seen.add(msg.Transaction.Signature)
+		c.JSON(http.StatusOK, gin.H{"status": "known"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"status": "rejected", "error": err.Error()})
+	}
+}
@@ -0,0 +1,363 @@
+package rpc
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hello-go/blockchain"
+	"hello-go/models"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// 以太坊钱包按 18 位小数解释余额和金额
+const weiDecimals = 18
+
+// toWei 将链上最小单位换算为 18 位小数的最小单位（与以太坊 wei 相同）
+func (s *Server) toWei(amount models.Amount) *big.Int {
+	wei := amount.ToBig()
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(weiDecimals-s.bc.Decimals())), nil)
+	return wei.Mul(wei, scale)
+}
+
+// fromWei 将 18 位小数的最小单位换算为链上最小单位，不能整除时返回错误
+func (s *Server) fromWei(wei *big.Int) (models.Amount, error) {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(weiDecimals-s.bc.Decimals())), nil)
+	units, rem := new(big.Int).QuoRem(wei, scale, new(big.Int))
+	if rem.Sign() != 0 {
+		return models.Amount{}, invalidParams("value %s is not a whole number of base units", wei)
+	}
+	amount, err := models.ParseAmount(units.String())
+	if err != nil {
+		return models.Amount{}, invalidParams("invalid value: %v", err)
+	}
+	return amount, nil
+}
+
+// blockNumber 区块参数：十六进制高度或 "earliest"、"latest"、"pending"、"safe"、"finalized"
+// 本链没有最终性和待出块状态，除 earliest 外的标签均指向链头
+type blockNumber struct {
+	latest  bool
+	pending bool
+	index   uint64
+}
+
+func (b *blockNumber) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	switch s {
+	case "earliest":
+		*b = blockNumber{index: 0}
+	case "pending":
+		*b = blockNumber{latest: true, pending: true}
+	case "latest", "safe", "finalized":
+		*b = blockNumber{latest: true}
+	default:
+		n, err := hexutil.DecodeUint64(s)
+		if err != nil {
+			return fmt.Errorf("invalid block number %q: %v", s, err)
+		}
+		*b = blockNumber{index: n}
+	}
+	return nil
+}
+
+// resolve 将区块参数换算为主链区块
+func (s *Server) resolve(number blockNumber) (*models.Block, error) {
+	if number.latest {
+		return s.bc.GetLatestBlock()
+	}
+	return s.bc.GetBlockByIndex(int(number.index))
+}
+
+// rpcBlock 以太坊格式的区块
+type rpcBlock struct {
+	Number           hexutil.Uint64 `json:"number"`
+	Hash             common.Hash    `json:"hash"`
+	ParentHash       common.Hash    `json:"parentHash"`
+	Nonce            hexutil.Bytes  `json:"nonce"`
+	Timestamp        hexutil.Uint64 `json:"timestamp"`
+	Difficulty       *hexutil.Big   `json:"difficulty"`
+	TransactionsRoot common.Hash    `json:"transactionsRoot"`
+	ExtraData        hexutil.Bytes  `json:"extraData"`
+	// Transactions 交易哈希列表，或在请求完整交易时为交易对象列表
+	Transactions interface{} `json:"transactions"`
+}
+
+// rpcTransaction 以太坊格式的交易，交易尚未上链时区块字段为 null
+type rpcTransaction struct {
+	Hash             common.Hash     `json:"hash"`
+	Nonce            hexutil.Uint64  `json:"nonce"`
+	BlockHash        *common.Hash    `json:"blockHash"`
+	BlockNumber      *hexutil.Uint64 `json:"blockNumber"`
+	TransactionIndex *hexutil.Uint64 `json:"transactionIndex"`
+	From             common.Address  `json:"from"`
+	To               common.Address  `json:"to"`
+	Value            *hexutil.Big    `json:"value"`
+	ChainID          hexutil.Uint64  `json:"chainId"`
+	Input            hexutil.Bytes   `json:"input"`
+	V                *hexutil.Big    `json:"v"`
+	R                *hexutil.Big    `json:"r"`
+	S                *hexutil.Big    `json:"s"`
+}
+
+func newRPCBlock(block *models.Block, txs interface{}) *rpcBlock {
+	nonce := make([]byte, 8)
+	binary.BigEndian.PutUint64(nonce, uint64(block.Nonce))
+
+	return &rpcBlock{
+		Number:           hexutil.Uint64(block.Index),
+		Hash:             common.HexToHash(block.Hash),
+		ParentHash:       common.HexToHash(block.PrevHash),
+		Nonce:            nonce,
+		Timestamp:        hexutil.Uint64(block.Timestamp.Unix()),
+		Difficulty:       (*hexutil.Big)(blockchain.BlockWork(block.Difficulty)),
+		TransactionsRoot: common.HexToHash(block.MerkleRoot),
+		ExtraData:        []byte(block.Data),
+		Transactions:     txs,
+	}
+}
+
+func (s *Server) newRPCTransaction(tx *models.Transaction, block *models.Block, index int) (*rpcTransaction, error) {
+	hash, err := blockchain.TransactionHash(tx)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := hexutil.Decode(tx.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &rpcTransaction{
+		Hash:    hash,
+		Nonce:   hexutil.Uint64(tx.Nonce),
+		From:    common.HexToAddress(tx.FromAddr),
+		To:      common.HexToAddress(tx.ToAddr),
+		Value:   (*hexutil.Big)(s.toWei(tx.Amount)),
+		ChainID: hexutil.Uint64(tx.ChainID),
+		Input:   hexutil.Bytes{},
+	}
+	if len(sig) == 65 {
+		result.R = (*hexutil.Big)(new(big.Int).SetBytes(sig[:32]))
+		result.S = (*hexutil.Big)(new(big.Int).SetBytes(sig[32:64]))
+		result.V = (*hexutil.Big)(big.NewInt(int64(sig[64])))
+	}
+	if block != nil {
+		blockHash := common.HexToHash(block.Hash)
+		blockNumber := hexutil.Uint64(block.Index)
+		txIndex := hexutil.Uint64(index)
+		result.BlockHash = &blockHash
+		result.BlockNumber = &blockNumber
+		result.TransactionIndex = &txIndex
+	}
+	return result, nil
+}
+
+// eth_chainId
+func (s *Server) chainID(params json.RawMessage) (interface{}, error) {
+	return hexutil.Uint64(s.bc.ChainID()), nil
+}
+
+// eth_blockNumber
+func (s *Server) blockNumber(params json.RawMessage) (interface{}, error) {
+	latest, err := s.bc.GetLatestBlock()
+	if err != nil {
+		return nil, err
+	}
+	return hexutil.Uint64(latest.Index), nil
+}
+
+// eth_getBalance [address, block]
+// 只保存了当前余额，区块参数必须指向链头
+func (s *Server) getBalance(params json.RawMessage) (interface{}, error) {
+	var address common.Address
+	number := blockNumber{latest: true}
+	if err := parseParams(params, 1, &address, &number); err != nil {
+		return nil, err
+	}
+	if err := s.checkLatest(number); err != nil {
+		return nil, err
+	}
+
+	balance, err := s.bc.GetBalance(address.Hex())
+	if errors.Is(err, sql.ErrNoRows) {
+		return (*hexutil.Big)(new(big.Int)), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(s.toWei(balance)), nil
+}
+
+// checkLatest 检查区块参数是否指向链头，历史状态不可查询
+func (s *Server) checkLatest(number blockNumber) error {
+	if number.latest {
+		return nil
+	}
+	latest, err := s.bc.GetLatestBlock()
+	if err != nil {
+		return err
+	}
+	if number.index != uint64(latest.Index) {
+		return fmt.Errorf("historical state is not available, only the latest block is supported")
+	}
+	return nil
+}
+
+// eth_getBlockByNumber [block, fullTransactions]
+func (s *Server) getBlockByNumber(params json.RawMessage) (interface{}, error) {
+	var number blockNumber
+	var fullTx bool
+	if err := parseParams(params, 1, &number, &fullTx); err != nil {
+		return nil, err
+	}
+
+	block, err := s.resolve(number)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	txs, err := s.bc.GetTransactionsByBlockID(block.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if fullTx {
+		result := make([]*rpcTransaction, 0, len(txs))
+		for i, tx := range txs {
+			rpcTx, err := s.newRPCTransaction(tx, block, i)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, rpcTx)
+		}
+		return newRPCBlock(block, result), nil
+	}
+
+	hashes := make([]common.Hash, 0, len(txs))
+	for _, tx := range txs {
+		hash, err := blockchain.TransactionHash(tx)
+		if err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return newRPCBlock(block, hashes), nil
+}
+
+// eth_getTransactionByHash [hash]
+func (s *Server) getTransactionByHash(params json.RawMessage) (interface{}, error) {
+	var hash common.Hash
+	if err := parseParams(params, 1, &hash); err != nil {
+		return nil, err
+	}
+
+	location, err := s.bc.GetTransactionByHash(hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.newRPCTransaction(location.Transaction, location.Block, location.Index)
+}
+
+// eth_sendRawTransaction [data]，chain_sendRawTransaction 为别名
+// data 为 blockchain.EncodeTransaction 编码的已签名交易，校验通过后提交到交易池并返回交易哈希
+// 以太坊格式的已签名交易（legacy/EIP-155）会被解码并换算为本链的转账，但本链只能校验自己的签名，
+// 因此返回错误说明需要按本链格式重新签名的转账内容
+func (s *Server) sendRawTransaction(params json.RawMessage) (interface{}, error) {
+	var data hexutil.Bytes
+	if err := parseParams(params, 1, &data); err != nil {
+		return nil, err
+	}
+
+	tx, err := blockchain.DecodeTransaction(data)
+	if err != nil {
+		var ethTx types.Transaction
+		if ethTx.UnmarshalBinary(data) != nil {
+			return nil, invalidParams("%v", err)
+		}
+		transfer, err := s.fromEthereumTransaction(&ethTx)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &Error{Code: codeServerError, Message: fmt.Sprintf(
+			"ethereum-signed transactions are not supported: re-sign the transfer of %s base units from %s to %s with nonce %d "+
+				"over rlp([chain_id, nonce, from, to, amount, fee]) and submit the native encoding",
+			transfer.Amount, transfer.FromAddr, transfer.ToAddr, transfer.Nonce)}
+	}
+	receipt, err := s.bc.Transfer(tx)
+	if err != nil {
+		return nil, err
+	}
+	return receipt.Hash, nil
+}
+
+// fromEthereumTransaction 将以太坊格式的已签名交易换算为本链的转账，签名者为转出地址
+// 只支持带 EIP-155 重放保护、没有调用数据的普通转账，金额必须能换算为整数个链上最小单位
+func (s *Server) fromEthereumTransaction(ethTx *types.Transaction) (*models.Transaction, error) {
+	if !ethTx.Protected() {
+		return nil, invalidParams("only replay-protected (EIP-155) transactions are supported")
+	}
+	chainID := new(big.Int).SetUint64(s.bc.ChainID())
+	if ethTx.ChainId().Cmp(chainID) != 0 {
+		return nil, invalidParams("invalid chain id: expected %d, got %s", chainID, ethTx.ChainId())
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(chainID), ethTx)
+	if err != nil {
+		return nil, invalidParams("invalid sender: %v", err)
+	}
+	if ethTx.To() == nil {
+		return nil, invalidParams("contract creation is not supported")
+	}
+	if len(ethTx.Data()) > 0 {
+		return nil, invalidParams("contract calls are not supported")
+	}
+	amount, err := s.fromWei(ethTx.Value())
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Transaction{
+		FromAddr: from.Hex(),
+		ToAddr:   ethTx.To().Hex(),
+		Amount:   amount,
+		Nonce:    ethTx.Nonce(),
+		ChainID:  s.bc.ChainID(),
+	}, nil
+}
+
+// eth_getTransactionCount [address, block]
+// "pending" 包含交易池中序号连续的待打包交易，其他区块参数只统计已上链的交易
+func (s *Server) getTransactionCount(params json.RawMessage) (interface{}, error) {
+	var address common.Address
+	number := blockNumber{latest: true}
+	if err := parseParams(params, 1, &address, &number); err != nil {
+		return nil, err
+	}
+	if err := s.checkLatest(number); err != nil {
+		return nil, err
+	}
+
+	// 交易数即下一个交易序号，未知地址按 0 处理
+	nonce, err := s.bc.GetNonce(address.Hex(), number.pending)
+	if errors.Is(err, sql.ErrNoRows) {
+		return hexutil.Uint64(0), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return hexutil.Uint64(nonce), nil
+}
@@ -0,0 +1,209 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hello-go/blockchain"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 单个批量请求最多包含的调用数
+const maxBatchSize = 100
+
+// JSON-RPC 2.0 标准错误码，-32000 为以太坊节点通用的服务端错误
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeServerError    = -32000
+)
+
+// Error JSON-RPC 错误对象
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func invalidParams(format string, args ...interface{}) *Error {
+	return &Error{Code: codeInvalidParams, Message: fmt.Sprintf(format, args...)}
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+var nullID = json.RawMessage("null")
+
+func errorResponse(id json.RawMessage, err *Error) *response {
+	if id == nil {
+		id = nullID
+	}
+	return &response{JSONRPC: "2.0", ID: id, Error: err}
+}
+
+// methodFunc 方法实现，params 为原始的位置参数数组
+type methodFunc func(params json.RawMessage) (interface{}, error)
+
+// Server 兼容以太坊 JSON-RPC 的接口服务，支持批量调用
+type Server struct {
+	bc      *blockchain.Blockchain
+	methods map[string]methodFunc
+}
+
+func NewServer(bc *blockchain.Blockchain) *Server {
+	s := &Server{bc: bc}
+	s.methods = map[string]methodFunc{
+		"eth_chainId":              s.chainID,
+		"eth_blockNumber":          s.blockNumber,
+		"eth_getBalance":           s.getBalance,
+		"eth_getBlockByNumber":     s.getBlockByNumber,
+		"eth_getTransactionByHash": s.getTransactionByHash,
+		"eth_sendRawTransaction":   s.sendRawTransaction,
+		"eth_getTransactionCount":  s.getTransactionCount,
+		"chain_sendRawTransaction": s.sendRawTransaction,
+	}
+	return s
+}
+
+// RegisterRoutes 注册 JSON-RPC 接口
+func (s *Server) RegisterRoutes(r *gin.Engine) {
+	r.POST("/rpc", s.handle)
+}
+
+// handle 处理单个调用或批量调用，全部为通知（不带 id）时不返回内容
+func (s *Server) handle(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusOK, errorResponse(nil, &Error{Code: codeParseError, Message: err.Error()}))
+		return
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '[' {
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			c.JSON(http.StatusOK, errorResponse(nil, &Error{Code: codeParseError, Message: "parse error"}))
+			return
+		}
+		if resp := s.call(&req); resp != nil {
+			c.JSON(http.StatusOK, resp)
+			return
+		}
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		c.JSON(http.StatusOK, errorResponse(nil, &Error{Code: codeParseError, Message: "parse error"}))
+		return
+	}
+	if len(batch) == 0 {
+		c.JSON(http.StatusOK, errorResponse(nil, &Error{Code: codeInvalidRequest, Message: "empty batch"}))
+		return
+	}
+	if len(batch) > maxBatchSize {
+		c.JSON(http.StatusOK, errorResponse(nil, &Error{
+			Code:    codeInvalidRequest,
+			Message: fmt.Sprintf("batch too large: at most %d calls", maxBatchSize),
+		}))
+		return
+	}
+
+	responses := make([]*response, 0, len(batch))
+	for _, raw := range batch {
+		var req request
+		if err := json.Unmarshal(raw, &req); err != nil {
+			responses = append(responses, errorResponse(nil, &Error{Code: codeInvalidRequest, Message: "invalid request"}))
+			continue
+		}
+		if resp := s.call(&req); resp != nil {
+			responses = append(responses, resp)
+		}
+	}
+
+	if len(responses) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.JSON(http.StatusOK, responses)
+}
+
+// call 执行一次调用，通知调用返回 nil
+func (s *Server) call(req *request) *response {
+	notification := req.ID == nil
+
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(req.ID, &Error{Code: codeInvalidRequest, Message: "invalid request"})
+	}
+
+	method, ok := s.methods[req.Method]
+	if !ok {
+		if notification {
+			return nil
+		}
+		return errorResponse(req.ID, &Error{
+			Code:    codeMethodNotFound,
+			Message: fmt.Sprintf("the method %s does not exist/is not available", req.Method),
+		})
+	}
+
+	result, err := method(req.Params)
+	if notification {
+		return nil
+	}
+	if err != nil {
+		if rpcErr, ok := err.(*Error); ok {
+			return errorResponse(req.ID, rpcErr)
+		}
+		return errorResponse(req.ID, &Error{Code: codeServerError, Message: err.Error()})
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return errorResponse(req.ID, &Error{Code: codeServerError, Message: err.Error()})
+	}
+	return &response{JSONRPC: "2.0", ID: req.ID, Result: encoded}
+}
+
+// parseParams 将位置参数数组依次解析到 args，前 required 个参数必须提供
+func parseParams(raw json.RawMessage, required int, args ...interface{}) error {
+	var params []json.RawMessage
+	if len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return invalidParams("non-array args")
+		}
+	}
+
+	if len(params) < required {
+		return invalidParams("missing value for required argument %d", len(params))
+	}
+	if len(params) > len(args) {
+		return invalidParams("too many arguments, want at most %d", len(args))
+	}
+
+	for i, param := range params {
+		if err := json.Unmarshal(param, args[i]); err != nil {
+			return invalidParams("invalid argument %d: %v", i, err)
+		}
+	}
+	return nil
+}
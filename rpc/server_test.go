@@ -0,0 +1,442 @@
+package rpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"hello-go/blockchain"
+	"hello-go/config"
+	"hello-go/database"
+	"hello-go/models"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gin-gonic/gin"
+)
+
+const testChainID = 1337
+
+// testNode 使用内存数据库的区块链和 JSON-RPC 服务，sender 有 1000 个最小单位的余额
+type testNode struct {
+	r         *gin.Engine
+	bc        *blockchain.Blockchain
+	key       *ecdsa.PrivateKey
+	sender    string
+	recipient string
+}
+
+func newTestNode(t *testing.T) *testNode {
+	t.Helper()
+
+	db := database.NewBlockchainMemory()
+	ks := keystore.NewKeyStore(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP)
+	chain := &config.ChainConfig{ChainID: testChainID, GenesisTime: time.Unix(0, 0), Decimals: 18, BlockReward: "0"}
+	mining := &config.MiningConfig{
+		InitialDifficulty: 1, MinDifficulty: 1, MaxDifficulty: 1,
+		TargetBlockTime: time.Second, AdjustmentWindow: 10,
+		MaxTransactionsPerBlock: 10, Workers: 1, Timeout: 10 * time.Second,
+	}
+	bc := blockchain.NewBlockchain(db, ks, chain, mining)
+	if _, err := bc.CreateGenesisBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := &testNode{
+		bc:        bc,
+		key:       key,
+		sender:    crypto.PubkeyToAddress(key.PublicKey).Hex(),
+		recipient: "0x00000000000000000000000000000000000000b0",
+	}
+	if err := db.SaveWallet(&models.Wallet{Address: n.sender, Balance: models.NewAmount(1000)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveWallet(&models.Wallet{Address: n.recipient}); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	n.r = gin.New()
+	NewServer(bc).RegisterRoutes(n.r)
+	return n
+}
+
+// signedTransfer 按本链格式签名并编码一笔转账，返回的交易带有交易哈希
+func (n *testNode) signedTransfer(t *testing.T, amount, nonce uint64) (*models.Transaction, hexutil.Bytes) {
+	t.Helper()
+
+	tx := &models.Transaction{
+		FromAddr: n.sender,
+		ToAddr:   n.recipient,
+		Amount:   models.NewAmount(amount),
+		Nonce:    nonce,
+		ChainID:  testChainID,
+	}
+	if err := blockchain.SignTransaction(tx, n.key); err != nil {
+		t.Fatal(err)
+	}
+	data, err := blockchain.EncodeTransaction(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := blockchain.TransactionHash(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Hash = hash.Hex()
+	return tx, data
+}
+
+// post 发送 JSON-RPC 请求体，返回状态码和响应体
+func (n *testNode) post(body string) (int, []byte) {
+	req := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	n.r.ServeHTTP(w, req)
+	return w.Code, w.Body.Bytes()
+}
+
+// call 执行一次调用并返回响应
+func (n *testNode) call(t *testing.T, method string, params ...interface{}) *response {
+	t.Helper()
+
+	if params == nil {
+		params = []interface{}{}
+	}
+	body, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	code, data := n.post(string(body))
+	if code != http.StatusOK {
+		t.Fatalf("%s: status %d", method, code)
+	}
+	var resp response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		t.Fatalf("%s: %v", method, err)
+	}
+	return &resp
+}
+
+func TestMethods(t *testing.T) {
+	n := newTestNode(t)
+
+	// 第一笔转账打包进区块 1，第二笔留在交易池
+	mined, raw := n.signedTransfer(t, 100, 0)
+	if resp := n.call(t, "eth_sendRawTransaction", raw); resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+	if _, _, err := n.bc.MinePendingTransactions(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	pending, raw := n.signedTransfer(t, 50, 1)
+	if resp := n.call(t, "eth_sendRawTransaction", raw); resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+	block, err := n.bc.GetBlockByIndex(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method string
+		params []interface{}
+		// check 校验解码后的结果
+		check func(t *testing.T, result json.RawMessage)
+	}{
+		{
+			method: "eth_chainId",
+			check:  wantJSON(`"0x539"`),
+		},
+		{
+			method: "eth_blockNumber",
+			check:  wantJSON(`"0x1"`),
+		},
+		{
+			method: "eth_getBalance",
+			params: []interface{}{n.recipient, "latest"},
+			check:  wantJSON(`"0x64"`),
+		},
+		{
+			method: "eth_getBalance",
+			params: []interface{}{"0x00000000000000000000000000000000000000c0"},
+			check:  wantJSON(`"0x0"`),
+		},
+		{
+			method: "eth_getTransactionCount",
+			params: []interface{}{n.sender, "latest"},
+			check:  wantJSON(`"0x1"`),
+		},
+		{
+			method: "eth_getTransactionCount",
+			params: []interface{}{n.sender, "pending"},
+			check:  wantJSON(`"0x2"`),
+		},
+		{
+			method: "eth_getBlockByNumber",
+			params: []interface{}{"0x1", false},
+			check: func(t *testing.T, result json.RawMessage) {
+				var got struct {
+					Number       string   `json:"number"`
+					Hash         string   `json:"hash"`
+					Transactions []string `json:"transactions"`
+				}
+				decode(t, result, &got)
+				if got.Number != "0x1" || got.Hash != "0x"+block.Hash {
+					t.Errorf("block = %s %s, want 0x1 0x%s", got.Number, got.Hash, block.Hash)
+				}
+				if len(got.Transactions) != 1 || got.Transactions[0] != mined.Hash {
+					t.Errorf("transactions = %v, want [%s]", got.Transactions, mined.Hash)
+				}
+			},
+		},
+		{
+			method: "eth_getBlockByNumber",
+			params: []interface{}{"latest", true},
+			check: func(t *testing.T, result json.RawMessage) {
+				var got struct {
+					Transactions []rpcTransaction `json:"transactions"`
+				}
+				decode(t, result, &got)
+				if len(got.Transactions) != 1 || got.Transactions[0].From != common.HexToAddress(n.sender) {
+					t.Errorf("transactions = %+v, want one transfer from %s", got.Transactions, n.sender)
+				}
+			},
+		},
+		{
+			method: "eth_getBlockByNumber",
+			params: []interface{}{"0x10"},
+			check:  wantJSON(`null`),
+		},
+		{
+			method: "eth_getTransactionByHash",
+			params: []interface{}{mined.Hash},
+			check: func(t *testing.T, result json.RawMessage) {
+				var got rpcTransaction
+				decode(t, result, &got)
+				if got.BlockNumber == nil || *got.BlockNumber != 1 || got.Value.ToInt().Int64() != 100 {
+					t.Errorf("transaction = %+v, want 100 units in block 1", got)
+				}
+			},
+		},
+		{
+			method: "eth_getTransactionByHash",
+			params: []interface{}{pending.Hash},
+			check: func(t *testing.T, result json.RawMessage) {
+				var got rpcTransaction
+				decode(t, result, &got)
+				if got.BlockHash != nil || got.Nonce != 1 {
+					t.Errorf("transaction = %+v, want pending transaction with nonce 1", got)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			resp := n.call(t, tt.method, tt.params...)
+			if resp.Error != nil {
+				t.Fatalf("error %d: %s", resp.Error.Code, resp.Error.Message)
+			}
+			tt.check(t, resp.Result)
+		})
+	}
+}
+
+func TestSendRawTransaction(t *testing.T) {
+	// ethereumTransfer 用以太坊工具的方式对 legacy 交易做 EIP-155 签名
+	ethereumTransfer := func(t *testing.T, n *testNode, chainID int64, tx *types.LegacyTx) hexutil.Bytes {
+		t.Helper()
+
+		signed, err := types.SignNewTx(n.key, types.NewEIP155Signer(big.NewInt(chainID)), tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := signed.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	to := common.HexToAddress("0x00000000000000000000000000000000000000b0")
+
+	tests := []struct {
+		name   string
+		method string
+		data   func(t *testing.T, n *testNode) hexutil.Bytes
+		// wantCode 为 0 时期望成功并返回交易哈希
+		wantCode    int
+		wantMessage string
+	}{
+		{
+			name:   "native transaction",
+			method: "eth_sendRawTransaction",
+			data: func(t *testing.T, n *testNode) hexutil.Bytes {
+				_, data := n.signedTransfer(t, 10, 0)
+				return data
+			},
+		},
+		{
+			name:   "native transaction through alias",
+			method: "chain_sendRawTransaction",
+			data: func(t *testing.T, n *testNode) hexutil.Bytes {
+				_, data := n.signedTransfer(t, 10, 0)
+				return data
+			},
+		},
+		{
+			name:   "ethereum transfer",
+			method: "eth_sendRawTransaction",
+			data: func(t *testing.T, n *testNode) hexutil.Bytes {
+				return ethereumTransfer(t, n, testChainID, &types.LegacyTx{Nonce: 3, To: &to, Value: big.NewInt(10), Gas: 21000, GasPrice: big.NewInt(1)})
+			},
+			wantCode:    codeServerError,
+			wantMessage: "re-sign the transfer of 10 base units from",
+		},
+		{
+			name:   "ethereum transfer for another chain",
+			method: "eth_sendRawTransaction",
+			data: func(t *testing.T, n *testNode) hexutil.Bytes {
+				return ethereumTransfer(t, n, 1, &types.LegacyTx{To: &to, Value: big.NewInt(10), Gas: 21000, GasPrice: big.NewInt(1)})
+			},
+			wantCode:    codeInvalidParams,
+			wantMessage: "invalid chain id",
+		},
+		{
+			name:   "ethereum contract creation",
+			method: "eth_sendRawTransaction",
+			data: func(t *testing.T, n *testNode) hexutil.Bytes {
+				return ethereumTransfer(t, n, testChainID, &types.LegacyTx{Value: big.NewInt(0), Gas: 100000, GasPrice: big.NewInt(1), Data: []byte{0x60}})
+			},
+			wantCode:    codeInvalidParams,
+			wantMessage: "contract creation",
+		},
+		{
+			name:   "garbage",
+			method: "eth_sendRawTransaction",
+			data: func(t *testing.T, n *testNode) hexutil.Bytes {
+				return hexutil.Bytes{0xde, 0xad, 0xbe, 0xef}
+			},
+			wantCode: codeInvalidParams,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := newTestNode(t)
+
+			resp := n.call(t, tt.method, tt.data(t, n))
+			if tt.wantCode == 0 {
+				if resp.Error != nil {
+					t.Fatalf("error %d: %s", resp.Error.Code, resp.Error.Message)
+				}
+				pending := n.bc.PendingTransactions()
+				if len(pending) != 1 {
+					t.Fatalf("mempool has %d transactions, want 1", len(pending))
+				}
+				wantJSON(`"`+pending[0].Hash+`"`)(t, resp.Result)
+				return
+			}
+			if resp.Error == nil || resp.Error.Code != tt.wantCode || !strings.Contains(resp.Error.Message, tt.wantMessage) {
+				t.Fatalf("error = %+v, want code %d containing %q", resp.Error, tt.wantCode, tt.wantMessage)
+			}
+			if pending := n.bc.PendingTransactions(); len(pending) != 0 {
+				t.Errorf("mempool has %d transactions, want 0", len(pending))
+			}
+		})
+	}
+}
+
+func TestBatch(t *testing.T) {
+	n := newTestNode(t)
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+		// want 期望的响应，为空时不校验响应体
+		want string
+	}{
+		{
+			name: "mixed batch",
+			body: `[{"jsonrpc":"2.0","id":1,"method":"eth_chainId"},
+				{"jsonrpc":"2.0","method":"eth_blockNumber"},
+				{"jsonrpc":"2.0","id":"b","method":"eth_unknown"},
+				42,
+				{"jsonrpc":"2.0","id":3,"method":"eth_blockNumber"}]`,
+			wantCode: http.StatusOK,
+			want: `[{"jsonrpc":"2.0","id":1,"result":"0x539"},
+				{"jsonrpc":"2.0","id":"b","error":{"code":-32601,"message":"the method eth_unknown does not exist/is not available"}},
+				{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"invalid request"}},
+				{"jsonrpc":"2.0","id":3,"result":"0x0"}]`,
+		},
+		{
+			name:     "notifications only",
+			body:     `[{"jsonrpc":"2.0","method":"eth_chainId"},{"jsonrpc":"2.0","method":"eth_blockNumber"}]`,
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:     "empty batch",
+			body:     `[]`,
+			wantCode: http.StatusOK,
+			want:     `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"empty batch"}}`,
+		},
+		{
+			name:     "too large",
+			body:     "[" + strings.Repeat(`{"jsonrpc":"2.0","id":1,"method":"eth_chainId"},`, maxBatchSize) + `{"jsonrpc":"2.0","id":1,"method":"eth_chainId"}]`,
+			wantCode: http.StatusOK,
+			want:     `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"batch too large: at most 100 calls"}}`,
+		},
+		{
+			name:     "parse error",
+			body:     `[{"jsonrpc":`,
+			wantCode: http.StatusOK,
+			want:     `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"parse error"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := n.post(tt.body)
+			if code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", code, tt.wantCode)
+			}
+			if tt.want != "" {
+				wantJSON(tt.want)(t, body)
+			}
+		})
+	}
+}
+
+// wantJSON 比较 JSON 的语义是否相同，忽略空白和字段顺序
+func wantJSON(want string) func(t *testing.T, got json.RawMessage) {
+	return func(t *testing.T, got json.RawMessage) {
+		t.Helper()
+
+		var g, w interface{}
+		decode(t, got, &g)
+		decode(t, json.RawMessage(want), &w)
+		gotJSON, _ := json.Marshal(g)
+		wantJSON, _ := json.Marshal(w)
+		if string(gotJSON) != string(wantJSON) {
+			t.Errorf("got %s, want %s", gotJSON, wantJSON)
+		}
+	}
+}
+
+func decode(t *testing.T, data json.RawMessage, v interface{}) {
+	t.Helper()
+
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("decode %s: %v", data, err)
+	}
+}
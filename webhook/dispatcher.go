@@ -0,0 +1,316 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hello-go/blockchain"
+	"hello-go/config"
+	"hello-go/models"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// 等待写入投递记录的事件队列长度
+	eventQueueSize = 1024
+	// 每轮最多投递的记录数
+	deliveryBatchSize = 50
+	// 投递记录中保存的响应内容最大长度
+	maxErrorLength = 512
+)
+
+// activity 一笔交易引起的地址活动
+type activity struct {
+	tx    *models.Transaction
+	block *models.Block
+}
+
+// Dispatcher 根据区块链事件生成回调投递记录，并在后台投递和重试
+type Dispatcher struct {
+	store  Store
+	cfg    *config.WebhookConfig
+	client *http.Client
+
+	activities chan *activity
+	// 有新的投递记录或某个回调投递完成时唤醒投递协程
+	wake chan struct{}
+
+	mu sync.Mutex
+	// busy 正在投递的回调，每个回调同一时间只有一个协程按顺序投递
+	busy map[int64]bool
+	// inFlight 已取出但尚未投递完成的记录数
+	inFlight int
+}
+
+func NewDispatcher(store Store, cfg *config.WebhookConfig) *Dispatcher {
+	return &Dispatcher{
+		store:      store,
+		cfg:        cfg,
+		client:     &http.Client{Timeout: cfg.RequestTimeout},
+		activities: make(chan *activity, eventQueueSize),
+		wake:       make(chan struct{}, 1),
+		busy:       make(map[int64]bool),
+	}
+}
+
+// Start 监听新交易和新区块，并启动后台投递，返回的函数用于停止
+func (d *Dispatcher) Start(bc *blockchain.Blockchain) (stop func()) {
+	bc.OnTransaction(func(tx *models.Transaction) {
+		d.enqueue(&activity{tx: tx})
+	})
+	bc.OnBlock(func(block *models.Block, txs []*models.Transaction) {
+		for _, tx := range txs {
+			d.enqueue(&activity{tx: tx, block: block})
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go d.recordLoop(ctx)
+	go d.deliverLoop(ctx)
+
+	log.Println("Webhook dispatcher started")
+	return cancel
+}
+
+// enqueue 将活动放入队列，不阻塞提交交易和区块的协程
+func (d *Dispatcher) enqueue(a *activity) {
+	select {
+	case d.activities <- a:
+	default:
+		log.Printf("Webhook event queue is full, dropping activity for transaction %s", a.tx.Signature)
+	}
+}
+
+// recordLoop 按发生顺序为每个活动生成投递记录
+func (d *Dispatcher) recordLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case a := <-d.activities:
+			if err := d.record(a); err != nil {
+				log.Println("Webhook delivery record failed:", err)
+				continue
+			}
+			d.notify()
+		}
+	}
+}
+
+// notify 唤醒投递协程，已有待处理的唤醒时不阻塞
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// record 为匹配的回调注册写入投递记录
+func (d *Dispatcher) record(a *activity) error {
+	hooks, err := d.store.ListWebhooks()
+	if err != nil {
+		return err
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	hash, err := blockchain.TransactionHash(a.tx)
+	if err != nil {
+		return err
+	}
+
+	type target struct {
+		event   string
+		address string
+	}
+	var targets []target
+	if a.block == nil {
+		targets = []target{{EventOutgoing, a.tx.FromAddr}, {EventIncoming, a.tx.ToAddr}}
+	} else {
+		targets = []target{{EventConfirmed, a.tx.FromAddr}, {EventConfirmed, a.tx.ToAddr}}
+	}
+
+	now := time.Now()
+	for _, hook := range hooks {
+		// 同一回调对同一笔交易的同一事件只投递一次，例如未按地址过滤时的上链事件
+		delivered := make(map[string]bool)
+		for _, t := range targets {
+			if delivered[t.event] || !subscribes(hook, t.event) || !matches(hook, t.address) {
+				continue
+			}
+			delivered[t.event] = true
+
+			payload := &Payload{
+				Event:       t.event,
+				Address:     t.address,
+				Hash:        hash.Hex(),
+				Transaction: a.tx,
+				Timestamp:   now,
+			}
+			if a.block != nil {
+				payload.Block = &BlockRef{Index: a.block.Index, Hash: a.block.Hash}
+			}
+			body, err := json.Marshal(payload)
+			if err != nil {
+				return err
+			}
+
+			delivery := &models.WebhookDelivery{
+				WebhookID:     hook.ID,
+				Event:         t.event,
+				Payload:       body,
+				Status:        models.DeliveryPending,
+				NextAttemptAt: now,
+				CreatedAt:     now,
+				UpdatedAt:     now,
+			}
+			if err := d.store.SaveWebhookDelivery(delivery); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// deliverLoop 定期或在有新记录时取出到期的记录，按回调分组后为每个回调启动一个投递协程，
+// 响应缓慢的回调地址不会延误其他回调的投递
+func (d *Dispatcher) deliverLoop(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+
+		d.dispatch(ctx)
+	}
+}
+
+// dispatch 取出到期的记录并为空闲的回调启动投递协程
+// 查询时持有锁，投递协程在更新记录之后才释放回调，不会把已投递的记录再取出投递一次
+func (d *Dispatcher) dispatch(ctx context.Context) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// 正在投递的记录仍处于待投递状态，会被再次取出，多取这部分以免占满批次
+	deliveries, err := d.store.GetDueWebhookDeliveries(time.Now(), deliveryBatchSize+d.inFlight)
+	if err != nil {
+		log.Println("Webhook delivery query failed:", err)
+		return
+	}
+
+	var order []int64
+	groups := make(map[int64][]*models.WebhookDelivery)
+	for _, delivery := range deliveries {
+		if _, ok := groups[delivery.WebhookID]; !ok {
+			order = append(order, delivery.WebhookID)
+		}
+		groups[delivery.WebhookID] = append(groups[delivery.WebhookID], delivery)
+	}
+
+	for _, id := range order {
+		// 回调正在投递时跳过，由该协程完成后唤醒重新取出，保证同一回调按顺序投递
+		if d.busy[id] {
+			continue
+		}
+		d.busy[id] = true
+		d.inFlight += len(groups[id])
+		go d.deliverAll(ctx, id, groups[id])
+	}
+}
+
+// deliverAll 按顺序投递同一回调的记录，完成后释放该回调并唤醒投递协程
+func (d *Dispatcher) deliverAll(ctx context.Context, webhookID int64, deliveries []*models.WebhookDelivery) {
+	defer func() {
+		d.mu.Lock()
+		delete(d.busy, webhookID)
+		d.inFlight -= len(deliveries)
+		d.mu.Unlock()
+		d.notify()
+	}()
+
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+		d.deliver(ctx, delivery)
+	}
+}
+
+// deliver 投递一条记录并更新其状态，失败时按指数退避安排下次重试
+func (d *Dispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	delivery.Attempts++
+	delivery.UpdatedAt = time.Now()
+
+	code, err := d.post(ctx, delivery)
+	delivery.ResponseCode = code
+	switch {
+	case err == nil:
+		delivery.Status = models.DeliverySucceeded
+		delivery.Error = ""
+	case delivery.Attempts >= d.cfg.MaxAttempts:
+		delivery.Status = models.DeliveryFailed
+		delivery.Error = err.Error()
+		log.Printf("Webhook delivery %d failed after %d attempts: %v", delivery.ID, delivery.Attempts, err)
+	default:
+		delivery.Error = err.Error()
+		delivery.NextAttemptAt = time.Now().Add(d.backoff(delivery.Attempts))
+	}
+
+	if err := d.store.UpdateWebhookDelivery(delivery); err != nil {
+		log.Println("Webhook delivery update failed:", err)
+	}
+}
+
+// backoff 第 attempts 次失败后的等待时间
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.cfg.InitialBackoff
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= d.cfg.MaxBackoff {
+			return d.cfg.MaxBackoff
+		}
+	}
+	return wait
+}
+
+// post 发送签名的回调请求，返回 HTTP 状态码，非 2xx 响应视为失败
+func (d *Dispatcher) post(ctx context.Context, delivery *models.WebhookDelivery) (int, error) {
+	hook, err := d.store.GetWebhook(delivery.WebhookID)
+	if err != nil {
+		return 0, fmt.Errorf("webhook %d not available: %v", delivery.WebhookID, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, delivery.Payload))
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderWebhook, strconv.FormatInt(hook.ID, 10))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+		return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,204 @@
+package webhook
+
+import (
+	"context"
+	"hello-go/config"
+	"hello-go/database"
+	"hello-go/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testSecret = "test-secret"
+
+// endpoint 记录收到的回调请求，按顺序返回 statuses 中的状态码，用完后返回最后一个
+type endpoint struct {
+	t        *testing.T
+	statuses []int
+
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (e *endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		e.t.Error(err)
+	}
+
+	e.mu.Lock()
+	e.requests = append(e.requests, r)
+	e.bodies = append(e.bodies, body)
+	status := e.statuses[len(e.statuses)-1]
+	if n := len(e.requests); n <= len(e.statuses) {
+		status = e.statuses[n-1]
+	}
+	e.mu.Unlock()
+
+	w.WriteHeader(status)
+}
+
+func (e *endpoint) count() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.requests)
+}
+
+// newTestDispatcher 创建使用内存存储的投递器并启动投递协程，重试间隔很短以便测试
+func newTestDispatcher(t *testing.T, maxAttempts int) (*Dispatcher, *database.BlockchainMemory) {
+	t.Helper()
+
+	store := database.NewBlockchainMemory()
+	d := NewDispatcher(store, &config.WebhookConfig{
+		MaxAttempts:    maxAttempts,
+		InitialBackoff: 5 * time.Millisecond,
+		MaxBackoff:     20 * time.Millisecond,
+		PollInterval:   5 * time.Millisecond,
+		RequestTimeout: 5 * time.Second,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go d.deliverLoop(ctx)
+	return d, store
+}
+
+// addDelivery 注册指向 url 的回调并写入一条待投递记录
+func addDelivery(t *testing.T, d *Dispatcher, store Store, url string) *models.WebhookDelivery {
+	t.Helper()
+
+	hook := &models.Webhook{URL: url, Secret: testSecret, CreatedAt: time.Now()}
+	if err := store.SaveWebhook(hook); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	delivery := &models.WebhookDelivery{
+		WebhookID:     hook.ID,
+		Event:         EventConfirmed,
+		Payload:       []byte(`{"event":"transfer.confirmed"}`),
+		Status:        models.DeliveryPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := store.SaveWebhookDelivery(delivery); err != nil {
+		t.Fatal(err)
+	}
+	d.notify()
+	return delivery
+}
+
+// waitDelivery 等待投递记录不再处于待投递状态
+func waitDelivery(t *testing.T, store Store, delivery *models.WebhookDelivery) *models.WebhookDelivery {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		deliveries, err := store.GetWebhookDeliveries(delivery.WebhookID, 1, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deliveries) == 1 && deliveries[0].Status != models.DeliveryPending {
+			return deliveries[0]
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("delivery %d is still pending", delivery.ID)
+	return nil
+}
+
+func TestDeliverRetriesUntilSuccessOrMaxAttempts(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantStatus   string
+		wantAttempts int
+		wantCode     int
+	}{
+		{name: "success on first attempt", statuses: []int{http.StatusOK}, wantStatus: models.DeliverySucceeded, wantAttempts: 1, wantCode: http.StatusOK},
+		{name: "any 2xx stops retrying", statuses: []int{http.StatusNoContent}, wantStatus: models.DeliverySucceeded, wantAttempts: 1, wantCode: http.StatusNoContent},
+		{name: "retry after 5xx", statuses: []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK}, wantStatus: models.DeliverySucceeded, wantAttempts: 3, wantCode: http.StatusOK},
+		{name: "give up after max attempts", statuses: []int{http.StatusServiceUnavailable}, wantStatus: models.DeliveryFailed, wantAttempts: 3, wantCode: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, store := newTestDispatcher(t, 3)
+			e := &endpoint{t: t, statuses: tt.statuses}
+			srv := httptest.NewServer(e)
+			defer srv.Close()
+
+			delivery := waitDelivery(t, store, addDelivery(t, d, store, srv.URL))
+			if delivery.Status != tt.wantStatus || delivery.Attempts != tt.wantAttempts || delivery.ResponseCode != tt.wantCode {
+				t.Errorf("delivery status = %s, attempts = %d, code = %d; want %s, %d, %d",
+					delivery.Status, delivery.Attempts, delivery.ResponseCode, tt.wantStatus, tt.wantAttempts, tt.wantCode)
+			}
+			// 投递结束后不再发送请求
+			time.Sleep(50 * time.Millisecond)
+			if n := e.count(); n != tt.wantAttempts {
+				t.Errorf("endpoint received %d requests, want %d", n, tt.wantAttempts)
+			}
+
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			for i, req := range e.requests {
+				signature := req.Header.Get(HeaderSignature)
+				if !strings.HasPrefix(signature, "sha256=") || !Verify(testSecret, e.bodies[i], signature) {
+					t.Errorf("request %d: signature %q does not verify", i, signature)
+				}
+				if got := req.Header.Get(HeaderDelivery); got != strconv.FormatInt(delivery.ID, 10) {
+					t.Errorf("request %d: %s = %q, want %d", i, HeaderDelivery, got, delivery.ID)
+				}
+				if got := req.Header.Get(HeaderEvent); got != EventConfirmed {
+					t.Errorf("request %d: %s = %q, want %s", i, HeaderEvent, got, EventConfirmed)
+				}
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDispatcher(nil, &config.WebhookConfig{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second})
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: time.Second},
+		{attempts: 2, want: 2 * time.Second},
+		{attempts: 3, want: 4 * time.Second},
+		{attempts: 4, want: 5 * time.Second},
+		{attempts: 10, want: 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := d.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+// TestSlowEndpointDoesNotDelayOthers 一个回调地址迟迟不响应时，其他回调照常投递
+func TestSlowEndpointDoesNotDelayOthers(t *testing.T) {
+	d, store := newTestDispatcher(t, 3)
+
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+	fast := httptest.NewServer(&endpoint{t: t, statuses: []int{http.StatusOK}})
+	defer fast.Close()
+
+	addDelivery(t, d, store, slow.URL)
+	delivery := waitDelivery(t, store, addDelivery(t, d, store, fast.URL))
+	if delivery.Status != models.DeliverySucceeded {
+		t.Errorf("fast endpoint delivery status = %s, want %s", delivery.Status, models.DeliverySucceeded)
+	}
+}
@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hello-go/models"
+	"strings"
+	"time"
+)
+
+// 回调事件类型
+const (
+	// EventOutgoing 地址发出的转账进入交易池
+	EventOutgoing = "transfer.outgoing"
+	// EventIncoming 转入地址的转账进入交易池
+	EventIncoming = "transfer.incoming"
+	// EventConfirmed 涉及地址的转账被打包上链
+	EventConfirmed = "transfer.confirmed"
+)
+
+// Events 全部可订阅的事件类型
+var Events = []string{EventOutgoing, EventIncoming, EventConfirmed}
+
+// 回调请求头
+const (
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderWebhook   = "X-Webhook-ID"
+)
+
+// Store 回调注册和投递记录的持久化接口，查询不到数据时返回 sql.ErrNoRows
+type Store interface {
+	SaveWebhook(hook *models.Webhook) error
+	GetWebhook(id int64) (*models.Webhook, error)
+	ListWebhooks() ([]*models.Webhook, error)
+	// DeleteWebhook 删除回调注册及其投递记录
+	DeleteWebhook(id int64) error
+
+	SaveWebhookDelivery(delivery *models.WebhookDelivery) error
+	UpdateWebhookDelivery(delivery *models.WebhookDelivery) error
+	// GetDueWebhookDeliveries 获取到达重试时间的待投递记录，按计划投递时间排序
+	GetDueWebhookDeliveries(now time.Time, limit int) ([]*models.WebhookDelivery, error)
+	// GetWebhookDeliveries 按时间倒序分页获取回调的投递记录
+	GetWebhookDeliveries(webhookID int64, limit, offset int) ([]*models.WebhookDelivery, error)
+}
+
+// BlockRef 交易所在区块
+type BlockRef struct {
+	Index int    `json:"index"`
+	Hash  string `json:"hash"`
+}
+
+// Payload 回调请求体
+type Payload struct {
+	Event string `json:"event"`
+	// Address 触发事件的注册地址，未按地址过滤的回调为交易的相应一方
+	Address     string              `json:"address"`
+	Hash        string              `json:"hash"`
+	Transaction *models.Transaction `json:"transaction"`
+	Block       *BlockRef           `json:"block,omitempty"`
+	Timestamp   time.Time           `json:"timestamp"`
+}
+
+// Sign 计算回调签名：sha256=hex(HMAC-SHA256(secret, body))
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 校验回调签名，接收方使用注册时返回的 secret 调用
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// ValidEvent 判断事件类型是否有效
+func ValidEvent(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// subscribes 判断回调是否订阅了该事件
+func subscribes(hook *models.Webhook, event string) bool {
+	if len(hook.Events) == 0 {
+		return true
+	}
+	for _, e := range hook.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// matches 判断回调的地址过滤条件是否匹配
+func matches(hook *models.Webhook, address string) bool {
+	return hook.Address == "" || strings.EqualFold(hook.Address, address)
+}
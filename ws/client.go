@@ -0,0 +1,172 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// 每个客户端最多订阅的主题数
+	maxTopics = 32
+	// 待发送消息缓冲区大小，客户端处理过慢导致缓冲区写满时断开连接
+	sendBufferSize = 64
+	writeTimeout   = 10 * time.Second
+)
+
+// 客户端请求的操作
+const (
+	actionSubscribe   = "subscribe"
+	actionUnsubscribe = "unsubscribe"
+)
+
+// clientMessage 客户端发送的订阅请求
+type clientMessage struct {
+	Action string   `json:"action"`
+	Topics []string `json:"topics"`
+}
+
+// replyMessage 订阅请求的应答，Topics 为当前已订阅的全部主题
+type replyMessage struct {
+	Type   string   `json:"type"`
+	Topics []string `json:"topics,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+type client struct {
+	conn *websocket.Conn
+
+	mu     sync.RWMutex
+	topics map[string]bool
+
+	outbox    chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newClient(conn *websocket.Conn) *client {
+	return &client{
+		conn:   conn,
+		topics: make(map[string]bool),
+		outbox: make(chan []byte, sendBufferSize),
+		done:   make(chan struct{}),
+	}
+}
+
+// run 在当前协程读取客户端请求，另起协程发送消息，连接断开后返回
+func (cl *client) run() {
+	go cl.writeLoop()
+	defer cl.close()
+
+	for {
+		var msg clientMessage
+		if err := websocket.JSON.Receive(cl.conn, &msg); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				cl.reply(&replyMessage{Type: "error", Error: "invalid message"})
+				continue
+			}
+			return
+		}
+		cl.handleMessage(&msg)
+	}
+}
+
+func (cl *client) writeLoop() {
+	for {
+		select {
+		case <-cl.done:
+			return
+		case msg := <-cl.outbox:
+			cl.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := websocket.Message.Send(cl.conn, string(msg)); err != nil {
+				cl.close()
+				return
+			}
+		}
+	}
+}
+
+// handleMessage 处理订阅和取消订阅请求，请求中有任一无效主题时不做任何修改
+func (cl *client) handleMessage(msg *clientMessage) {
+	if msg.Action != actionSubscribe && msg.Action != actionUnsubscribe {
+		cl.reply(&replyMessage{Type: "error", Error: "unknown action: " + msg.Action})
+		return
+	}
+
+	topics := make([]string, 0, len(msg.Topics))
+	for _, raw := range msg.Topics {
+		topic, ok := normalizeTopic(raw)
+		if !ok {
+			cl.reply(&replyMessage{Type: "error", Error: "invalid topic: " + raw})
+			return
+		}
+		topics = append(topics, topic)
+	}
+
+	cl.mu.Lock()
+	if msg.Action == actionSubscribe {
+		added := make(map[string]bool)
+		for _, topic := range topics {
+			if !cl.topics[topic] {
+				added[topic] = true
+			}
+		}
+		if len(cl.topics)+len(added) > maxTopics {
+			cl.mu.Unlock()
+			cl.reply(&replyMessage{Type: "error", Error: "too many topics"})
+			return
+		}
+		for _, topic := range topics {
+			cl.topics[topic] = true
+		}
+	} else {
+		for _, topic := range topics {
+			delete(cl.topics, topic)
+		}
+	}
+
+	current := make([]string, 0, len(cl.topics))
+	for topic := range cl.topics {
+		current = append(current, topic)
+	}
+	cl.mu.Unlock()
+
+	cl.reply(&replyMessage{Type: msg.Action + "d", Topics: current})
+}
+
+func (cl *client) reply(msg *replyMessage) {
+	encoded, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	cl.send(encoded)
+}
+
+func (cl *client) subscribed(topic string) bool {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+
+	return cl.topics[topic]
+}
+
+// send 将消息放入发送缓冲区，缓冲区已满时断开客户端，避免拖慢事件发布
+func (cl *client) send(msg []byte) {
+	select {
+	case <-cl.done:
+	case cl.outbox <- msg:
+	default:
+		cl.close()
+	}
+}
+
+func (cl *client) close() {
+	cl.closeOnce.Do(func() {
+		close(cl.done)
+		cl.conn.Close()
+	})
+}
@@ -0,0 +1,213 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"hello-go/blockchain"
+	"hello-go/models"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// 订阅主题
+const (
+	TopicNewBlocks           = "newBlocks"
+	TopicPendingTransactions = "pendingTransactions"
+	// 地址主题的前缀，完整主题为 "address:<地址>"
+	topicAddressPrefix = "address:"
+)
+
+// 推送的事件类型
+const (
+	eventBlock              = "block"
+	eventPendingTransaction = "pendingTransaction"
+	eventBalance            = "balance"
+)
+
+// Event 推送给客户端的事件
+type Event struct {
+	Type  string      `json:"type"`
+	Topic string      `json:"topic"`
+	Event string      `json:"event"`
+	Data  interface{} `json:"data"`
+}
+
+type blockEvent struct {
+	Block            *models.Block         `json:"block"`
+	TransactionCount int                   `json:"transaction_count"`
+	Transactions     []*models.Transaction `json:"transactions"`
+}
+
+type transactionEvent struct {
+	Hash        string              `json:"hash"`
+	Transaction *models.Transaction `json:"transaction"`
+}
+
+type balanceEvent struct {
+	Address string        `json:"address"`
+	Balance models.Amount `json:"balance"`
+}
+
+// Hub 管理 WebSocket 客户端，将区块链事件推送给订阅了相应主题的客户端
+type Hub struct {
+	bc *blockchain.Blockchain
+	// origins 允许建立连接的跨域来源，包含 "*" 时允许所有来源
+	origins map[string]bool
+
+	mu      sync.RWMutex
+	clients map[*client]bool
+}
+
+// NewHub origins 为允许跨域建立连接的来源，与 CORS 配置相同
+func NewHub(bc *blockchain.Blockchain, origins []string) *Hub {
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		allowed[strings.TrimRight(origin, "/")] = true
+	}
+	return &Hub{
+		bc:      bc,
+		origins: allowed,
+		clients: make(map[*client]bool),
+	}
+}
+
+// RegisterRoutes 注册 WebSocket 接口
+func (h *Hub) RegisterRoutes(r *gin.Engine) {
+	r.GET("/ws", h.handle)
+}
+
+// Start 开始监听新区块、新交易和余额变化，返回的函数用于断开所有客户端
+func (h *Hub) Start() (stop func()) {
+	h.bc.OnBlock(func(block *models.Block, txs []*models.Transaction) {
+		h.publish(TopicNewBlocks, eventBlock, &blockEvent{
+			Block:            block,
+			TransactionCount: len(txs),
+			Transactions:     txs,
+		})
+	})
+	h.bc.OnTransaction(func(tx *models.Transaction) {
+		event := &transactionEvent{Transaction: tx}
+		if hash, err := blockchain.TransactionHash(tx); err == nil {
+			event.Hash = hash.Hex()
+		}
+		h.publish(TopicPendingTransactions, eventPendingTransaction, event)
+		h.publish(addressTopic(tx.FromAddr), eventPendingTransaction, event)
+		if !strings.EqualFold(tx.FromAddr, tx.ToAddr) {
+			h.publish(addressTopic(tx.ToAddr), eventPendingTransaction, event)
+		}
+	})
+	h.bc.OnBalanceChange(func(address string, balance models.Amount) {
+		h.publish(addressTopic(address), eventBalance, &balanceEvent{Address: address, Balance: balance})
+	})
+
+	return h.closeAll
+}
+
+// handle 升级为 WebSocket 连接，可以通过 topics 查询参数（逗号分隔）在连接时直接订阅
+func (h *Hub) handle(c *gin.Context) {
+	var initial []string
+	if v := c.Query("topics"); v != "" {
+		initial = strings.Split(v, ",")
+	}
+
+	server := websocket.Server{
+		Handshake: func(_ *websocket.Config, req *http.Request) error { return h.checkOrigin(req) },
+		Handler: func(conn *websocket.Conn) {
+			cl := newClient(conn)
+			h.register(cl)
+			defer h.unregister(cl)
+
+			if len(initial) > 0 {
+				cl.handleMessage(&clientMessage{Action: actionSubscribe, Topics: initial})
+			}
+			cl.run()
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// checkOrigin 浏览器不对 WebSocket 握手做 CORS 检查，需要自行校验 Origin
+// 没有 Origin 的请求来自非浏览器客户端，同源页面和配置的来源可以建立连接
+func (h *Hub) checkOrigin(req *http.Request) error {
+	origin := req.Header.Get("Origin")
+	if origin == "" || h.origins["*"] || h.origins[origin] {
+		return nil
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, req.Host) {
+		return nil
+	}
+	return fmt.Errorf("origin %s is not allowed", origin)
+}
+
+func (h *Hub) register(cl *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.clients[cl] = true
+}
+
+func (h *Hub) unregister(cl *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.clients, cl)
+}
+
+func (h *Hub) closeAll() {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for cl := range h.clients {
+		cl.close()
+	}
+}
+
+// publish 将事件发送给订阅了该主题的所有客户端
+func (h *Hub) publish(topic, event string, data interface{}) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var encoded []byte
+	for cl := range h.clients {
+		if !cl.subscribed(topic) {
+			continue
+		}
+		if encoded == nil {
+			var err error
+			encoded, err = json.Marshal(&Event{Type: "event", Topic: topic, Event: event, Data: data})
+			if err != nil {
+				log.Println("WebSocket event encoding failed:", err)
+				return
+			}
+		}
+		cl.send(encoded)
+	}
+}
+
+// normalizeTopic 校验主题，地址主题统一为校验和格式的地址
+func normalizeTopic(topic string) (string, bool) {
+	topic = strings.TrimSpace(topic)
+	switch topic {
+	case TopicNewBlocks, TopicPendingTransactions:
+		return topic, true
+	}
+
+	if strings.HasPrefix(topic, topicAddressPrefix) {
+		address := strings.TrimPrefix(topic, topicAddressPrefix)
+		if common.IsHexAddress(address) {
+			return addressTopic(address), true
+		}
+	}
+	return "", false
+}
+
+func addressTopic(address string) string {
+	return topicAddressPrefix + common.HexToAddress(address).Hex()
+}
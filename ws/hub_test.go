@@ -0,0 +1,75 @@
+package ws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		origins []string
+		origin  string
+		wantOK  bool
+	}{
+		{name: "no origin header", origin: "", wantOK: true},
+		{name: "same origin", origin: "http://node.example.com:8080", wantOK: true},
+		{name: "same host on another port", origin: "http://node.example.com:3000", wantOK: false},
+		{name: "cross origin rejected by default", origin: "https://evil.example.com", wantOK: false},
+		{name: "configured origin", origins: []string{"https://explorer.example.com/"}, origin: "https://explorer.example.com", wantOK: true},
+		{name: "unlisted origin", origins: []string{"https://explorer.example.com"}, origin: "https://evil.example.com", wantOK: false},
+		{name: "opaque origin", origins: []string{"https://explorer.example.com"}, origin: "null", wantOK: false},
+		{name: "wildcard", origins: []string{"*"}, origin: "https://evil.example.com", wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://node.example.com:8080/ws", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			err := NewHub(nil, tt.origins).checkOrigin(req)
+			if ok := err == nil; ok != tt.wantOK {
+				t.Errorf("checkOrigin(%q) = %v, want allowed = %v", tt.origin, err, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestHandshakeRejectsCrossOrigin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	NewHub(nil, []string{"https://explorer.example.com"}).RegisterRoutes(r)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	tests := []struct {
+		origin string
+		wantOK bool
+	}{
+		{origin: srv.URL, wantOK: true},
+		{origin: "https://explorer.example.com", wantOK: true},
+		{origin: "https://evil.example.com", wantOK: false},
+	}
+	for _, tt := range tests {
+		config, err := websocket.NewConfig("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config.Origin = &url.URL{Opaque: tt.origin}
+
+		conn, err := websocket.DialConfig(config)
+		if err == nil {
+			conn.Close()
+		}
+		if ok := err == nil; ok != tt.wantOK {
+			t.Errorf("handshake from %s succeeded = %v, want %v (err: %v)", tt.origin, ok, tt.wantOK, err)
+		}
+	}
+}